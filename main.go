@@ -0,0 +1,7 @@
+package main
+
+import "vmware-analyzer-to-netpol/pkg/convert"
+
+func main() {
+	convert.Main()
+}
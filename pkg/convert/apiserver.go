@@ -0,0 +1,37 @@
+package convert
+
+// apiServerEntity is the Cilium entity of the Kubernetes API server
+const apiServerEntity = "kube-apiserver"
+
+// defaultAPIServerPort is the port the API server endpoints listen on.
+// NetworkPolicies see traffic to the kubernetes service after it has been
+// translated to an endpoint, so its port 443 does not apply.
+const defaultAPIServerPort = 6443
+
+// APIServerEgress is the egress to the Kubernetes API server added to every
+// policy that restricts egress
+type APIServerEgress struct {
+	// CIDR holds the API server endpoints. Only Cilium output, which uses
+	// the kube-apiserver entity, can do without it.
+	CIDR string
+	Port int
+}
+
+// allowAPIServer appends a rule permitting TCP to the API server to every
+// policy that restricts egress
+func allowAPIServer(policies []NetworkPolicy, apiServer *APIServerEgress) {
+	if apiServer == nil {
+		return
+	}
+	port := IntOrString{IntVal: apiServer.Port}
+	rule := Rule{Ports: []PortRule{{Port: &port, Protocol: "TCP"}}, apiServer: true}
+	if apiServer.CIDR != "" {
+		rule.To = []NetworkPolicyPeer{{IPBlock: &IPBlock{CIDR: apiServer.CIDR}}}
+	}
+	for i := range policies {
+		if !hasPolicyType(policies[i], "Egress") {
+			continue
+		}
+		policies[i].Spec.Egress = append(policies[i].Spec.Egress, rule)
+	}
+}
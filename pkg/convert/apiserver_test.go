@@ -0,0 +1,45 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllowAPIServer(t *testing.T) {
+	apiServer := &APIServerEgress{CIDR: "10.96.0.1/32", Port: defaultAPIServerPort}
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions(), APIServer: apiServer})
+
+	port := IntOrString{IntVal: defaultAPIServerPort}
+	want := Rule{
+		To:        []NetworkPolicyPeer{{IPBlock: &IPBlock{CIDR: "10.96.0.1/32"}}},
+		Ports:     []PortRule{{Port: &port, Protocol: "TCP"}},
+		apiServer: true,
+	}
+	client := findPolicy(t, policies, "client")
+	if egress := client.Spec.Egress; len(egress) != 2 || !reflect.DeepEqual(egress[1], want) {
+		t.Errorf("client egress = %+v, want the service rule followed by %+v", egress, want)
+	}
+	if web := findPolicy(t, policies, "web"); len(web.Spec.Egress) != 0 {
+		t.Errorf("ingress-only policy web got egress rules %+v", web.Spec.Egress)
+	}
+
+	cilium := ciliumPolicy(client).Spec.Egress
+	wantCilium := CiliumEgressRule{
+		ToEntities: []string{apiServerEntity},
+		ToPorts:    []CiliumPortRule{{Ports: []CiliumPort{{Port: "6443", Protocol: "TCP"}}}},
+	}
+	if len(cilium) != 2 || !reflect.DeepEqual(cilium[1], wantCilium) {
+		t.Errorf("Cilium egress = %+v, want the service rule followed by %+v", cilium, wantCilium)
+	}
+}
+
+func TestAllowAPIServerWithoutCIDR(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions(), APIServer: &APIServerEgress{Port: 443}})
+	client := findPolicy(t, policies, "client")
+	if rule := client.Spec.Egress[len(client.Spec.Egress)-1]; !rule.apiServer || len(rule.To) != 0 || rule.Ports[0].Port.IntVal != 443 {
+		t.Errorf("API server rule without a CIDR = %+v, want TCP/443 to any destination", rule)
+	}
+	if egress := ciliumPolicy(client).Spec.Egress; !reflect.DeepEqual(egress[len(egress)-1].ToEntities, []string{apiServerEntity}) {
+		t.Errorf("Cilium egress = %+v, want the kube-apiserver entity last", egress)
+	}
+}
@@ -0,0 +1,50 @@
+package convert
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// applyHeredoc delimits the policy YAML in printed kubectl apply commands
+const applyHeredoc = "NETPOL"
+
+// printApplyFiles prints a kubectl apply command for each written policy file
+func printApplyFiles(w io.Writer, dir string, entries []IndexEntry, generateName bool) {
+	for _, entry := range entries {
+		fmt.Fprintf(w, "kubectl %s%s -f %s\n", applyVerb(generateName), namespaceArg(entry.Namespace), filepath.Join(dir, entry.File))
+	}
+}
+
+// printApplyHeredocs prints a kubectl apply command for each policy, with
+// the policy YAML inlined as a heredoc
+func printApplyHeredocs(w io.Writer, policies []NetworkPolicy, kinds []string, render RenderOptions, yamlOpts YAMLOptions) error {
+	for _, kind := range kinds {
+		for _, policy := range policies {
+			yamlData, err := marshalYAML(renderPolicy(policy, kind, render), yamlOpts)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "kubectl %s%s -f - <<'%s'\n%s%s\n", applyVerb(policy.Metadata.GenerateName != ""), namespaceArg(policy.Metadata.Namespace), applyHeredoc, yamlData, applyHeredoc)
+		}
+	}
+	return nil
+}
+
+// applyVerb returns the kubectl command for the policies. kubectl apply
+// cannot be used with generateName.
+func applyVerb(generateName bool) string {
+	if generateName {
+		return "create"
+	}
+	return "apply"
+}
+
+// namespaceArg returns the kubectl namespace argument, or nothing to use the
+// current namespace
+func namespaceArg(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	return " -n " + namespace
+}
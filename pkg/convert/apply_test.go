@@ -0,0 +1,51 @@
+package convert
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintApplyFiles(t *testing.T) {
+	entries := []IndexEntry{{Name: "web", Namespace: "shop", File: "web.yaml"}, {Name: "db", File: "shop/db.yaml"}}
+	var buf bytes.Buffer
+	printApplyFiles(&buf, "policies", entries, false)
+	want := "kubectl apply -n shop -f policies/web.yaml\nkubectl apply -f policies/shop/db.yaml\n"
+	if buf.String() != want {
+		t.Errorf("commands =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	printApplyFiles(&buf, "policies", entries[:1], true)
+	if want := "kubectl create -n shop -f policies/web.yaml\n"; buf.String() != want {
+		t.Errorf("commands with generateName = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPrintApplyHeredocs(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	var buf bytes.Buffer
+	if err := printApplyHeredocs(&buf, policies[:1], []string{kindNetworkPolicy}, defaultRender, defaultYAML); err != nil {
+		t.Fatal(err)
+	}
+	want := `kubectl apply -n default -f - <<'NETPOL'
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: web
+  namespace: default
+spec:
+  podSelector:
+    matchLabels:
+      app: web
+  policyTypes:
+  - Ingress
+  ingress:
+  - ports:
+    - port: 80
+      protocol: TCP
+NETPOL
+`
+	if buf.String() != want {
+		t.Errorf("commands =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,55 @@
+package convert
+
+import "fmt"
+
+// ArgoCD resource tracking metadata
+const (
+	argoTrackingAnnotation = "argocd.argoproj.io/tracking-id"
+	argoInstanceLabel      = "app.kubernetes.io/instance"
+)
+
+// ArgoCDOptions marks the emitted objects as resources of an ArgoCD
+// Application. InstanceLabel also sets the label used by label tracking.
+type ArgoCDOptions struct {
+	App           string
+	InstanceLabel bool
+}
+
+// validate checks that the Application name can be used in the tracking
+// annotation and, with InstanceLabel, as a label value
+func (o ArgoCDOptions) validate() error {
+	if o.App == "" {
+		if o.InstanceLabel {
+			return fmt.Errorf("-argocd-instance-label requires -argocd-app")
+		}
+		return nil
+	}
+	if !namespacePattern.MatchString(o.App) {
+		return fmt.Errorf("application name %q must be a DNS-1123 label", o.App)
+	}
+	return nil
+}
+
+// trackMetadata returns the metadata with the ArgoCD tracking annotation,
+// and the instance label when enabled, for an object of the output kind.
+// The maps are copied, as the policy's metadata may be shared.
+func trackMetadata(meta ObjectMeta, kind string, argoCD ArgoCDOptions) ObjectMeta {
+	if argoCD.App == "" {
+		return meta
+	}
+	annotations := copyLabels(meta.Annotations)
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[argoTrackingAnnotation] = fmt.Sprintf("%s:%s/%s:%s/%s", argoCD.App, resourceGroup(kind), resourceKind(kind), meta.Namespace, meta.Name)
+	meta.Annotations = annotations
+	if argoCD.InstanceLabel {
+		labels := copyLabels(meta.Labels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[argoInstanceLabel] = argoCD.App
+		meta.Labels = labels
+	}
+	return meta
+}
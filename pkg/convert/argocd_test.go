@@ -0,0 +1,54 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrackMetadata(t *testing.T) {
+	meta := ObjectMeta{
+		Name:        "postgresql",
+		Namespace:   "demo",
+		Labels:      map[string]string{"team": "db"},
+		Annotations: map[string]string{"nsx.vmware.com/source": "postgresql"},
+	}
+	if got := trackMetadata(meta, kindNetworkPolicy, ArgoCDOptions{}); !reflect.DeepEqual(got, meta) {
+		t.Errorf("metadata without -argocd-app = %+v, want it unchanged", got)
+	}
+
+	for kind, id := range map[string]string{
+		kindNetworkPolicy: "netpol-demo:networking.k8s.io/NetworkPolicy:demo/postgresql",
+		kindCilium:        "netpol-demo:cilium.io/CiliumNetworkPolicy:demo/postgresql",
+		kindConfigMap:     "netpol-demo:/ConfigMap:demo/postgresql",
+	} {
+		got := trackMetadata(meta, kind, ArgoCDOptions{App: "netpol-demo"})
+		want := map[string]string{"nsx.vmware.com/source": "postgresql", argoTrackingAnnotation: id}
+		if !reflect.DeepEqual(got.Annotations, want) {
+			t.Errorf("%s annotations = %v, want %v", kind, got.Annotations, want)
+		}
+		if !reflect.DeepEqual(got.Labels, meta.Labels) {
+			t.Errorf("%s labels = %v, want them unchanged without -argocd-instance-label", kind, got.Labels)
+		}
+	}
+
+	got := trackMetadata(meta, kindNetworkPolicy, ArgoCDOptions{App: "netpol-demo", InstanceLabel: true})
+	if want := map[string]string{"team": "db", argoInstanceLabel: "netpol-demo"}; !reflect.DeepEqual(got.Labels, want) {
+		t.Errorf("labels = %v, want %v", got.Labels, want)
+	}
+	if len(meta.Labels) != 1 || len(meta.Annotations) != 1 {
+		t.Errorf("trackMetadata changed the policy's metadata maps in place: %+v", meta)
+	}
+}
+
+func TestArgoCDOptionsValidate(t *testing.T) {
+	for _, opts := range []ArgoCDOptions{{}, {App: "netpol-demo"}, {App: "netpol-demo", InstanceLabel: true}} {
+		if err := opts.validate(); err != nil {
+			t.Errorf("validate(%+v) = %v", opts, err)
+		}
+	}
+	for _, opts := range []ArgoCDOptions{{InstanceLabel: true}, {App: "Netpol_Demo"}} {
+		if err := opts.validate(); err == nil {
+			t.Errorf("validate accepted %+v", opts)
+		}
+	}
+}
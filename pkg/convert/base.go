@@ -0,0 +1,136 @@
+package convert
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// loadBasePolicy reads the NetworkPolicy whose rules every generated policy
+// inherits
+func loadBasePolicy(path string) (NetworkPolicy, error) {
+	var base NetworkPolicy
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return base, err
+	}
+	if err := yaml.UnmarshalStrict(data, &base); err != nil {
+		return base, err
+	}
+	if base.Kind != "" && base.Kind != "NetworkPolicy" {
+		return base, fmt.Errorf("kind is %q, expected NetworkPolicy", base.Kind)
+	}
+
+	// Like Kubernetes, default ports without a protocol to TCP
+	for _, rules := range [][]Rule{base.Spec.Ingress, base.Spec.Egress} {
+		for _, rule := range rules {
+			for k := range rule.Ports {
+				if rule.Ports[k].Protocol == "" {
+					rule.Ports[k].Protocol = "TCP"
+				}
+			}
+		}
+	}
+	return base, nil
+}
+
+// mergeBasePolicy merges the rules and policy types of the base policy into
+// every policy. A base rule whose peers match an existing rule adds its ports
+// to that rule, and any other base rule is appended. A base podSelector that
+// differs from the policy's is reported, but the rules are still merged.
+func mergeBasePolicy(diag *diagnostics, policies []NetworkPolicy, base NetworkPolicy) {
+	baseSelects := len(base.Spec.PodSelector.MatchLabels) > 0 || len(base.Spec.PodSelector.MatchExpressions) > 0
+	for i := range policies {
+		policy := &policies[i]
+		if baseSelects && !reflect.DeepEqual(base.Spec.PodSelector, policy.Spec.PodSelector) {
+			diag.warn(policy.source, warnBaseConflict, "policy %s selects %q but the base policy selects %q, merging the base rules anyway",
+				policy.Metadata.Name, selectorString(policy.Spec.PodSelector), selectorString(base.Spec.PodSelector))
+		}
+		for _, policyType := range base.Spec.PolicyTypes {
+			if !hasPolicyType(*policy, policyType) {
+				policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, policyType)
+			}
+		}
+		policy.Spec.Ingress = mergeRules(policy.Spec.Ingress, base.Spec.Ingress)
+		policy.Spec.Egress = mergeRules(policy.Spec.Egress, base.Spec.Egress)
+	}
+}
+
+// mergeRules adds the base rules to rules, pooling ports of rules with the
+// same peers and dropping duplicates. Rules without ports allow all ports and
+// are never pooled.
+func mergeRules(rules, base []Rule) []Rule {
+	if len(base) == 0 {
+		return rules
+	}
+	merged := make([]Rule, 0, len(rules)+len(base))
+	for _, rule := range rules {
+		merged = append(merged, Rule{From: rule.From, To: rule.To, Ports: append([]PortRule(nil), rule.Ports...), apiServer: rule.apiServer, http: rule.http})
+	}
+	for _, rule := range base {
+		pooled := false
+		for j := range merged {
+			if !reflect.DeepEqual(merged[j].From, rule.From) || !reflect.DeepEqual(merged[j].To, rule.To) {
+				continue
+			}
+			// Pooling would apply the HTTP rules of one to the ports of the other
+			if !reflect.DeepEqual(merged[j].http, rule.http) {
+				continue
+			}
+			if len(merged[j].Ports) == 0 || len(rule.Ports) == 0 {
+				// Both allow all ports, or the base rule would widen or
+				// narrow the existing one
+				if len(merged[j].Ports) == len(rule.Ports) {
+					pooled = true
+					break
+				}
+				continue
+			}
+			for _, port := range rule.Ports {
+				if !containsPort(merged[j].Ports, port) {
+					merged[j].Ports = append(merged[j].Ports, port)
+				}
+			}
+			pooled = true
+			break
+		}
+		if !pooled {
+			merged = append(merged, rule)
+		}
+	}
+	return merged
+}
+
+// selectorString formats a pod selector in kubectl's label selector syntax
+func selectorString(selector PodSelector) string {
+	var terms []string
+	for key, value := range selector.MatchLabels {
+		terms = append(terms, key+"="+value)
+	}
+	sort.Strings(terms)
+	for _, expr := range selector.MatchExpressions {
+		switch expr.Operator {
+		case "Exists":
+			terms = append(terms, expr.Key)
+		case "DoesNotExist":
+			terms = append(terms, "!"+expr.Key)
+		default:
+			terms = append(terms, fmt.Sprintf("%s %s (%s)", expr.Key, strings.ToLower(expr.Operator), strings.Join(expr.Values, ",")))
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+// containsPort reports whether ports already has an identical port
+func containsPort(ports []PortRule, port PortRule) bool {
+	for _, existing := range ports {
+		if reflect.DeepEqual(existing, port) {
+			return true
+		}
+	}
+	return false
+}
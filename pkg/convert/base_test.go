@@ -0,0 +1,63 @@
+package convert
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeBasePolicy(t *testing.T) {
+	base, err := loadBasePolicy(filepath.Join(repoRoot, "json/base-policy.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protocol := base.Spec.Egress[0].Ports[0].Protocol; protocol != "TCP" {
+		t.Errorf("base port without a protocol got %q, want TCP", protocol)
+	}
+	policies, diag := convertExport(t, directionsExport, Pipeline{Options: testOptions(), Base: &base})
+	web := findPolicy(t, policies, "web")
+	if want := []string{"Ingress", "Egress"}; !reflect.DeepEqual(web.Spec.PolicyTypes, want) {
+		t.Errorf("policyTypes of web = %v, want %v", web.Spec.PolicyTypes, want)
+	}
+	if !reflect.DeepEqual(web.Spec.Egress, base.Spec.Egress) {
+		t.Errorf("egress of web = %+v, want the base rules", web.Spec.Egress)
+	}
+	client := findPolicy(t, policies, "client")
+	if len(client.Spec.Egress) != 3 || !reflect.DeepEqual(client.Spec.Egress[1:], base.Spec.Egress) {
+		t.Errorf("egress of client = %+v, want its rule followed by the base rules", client.Spec.Egress)
+	}
+	if len(diag.warnings) != 0 {
+		t.Errorf("the empty base podSelector was reported as a conflict: %v", diag.warnings)
+	}
+}
+
+func TestMergeRulesPoolsPorts(t *testing.T) {
+	monitoring := []NetworkPolicyPeer{{NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{"team": "monitoring"}}}}
+	rules := []Rule{{To: monitoring, Ports: testPorts(t, "TCP", "9090", "9100")}}
+	base := []Rule{
+		{To: monitoring, Ports: testPorts(t, "TCP", "9100", "9200")},
+		{To: monitoring},
+	}
+	merged := mergeRules(rules, base)
+	if len(merged) != 2 {
+		t.Fatalf("mergeRules = %+v, want the pooled rule and the all-ports rule", merged)
+	}
+	if got, want := portSummaries(merged[0].Ports), []string{"TCP/9090", "TCP/9100", "TCP/9200"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pooled ports = %v, want %v", got, want)
+	}
+	if len(merged[1].Ports) != 0 {
+		t.Errorf("the all-ports base rule got ports %v", portSummaries(merged[1].Ports))
+	}
+	if len(rules[0].Ports) != 2 {
+		t.Error("mergeRules changed the ports of the original rule")
+	}
+}
+
+func TestMergeBasePolicyReportsSelectorConflict(t *testing.T) {
+	base := newPolicy("base", "default")
+	base.Spec.PodSelector.MatchLabels = map[string]string{"tier": "web"}
+	_, diag := convertExport(t, directionsExport, Pipeline{Options: testOptions(), Base: &base})
+	if len(diag.warnings) != 3 || diag.warnings[0].Type != warnBaseConflict {
+		t.Errorf("warnings = %+v, want a base-conflict per policy", diag.warnings)
+	}
+}
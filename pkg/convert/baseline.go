@@ -0,0 +1,39 @@
+package convert
+
+// namespaceBaselineName is the name of the combined policy of a namespace
+const namespaceBaselineName = "namespace-baseline"
+
+// namespaceBaselines replaces the policies of each namespace with a single
+// policy that selects all pods, restricts both directions and allows the
+// union of their rules. Rules with the same peers have their ports pooled,
+// so the allowed ports of the services end up in one rule. Each rule keeps
+// the HTTP rules of its policy, and the annotations of the policies are
+// combined. Traffic that no policy allowed is denied, as the policy lists
+// both policy types.
+func namespaceBaselines(policies []NetworkPolicy) []NetworkPolicy {
+	baselines := map[string]*NetworkPolicy{}
+	var namespaces []string
+	for _, policy := range policies {
+		namespace := policy.Metadata.Namespace
+		baseline, found := baselines[namespace]
+		if !found {
+			// The empty podSelector of a new policy selects all pods
+			created := newPolicy(namespaceBaselineName, namespace)
+			created.source = "namespace baseline"
+			created.allPods = true
+			created.Spec.PolicyTypes = []string{"Ingress", "Egress"}
+			baseline = &created
+			baselines[namespace] = baseline
+			namespaces = append(namespaces, namespace)
+		}
+		baseline.Spec.Ingress = mergeRules(baseline.Spec.Ingress, withHTTP(policy.Spec.Ingress, policy.http))
+		baseline.Spec.Egress = mergeRules(baseline.Spec.Egress, withHTTP(policy.Spec.Egress, policy.http))
+		mergeICMPs(baseline, policy.icmps)
+		mergeAnnotations(baseline, policy.Metadata.Annotations)
+	}
+	combined := make([]NetworkPolicy, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		combined = append(combined, *baselines[namespace])
+	}
+	return combined
+}
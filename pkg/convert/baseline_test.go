@@ -0,0 +1,49 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespaceBaselinesCombinePolicies(t *testing.T) {
+	peer := []NetworkPolicyPeer{{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "client"}}}}
+	echo := CiliumICMPField{Family: "IPv4", Type: 8}
+	api := newPolicy("api", "shop")
+	api.Metadata.Annotations = map[string]string{peerGroupsAnnotation: "clients", profileAnnotation: "HTTP", icmpAnnotation: "ICMP type 8"}
+	api.Spec.Ingress = []Rule{{From: peer, Ports: testPorts(t, "TCP", "8080")}}
+	api.http = []CiliumHTTPRule{{Method: "GET", Path: "/api"}}
+	api.icmps = []CiliumICMPField{echo}
+	web := newPolicy("web", "shop")
+	web.Metadata.Annotations = map[string]string{peerGroupsAnnotation: "clients, partners", profileAnnotation: "SSL"}
+	web.Spec.Ingress = []Rule{{From: peer, Ports: testPorts(t, "TCP", "443")}}
+	web.icmps = []CiliumICMPField{echo, {Family: "IPv4", Type: 0}}
+
+	baselines := namespaceBaselines([]NetworkPolicy{api, web})
+	if len(baselines) != 1 {
+		t.Fatalf("namespaceBaselines returned %d policies, want 1", len(baselines))
+	}
+	baseline := baselines[0]
+
+	// The HTTP rules only apply to the port of the api policy
+	ingress := ciliumPolicy(baseline).Spec.Ingress
+	if len(ingress) != 3 {
+		t.Fatalf("baseline has %d ingress rules, want the rules of both policies and the ICMP rule: %+v", len(ingress), ingress)
+	}
+	if rules := ingress[0].ToPorts[0].Rules; rules == nil || !reflect.DeepEqual(rules.HTTP, api.http) {
+		t.Errorf("HTTP rules of the api port = %+v, want %+v", rules, api.http)
+	}
+	if rules := ingress[1].ToPorts[0].Rules; rules != nil {
+		t.Errorf("web port got HTTP rules %+v", rules)
+	}
+	if want := []CiliumICMPField{echo, {Family: "IPv4", Type: 0}}; !reflect.DeepEqual(baseline.icmps, want) {
+		t.Errorf("ICMP fields = %+v, want %+v", baseline.icmps, want)
+	}
+	want := map[string]string{
+		peerGroupsAnnotation: "clients, partners",
+		profileAnnotation:    "HTTP | SSL",
+		icmpAnnotation:       "ICMP type 8",
+	}
+	if !reflect.DeepEqual(baseline.Metadata.Annotations, want) {
+		t.Errorf("annotations = %v, want %v", baseline.Metadata.Annotations, want)
+	}
+}
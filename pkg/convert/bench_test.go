@@ -0,0 +1,29 @@
+package convert
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"testing"
+)
+
+func BenchmarkServicePolicies(b *testing.B) {
+	data, err := ioutil.ReadFile(filepath.Join(repoRoot, "json/Example1.json"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	root, err := parseRoot(data)
+	if err != nil {
+		b.Fatal(err)
+	}
+	log.SetOutput(io.Discard)
+	opts := Options{Namespace: "default", Naming: NameOptions{Replacement: "-"}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		diag := newDiagnostics(verbosityQuiet)
+		servicePolicies(diag, root, opts)
+	}
+}
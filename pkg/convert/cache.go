@@ -0,0 +1,155 @@
+package convert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// fileFlags are the flags that write files, which a cached run would skip
+var fileFlags = []string{"out-dir", "tar", "coverage", "warnings-file", "metrics-file", "gen-test-script", "deletions-file", "dropped-rules"}
+
+// restoredFlags are the fileFlags whose file is stored with the cached
+// output and written again on a cache hit. The others cannot be combined
+// with -cache-dir.
+var restoredFlags = map[string]bool{"dropped-rules": true}
+
+// isFileFlag reports whether a flag names a file the run writes
+func isFileFlag(name string) bool {
+	for _, fileFlag := range fileFlags {
+		if fileFlag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey hashes the running executable, so a new version never reuses
+// output of an old one, with every flag set on the command line. The
+// content of each file or directory of export files named by a flag is
+// hashed too, so editing an input invalidates the cache. The files the run
+// writes are outputs, so only their names are hashed.
+func cacheKey() (string, error) {
+	h := sha256.New()
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if err := hashFile(h, executable); err != nil {
+		return "", err
+	}
+
+	var visitErr error
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "cache-dir" || visitErr != nil {
+			return
+		}
+		value := f.Value.String()
+		fmt.Fprintf(h, "-%s=%q\n", f.Name, value)
+		info, err := os.Stat(value)
+		switch {
+		case err != nil || isFileFlag(f.Name):
+		case info.Mode().IsRegular():
+			visitErr = hashFile(h, value)
+		case info.IsDir() && f.Name == "dir":
+			files, err := exportFiles(value)
+			if err != nil {
+				visitErr = err
+				return
+			}
+			for _, file := range files {
+				fmt.Fprintf(h, "%s\n", file)
+				if err := hashFile(h, file); err != nil {
+					visitErr = err
+					return
+				}
+			}
+		}
+	})
+	if visitErr != nil {
+		return "", visitErr
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile adds the size and content of a file to the hash
+func hashFile(h io.Writer, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(h, "%d\n", len(data))
+	_, err = h.Write(data)
+	return err
+}
+
+// cachePath returns the file the output for a cache key is stored in
+func cachePath(dir, key string) string {
+	return filepath.Join(dir, key+".yaml")
+}
+
+// writeCache stores the output for a cache key. The output is written to a
+// temporary file first, so a concurrent run never reads a partial entry.
+func writeCache(dir, key string, data []byte) error {
+	return writeCacheFile(dir, key, cachePath(dir, key), data)
+}
+
+// writeCacheFile atomically writes a file of the cache entry for a key
+func writeCacheFile(dir, key, path string, data []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cachedFilePath returns the file the output of a restored flag is stored in
+func cachedFilePath(dir, key, name string) string {
+	return filepath.Join(dir, key+"."+name)
+}
+
+// storeCachedFiles stores the files written for the restored flags, given
+// by flag name, with the cached output. They must be stored before the
+// output, whose presence makes a cache hit.
+func storeCachedFiles(dir, key string, files map[string]string) error {
+	for name, path := range files {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := writeCacheFile(dir, key, cachedFilePath(dir, key, name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreCachedFiles writes the stored files of the restored flags again
+func restoreCachedFiles(dir, key string, files map[string]string) error {
+	for name, path := range files {
+		data, err := ioutil.ReadFile(cachedFilePath(dir, key, name))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
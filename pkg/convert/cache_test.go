@@ -0,0 +1,105 @@
+package convert
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildBinary builds the program into a temporary directory, so tests can
+// run it like a user and keep the executable the cache key hashes fixed
+func buildBinary(t *testing.T) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "vmware-analyzer-to-netpol")
+	if output, err := exec.Command("go", "build", "-o", binary, "../..").CombinedOutput(); err != nil {
+		t.Fatalf("go build: %v\n%s", err, output)
+	}
+	return binary
+}
+
+func TestCacheHitRestoresDroppedRules(t *testing.T) {
+	binary := buildBinary(t)
+	dir := t.TempDir()
+	report := filepath.Join(dir, "dropped.yaml")
+	run := func() (string, string) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(binary, "-f", "../../json/gre-tunnel.json", "-cache-dir", filepath.Join(dir, "cache"), "-dropped-rules", report)
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("run: %v\n%s", err, stderr.String())
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	first, logged := run()
+	if strings.Contains(logged, "Using cached output") {
+		t.Fatal("first run used the cache")
+	}
+	want, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(want), "GRE") {
+		t.Fatalf("report does not list the GRE entry:\n%s", want)
+	}
+	if err := os.Remove(report); err != nil {
+		t.Fatal(err)
+	}
+
+	second, logged := run()
+	if !strings.Contains(logged, "Using cached output") {
+		t.Fatalf("second run did not use the cache:\n%s", logged)
+	}
+	if second != first {
+		t.Errorf("cached output differs:\n%s\nwant:\n%s", second, first)
+	}
+	got, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatalf("cache hit did not restore the report: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("restored report = %s, want %s", got, want)
+	}
+}
+
+func TestCacheHitAndMiss(t *testing.T) {
+	binary := buildBinary(t)
+	dir := t.TempDir()
+	export := filepath.Join(dir, "export.json")
+	if err := ioutil.WriteFile(export, []byte(directionsExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cached := func(args ...string) bool {
+		t.Helper()
+		var stderr bytes.Buffer
+		cmd := exec.Command(binary, append([]string{"-f", export, "-cache-dir", filepath.Join(dir, "cache")}, args...)...)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("run %v: %v\n%s", args, err, stderr.String())
+		}
+		return strings.Contains(stderr.String(), "Using cached output")
+	}
+
+	if cached() {
+		t.Error("first run used the cache")
+	}
+	if !cached() {
+		t.Error("repeated run did not use the cache")
+	}
+	if cached("-n", "other") {
+		t.Error("run with a different namespace used the cache")
+	}
+	if !cached("-n", "other") {
+		t.Error("repeated run with a different namespace did not use the cache")
+	}
+	if err := ioutil.WriteFile(export, []byte(strings.Replace(directionsExport, `"80"`, `"8080"`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cached() {
+		t.Error("run over an edited export used the cache")
+	}
+}
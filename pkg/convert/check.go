@@ -0,0 +1,66 @@
+package convert
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Problem is an issue found by -check
+type Problem struct {
+	Type    string
+	Message string
+}
+
+// checkProblems collects the warnings of the conversion and, when the build
+// supports it, the policies that fail Kubernetes validation
+func checkProblems(diag *diagnostics, policies []NetworkPolicy, maxPolicies int) []Problem {
+	var problems []Problem
+	for _, w := range diag.warnings {
+		problems = append(problems, Problem{Type: w.Type, Message: w.Message})
+	}
+	if k8sValidation {
+		for _, policy := range policies {
+			if err := validateK8s(policy); err != nil {
+				problems = append(problems, Problem{Type: "k8s-validation", Message: fmt.Sprintf("policy %s failed Kubernetes validation: %v", policy.Metadata.Name, err)})
+			}
+		}
+	}
+	if maxPolicies > 0 && len(policies) > maxPolicies {
+		problems = append(problems, Problem{Type: "max-policies", Message: fmt.Sprintf("conversion would generate %d policies of each output kind, exceeding -max-policies %d", len(policies), maxPolicies)})
+	}
+	return problems
+}
+
+// writeCheckReport prints the policy count, the number of problems of each
+// type and the first limit problems
+func writeCheckReport(w io.Writer, policies []NetworkPolicy, problems []Problem, limit int) {
+	fmt.Fprintf(w, "%d NetworkPolicies, %d problems\n", len(policies), len(problems))
+	if len(problems) == 0 {
+		return
+	}
+
+	counts := map[string]int{}
+	for _, problem := range problems {
+		counts[problem.Type]++
+	}
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Fprintf(w, "  %s: %d\n", t, counts[t])
+	}
+
+	shown := problems
+	if limit > 0 && len(shown) > limit {
+		shown = shown[:limit]
+	}
+	for _, problem := range shown {
+		fmt.Fprintf(w, "%s: %s\n", problem.Type, problem.Message)
+	}
+	if len(shown) < len(problems) {
+		fmt.Fprintf(w, "... and %d more\n", len(problems)-len(shown))
+	}
+}
@@ -0,0 +1,88 @@
+package convert
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	binary := buildBinary(t)
+	tests := []struct {
+		export string
+		clean  bool
+		report string
+	}{
+		{"multi-protocol.json", true, "1 NetworkPolicies, 0 problems\n"},
+		{"port-conflict.json", false, "1 NetworkPolicies, 2 problems\n" +
+			"  port-conflict: 2\n" +
+			"port-conflict: policy app-ports lists TCP/8000-8100 and TCP/8000-8200, which start at the same port, keeping TCP/8000-8200\n" +
+			"port-conflict: policy app-ports lists TCP/8000-8100 and TCP/8000, which start at the same port, keeping TCP/8000-8200\n"},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(binary, "-f", "json/"+tt.export, "-check")
+		cmd.Dir = repoRoot
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		if tt.clean && err != nil {
+			t.Errorf("%s: -check failed on a clean export: %v\n%s", tt.export, err, stderr.String())
+		}
+		if !tt.clean && err == nil {
+			t.Errorf("%s: -check succeeded on an export with problems", tt.export)
+		}
+		if stdout.String() != tt.report {
+			t.Errorf("%s: report =\n%s\nwant\n%s", tt.export, stdout.String(), tt.report)
+		}
+		if strings.Contains(stdout.String(), "apiVersion:") {
+			t.Errorf("%s: -check emitted policies", tt.export)
+		}
+	}
+}
+
+func TestWriteCheckReportLimit(t *testing.T) {
+	problems := []Problem{
+		{Type: "port-conflict", Message: "first"},
+		{Type: "unsupported-icmp", Message: "second"},
+		{Type: "port-conflict", Message: "third"},
+	}
+	var out bytes.Buffer
+	writeCheckReport(&out, make([]NetworkPolicy, 4), problems, 2)
+	want := "4 NetworkPolicies, 3 problems\n" +
+		"  port-conflict: 2\n" +
+		"  unsupported-icmp: 1\n" +
+		"port-conflict: first\n" +
+		"unsupported-icmp: second\n" +
+		"... and 1 more\n"
+	if out.String() != want {
+		t.Errorf("report =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestMaxPolicies(t *testing.T) {
+	binary := buildBinary(t)
+	tests := []struct {
+		args []string
+		ok   bool
+		log  string
+	}{
+		{[]string{"-max-policies", "-1"}, false, "-max-policies must not be negative, got -1"},
+		{[]string{"-max-policies", "1"}, false, "conversion would generate 2 policies of each output kind, exceeding -max-policies 1"},
+		// Both kinds of the two policies fit, as the limit is per kind
+		{[]string{"-max-policies", "2", "-output-kind", "networkpolicy,cilium"}, true, ""},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		cmd := exec.Command(binary, append([]string{"-f", "json/applied-to.json"}, tt.args...)...)
+		cmd.Dir = repoRoot
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		if tt.ok && err != nil {
+			t.Errorf("%v: %v\n%s", tt.args, err, stderr.String())
+		}
+		if !tt.ok && (err == nil || !strings.Contains(stderr.String(), tt.log)) {
+			t.Errorf("%v: err = %v, log =\n%s\nwant the error %q", tt.args, err, stderr.String(), tt.log)
+		}
+	}
+}
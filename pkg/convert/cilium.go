@@ -0,0 +1,220 @@
+package convert
+
+// ciliumNamespaceLabel is the prefix Cilium gives namespace labels in
+// endpoint selectors
+const ciliumNamespaceLabel = "k8s:io.cilium.k8s.namespace.labels."
+
+// ciliumPodNamespace is the endpoint label holding a pod's namespace. A
+// selector that mentions it is not restricted to the policy's namespace.
+const ciliumPodNamespace = "k8s:io.kubernetes.pod.namespace"
+
+// ciliumAPIVersion is the API version of CiliumNetworkPolicies
+const ciliumAPIVersion = "cilium.io/v2"
+
+// CiliumNetworkPolicy represents a cilium.io/v2 CiliumNetworkPolicy
+type CiliumNetworkPolicy struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Metadata   ObjectMeta       `yaml:"metadata"`
+	Spec       CiliumPolicySpec `yaml:"spec"`
+}
+
+// CiliumPolicySpec is the spec of a CiliumNetworkPolicy
+type CiliumPolicySpec struct {
+	EndpointSelector CiliumSelector      `yaml:"endpointSelector"`
+	Ingress          []CiliumIngressRule `yaml:"ingress,omitempty"`
+	Egress           []CiliumEgressRule  `yaml:"egress,omitempty"`
+}
+
+// CiliumSelector is a Cilium endpoint selector
+type CiliumSelector struct {
+	MatchLabels      map[string]string          `yaml:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `yaml:"matchExpressions,omitempty"`
+}
+
+// CiliumIngressRule is an ingress rule of a CiliumNetworkPolicy
+type CiliumIngressRule struct {
+	FromEndpoints []CiliumSelector `yaml:"fromEndpoints,omitempty"`
+	FromCIDRSet   []CiliumCIDR     `yaml:"fromCIDRSet,omitempty"`
+	FromEntities  []string         `yaml:"fromEntities,omitempty"`
+	ToPorts       []CiliumPortRule `yaml:"toPorts,omitempty"`
+	ICMPs         []CiliumICMPRule `yaml:"icmps,omitempty"`
+}
+
+// CiliumEgressRule is an egress rule of a CiliumNetworkPolicy
+type CiliumEgressRule struct {
+	ToEndpoints []CiliumSelector `yaml:"toEndpoints,omitempty"`
+	ToCIDRSet   []CiliumCIDR     `yaml:"toCIDRSet,omitempty"`
+	ToEntities  []string         `yaml:"toEntities,omitempty"`
+	ToPorts     []CiliumPortRule `yaml:"toPorts,omitempty"`
+}
+
+// CiliumCIDR is an entry of a fromCIDRSet or toCIDRSet
+type CiliumCIDR struct {
+	CIDR   string   `yaml:"cidr"`
+	Except []string `yaml:"except,omitempty"`
+}
+
+// CiliumPortRule is a toPorts entry of a Cilium rule
+type CiliumPortRule struct {
+	Ports []CiliumPort   `yaml:"ports"`
+	Rules *CiliumL7Rules `yaml:"rules,omitempty"`
+}
+
+// CiliumL7Rules are the L7 rules of a toPorts entry
+type CiliumL7Rules struct {
+	HTTP []CiliumHTTPRule `yaml:"http"`
+}
+
+// CiliumHTTPRule matches HTTP requests by method and path, both regular
+// expressions. An empty rule allows any request but still requires the
+// traffic to be HTTP.
+type CiliumHTTPRule struct {
+	Method string `yaml:"method,omitempty"`
+	Path   string `yaml:"path,omitempty"`
+}
+
+// CiliumPort is a port of a Cilium rule. Port "0" matches all ports.
+type CiliumPort struct {
+	Port     string `yaml:"port"`
+	EndPort  int    `yaml:"endPort,omitempty"`
+	Protocol string `yaml:"protocol"`
+}
+
+// ciliumPolicy converts a NetworkPolicy into the equivalent
+// CiliumNetworkPolicy. As in Kubernetes, a policy type without rules denies
+// all traffic in that direction, which Cilium expresses as a single empty rule.
+func ciliumPolicy(policy NetworkPolicy) CiliumNetworkPolicy {
+	cnp := CiliumNetworkPolicy{
+		APIVersion: ciliumAPIVersion,
+		Kind:       "CiliumNetworkPolicy",
+		Metadata:   policy.Metadata,
+	}
+	cnp.Spec.EndpointSelector = CiliumSelector{
+		MatchLabels:      policy.Spec.PodSelector.MatchLabels,
+		MatchExpressions: policy.Spec.PodSelector.MatchExpressions,
+	}
+
+	// Kubernetes treats a policy without policy types as an ingress policy
+	if len(policy.Spec.PolicyTypes) == 0 || hasPolicyType(policy, "Ingress") {
+		for _, rule := range policy.Spec.Ingress {
+			endpoints, cidrs, entities := ciliumPeers(rule.From)
+			cnp.Spec.Ingress = append(cnp.Spec.Ingress, CiliumIngressRule{
+				FromEndpoints: endpoints,
+				FromCIDRSet:   cidrs,
+				FromEntities:  entities,
+				ToPorts:       ciliumPorts(rule.Ports, ruleHTTP(policy, rule)),
+			})
+		}
+		// The ICMP types of a service are allowed from any source
+		if len(policy.icmps) > 0 {
+			cnp.Spec.Ingress = append(cnp.Spec.Ingress, CiliumIngressRule{
+				FromEntities: []string{"all"},
+				ICMPs:        []CiliumICMPRule{{Fields: policy.icmps}},
+			})
+		}
+		if len(cnp.Spec.Ingress) == 0 {
+			cnp.Spec.Ingress = []CiliumIngressRule{{}}
+		}
+	}
+	if hasPolicyType(policy, "Egress") {
+		for _, rule := range policy.Spec.Egress {
+			endpoints, cidrs, entities := ciliumPeers(rule.To)
+			if rule.apiServer {
+				// The entity matches the API server wherever it runs
+				endpoints, cidrs, entities = nil, nil, []string{apiServerEntity}
+			}
+			cnp.Spec.Egress = append(cnp.Spec.Egress, CiliumEgressRule{
+				ToEndpoints: endpoints,
+				ToCIDRSet:   cidrs,
+				ToEntities:  entities,
+				ToPorts:     ciliumPorts(rule.Ports, ruleHTTP(policy, rule)),
+			})
+		}
+		if len(cnp.Spec.Egress) == 0 {
+			cnp.Spec.Egress = []CiliumEgressRule{{}}
+		}
+	}
+	return cnp
+}
+
+// ciliumPeers converts NetworkPolicy peers into endpoint selectors and CIDRs.
+// No peers means any peer, which is the "all" entity.
+func ciliumPeers(peers []NetworkPolicyPeer) ([]CiliumSelector, []CiliumCIDR, []string) {
+	if len(peers) == 0 {
+		return nil, nil, []string{"all"}
+	}
+	var endpoints []CiliumSelector
+	var cidrs []CiliumCIDR
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			cidrs = append(cidrs, CiliumCIDR{CIDR: peer.IPBlock.CIDR, Except: peer.IPBlock.Except})
+			continue
+		}
+		selector := CiliumSelector{}
+		if peer.PodSelector != nil {
+			selector.MatchLabels = copyLabels(peer.PodSelector.MatchLabels)
+			selector.MatchExpressions = append(selector.MatchExpressions, peer.PodSelector.MatchExpressions...)
+		}
+		if peer.NamespaceSelector != nil {
+			if selector.MatchLabels == nil {
+				selector.MatchLabels = map[string]string{}
+			}
+			for key, value := range peer.NamespaceSelector.MatchLabels {
+				selector.MatchLabels[ciliumNamespaceLabel+key] = value
+			}
+			for _, expr := range peer.NamespaceSelector.MatchExpressions {
+				expr.Key = ciliumNamespaceLabel + expr.Key
+				selector.MatchExpressions = append(selector.MatchExpressions, expr)
+			}
+			selector.MatchExpressions = append(selector.MatchExpressions, LabelSelectorRequirement{Key: ciliumPodNamespace, Operator: "Exists"})
+		}
+		endpoints = append(endpoints, selector)
+	}
+	return endpoints, cidrs, nil
+}
+
+// ruleHTTP returns the HTTP rules of a rule: its own when it was combined
+// from another policy, otherwise those of the policy holding it
+func ruleHTTP(policy NetworkPolicy, rule Rule) []CiliumHTTPRule {
+	if rule.http != nil {
+		return rule.http
+	}
+	return policy.http
+}
+
+// ciliumPorts converts NetworkPolicy ports into a toPorts entry. No ports
+// means all ports, which Cilium expresses by leaving toPorts out. With HTTP
+// rules, the entry only allows matching requests when Cilium can inspect
+// every port.
+func ciliumPorts(ports []PortRule, http []CiliumHTTPRule) []CiliumPortRule {
+	if len(ports) == 0 {
+		return nil
+	}
+	rule := CiliumPortRule{Ports: make([]CiliumPort, 0, len(ports))}
+	for _, port := range ports {
+		cp := CiliumPort{Port: "0", Protocol: port.Protocol}
+		if port.Port != nil {
+			cp.Port = port.Port.String()
+		}
+		if port.EndPort != nil {
+			cp.EndPort = *port.EndPort
+		}
+		rule.Ports = append(rule.Ports, cp)
+	}
+	if len(http) > 0 && httpInspectable(ports) {
+		rule.Rules = &CiliumL7Rules{HTTP: http}
+	}
+	return []CiliumPortRule{rule}
+}
+
+// httpInspectable reports whether Cilium can apply L7 rules to the ports,
+// which must be single TCP ports
+func httpInspectable(ports []PortRule) bool {
+	for _, port := range ports {
+		if port.Protocol != "TCP" || port.Port == nil || port.EndPort != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configMapKey is the data key holding the policy YAML in a ConfigMap
+const configMapKey = "networkpolicy.yaml"
+
+// ConfigMap is a Kubernetes ConfigMap carrying a generated NetworkPolicy for
+// an operator to apply
+type ConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+}
+
+// ConfigMapOptions controls the ConfigMaps of -output-kind configmap
+type ConfigMapOptions struct {
+	// Prefix is put in front of the policy name to name its ConfigMap
+	Prefix string
+	Labels map[string]string
+}
+
+// defaultConfigMapPrefix is the -configmap-prefix default
+const defaultConfigMapPrefix = "netpol-"
+
+// validate checks that the prefix followed by a policy name is a valid
+// ConfigMap name, a DNS-1123 subdomain
+func (o ConfigMapOptions) validate() error {
+	if name := o.Prefix + "a"; len(o.Prefix)+maxNameLength > 253 || !labelPrefixPattern.MatchString(name) {
+		return fmt.Errorf("prefix %q followed by a policy name is not a valid DNS-1123 subdomain", o.Prefix)
+	}
+	return nil
+}
+
+// policyConfigMap marshals a policy as the ConfigMap carrying it
+type policyConfigMap struct {
+	policy NetworkPolicy
+	render RenderOptions
+}
+
+// MarshalYAML returns the ConfigMap, with the policy YAML as its data value
+func (c policyConfigMap) MarshalYAML() (interface{}, error) {
+	data, err := yaml.Marshal(&c.policy)
+	if err != nil {
+		return nil, err
+	}
+	cm := ConfigMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   ObjectMeta{Namespace: c.policy.Metadata.Namespace, Labels: copyLabels(c.render.ConfigMap.Labels)},
+		Data:       map[string]string{configMapKey: string(data)},
+	}
+	if c.policy.Metadata.GenerateName != "" {
+		cm.Metadata.GenerateName = c.render.ConfigMap.Prefix + c.policy.Metadata.GenerateName
+	} else {
+		cm.Metadata.Name = c.render.ConfigMap.Prefix + c.policy.Metadata.Name
+	}
+	cm.Metadata = trackMetadata(cm.Metadata, kindConfigMap, c.render.ArgoCD)
+	return cm, nil
+}
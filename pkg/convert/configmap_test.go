@@ -0,0 +1,60 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestPolicyConfigMap(t *testing.T) {
+	render := RenderOptions{ConfigMap: ConfigMapOptions{Prefix: "op-", Labels: map[string]string{"operator": "netpol"}}}
+
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	data, err := marshalYAML(renderPolicy(policies[0], kindConfigMap, render), defaultYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "data:\n  "+configMapKey+": |\n    apiVersion: networking.k8s.io/v1\n    kind: NetworkPolicy\n") {
+		t.Errorf("ConfigMap does not hold the policy as an indented block:\n%s", data)
+	}
+
+	var cm ConfigMap
+	if err := yaml.UnmarshalStrict(data, &cm); err != nil {
+		t.Fatalf("ConfigMap is not valid YAML: %v\n%s", err, data)
+	}
+	if cm.APIVersion != "v1" || cm.Kind != "ConfigMap" {
+		t.Errorf("ConfigMap is %s %s, want v1 ConfigMap", cm.APIVersion, cm.Kind)
+	}
+	if cm.Metadata.Name != "op-web" || cm.Metadata.Namespace != "default" {
+		t.Errorf("ConfigMap is %s/%s, want default/op-web", cm.Metadata.Namespace, cm.Metadata.Name)
+	}
+	if want := map[string]string{"operator": "netpol"}; !reflect.DeepEqual(cm.Metadata.Labels, want) {
+		t.Errorf("ConfigMap labels = %v, want %v", cm.Metadata.Labels, want)
+	}
+	if name := resourceName(policies[0], kindConfigMap, render); name != cm.Metadata.Name {
+		t.Errorf("resourceName = %q, want the ConfigMap name %q", name, cm.Metadata.Name)
+	}
+
+	want, err := yaml.Marshal(&policies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cm.Data[configMapKey]; got != string(want) {
+		t.Errorf("ConfigMap data =\n%s\nwant the policy\n%s", got, want)
+	}
+}
+
+func TestConfigMapOptionsValidate(t *testing.T) {
+	for _, prefix := range []string{"", defaultConfigMapPrefix, "netpol.", "team-a.netpol-"} {
+		if err := (ConfigMapOptions{Prefix: prefix}).validate(); err != nil {
+			t.Errorf("validate(%q) = %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"NetPol-", "netpol_", "-netpol", "netpol..", strings.Repeat("a", 200)} {
+		if err := (ConfigMapOptions{Prefix: prefix}).validate(); err == nil {
+			t.Errorf("validate accepted %q", prefix)
+		}
+	}
+}
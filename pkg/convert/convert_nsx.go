@@ -0,0 +1,1440 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ServiceEntry represents a single service entry
+type ServiceEntry struct {
+	DisplayName       string   `json:"display_name"`
+	ResourceType      string   `json:"resource_type"`
+	NestedServicePath string   `json:"nested_service_path"`
+	L4Protocol        string   `json:"l4_protocol"`
+	L4Protocols       []string `json:"l4_protocols"`
+	DestinationPorts  PortList `json:"destination_ports"`
+	SourcePorts       PortList `json:"source_ports"`
+	// Protocol, ICMPType and ICMPCode describe ICMP entries
+	Protocol string `json:"protocol"`
+	ICMPType *int   `json:"icmp_type"`
+	ICMPCode *int   `json:"icmp_code"`
+	// ProtocolNumber is the IP protocol of IPProtocolServiceEntry entries
+	ProtocolNumber *int `json:"protocol_number"`
+}
+
+// PortList is the ports of a service entry. Exports list them as strings
+// such as "80" or "1024-65535", but some encode single ports as numbers.
+type PortList []string
+
+// UnmarshalJSON accepts both string and numeric ports
+func (p *PortList) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if items == nil {
+		*p = nil
+		return nil
+	}
+	ports := make(PortList, 0, len(items))
+	for _, item := range items {
+		var port string
+		if err := json.Unmarshal(item, &port); err != nil {
+			var number json.Number
+			if err := json.Unmarshal(item, &number); err != nil {
+				return fmt.Errorf("port %s is neither a string nor a number", item)
+			}
+			port = number.String()
+		}
+		ports = append(ports, port)
+	}
+	*p = ports
+	return nil
+}
+
+// protocols returns the entry's L4 protocols, preferring the l4_protocols
+// list over the singular l4_protocol when both are present
+func (e ServiceEntry) protocols() []string {
+	if len(e.L4Protocols) > 0 {
+		return e.L4Protocols
+	}
+	return []string{e.L4Protocol}
+}
+
+// Service represents a service with its entries
+type Service struct {
+	ID             string         `json:"id"`
+	Path           string         `json:"path"`
+	DisplayName    string         `json:"display_name"`
+	ServiceEntries []ServiceEntry `json:"service_entries"`
+	Children       []ServiceChild `json:"children"`
+	Tags           []Tag          `json:"tags"`
+	// AppliedTo limits where the service is enforced. ANY or ALL means
+	// every workload.
+	AppliedTo []string `json:"applied_to"`
+}
+
+// appliesToAll reports whether the service is applied to every workload
+func (s Service) appliesToAll() bool {
+	for _, target := range s.AppliedTo {
+		if strings.EqualFold(target, "ANY") || strings.EqualFold(target, "ALL") {
+			return true
+		}
+	}
+	return false
+}
+
+// Tag is an NSX scoped tag
+type Tag struct {
+	Scope string `json:"scope"`
+	Tag   string `json:"tag"`
+}
+
+// ServiceChild is an item of a service's children array in a Policy API
+// export. Entries are either wrapped as a ChildServiceEntry or listed directly.
+type ServiceChild struct {
+	ResourceType    string        `json:"resource_type"`
+	MarkedForDelete bool          `json:"marked_for_delete"`
+	ServiceEntry    *ServiceEntry `json:"ServiceEntry"`
+}
+
+// UnmarshalJSON decodes a child, keeping its entry data whether it is wrapped
+// or not. Children of other resource types are kept without an entry.
+func (c *ServiceChild) UnmarshalJSON(data []byte) error {
+	type child ServiceChild
+	if err := json.Unmarshal(data, (*child)(c)); err != nil {
+		return err
+	}
+	if c.ResourceType != "ChildServiceEntry" && strings.HasSuffix(c.ResourceType, "ServiceEntry") {
+		var entry ServiceEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return err
+		}
+		c.ServiceEntry = &entry
+	}
+	return nil
+}
+
+// Root represents the root of the JSON structure
+type Root struct {
+	Services        []Service        `json:"services"`
+	Domains         []Domain         `json:"domains"`
+	ContextProfiles []ContextProfile `json:"context_profiles"`
+	Segments        []Segment        `json:"segments"`
+}
+
+// NetworkPolicy represents a Kubernetes NetworkPolicy. The fields of the
+// policy types are declared in the order their keys are written.
+type NetworkPolicy struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata"`
+	Spec       PolicySpec `yaml:"spec"`
+
+	// source is the NSX service or rule the policy was generated from
+	source string
+	// http holds the HTTP rules of the source rule's context profiles
+	http []CiliumHTTPRule
+	// icmps holds the ICMP types of the source service
+	icmps []CiliumICMPField
+	// allPods is set when the empty podSelector selecting all pods is
+	// intended, e.g. for a service applied to all workloads
+	allPods bool
+}
+
+// ObjectMeta is the metadata of a generated NetworkPolicy
+type ObjectMeta struct {
+	Name         string            `yaml:"name,omitempty"`
+	GenerateName string            `yaml:"generateName,omitempty"`
+	Namespace    string            `yaml:"namespace,omitempty"`
+	Labels       map[string]string `yaml:"labels,omitempty"`
+	Annotations  map[string]string `yaml:"annotations,omitempty"`
+
+	OwnerReferences []OwnerReference `yaml:"ownerReferences,omitempty"`
+}
+
+// PolicySpec is the spec of a NetworkPolicy
+type PolicySpec struct {
+	PodSelector PodSelector `yaml:"podSelector"`
+	PolicyTypes []string    `yaml:"policyTypes"`
+	Ingress     []Rule      `yaml:"ingress,omitempty"`
+	Egress      []Rule      `yaml:"egress,omitempty"`
+}
+
+// PodSelector selects the pods a NetworkPolicy applies to. Unlike a peer
+// LabelSelector, an empty matchLabels is kept to select all pods.
+type PodSelector struct {
+	MatchLabels      map[string]string          `yaml:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `yaml:"matchExpressions,omitempty"`
+}
+
+// Rule is an ingress or egress rule of a NetworkPolicy. Ingress rules use
+// From and egress rules use To.
+type Rule struct {
+	From  []NetworkPolicyPeer `yaml:"from,omitempty"`
+	To    []NetworkPolicyPeer `yaml:"to,omitempty"`
+	Ports []PortRule          `yaml:"ports,omitempty"`
+
+	// apiServer is set on the rule allowing egress to the API server
+	apiServer bool
+	// http holds the HTTP rules of the policy the rule came from, once it is
+	// combined with the rules of other policies. A nil http defers to the
+	// HTTP rules of the policy holding the rule.
+	http []CiliumHTTPRule
+}
+
+// PortRule is a single port or port range of a NetworkPolicy rule. A nil
+// Port matches all ports of the protocol, and EndPort is set for ranges.
+type PortRule struct {
+	Port     *IntOrString `yaml:"port,omitempty"`
+	EndPort  *int         `yaml:"endPort,omitempty"`
+	Protocol string       `yaml:"protocol"`
+}
+
+// NetworkPolicyPeer represents a from/to peer of a NetworkPolicy rule
+type NetworkPolicyPeer struct {
+	PodSelector       *LabelSelector `yaml:"podSelector,omitempty"`
+	NamespaceSelector *LabelSelector `yaml:"namespaceSelector,omitempty"`
+	IPBlock           *IPBlock       `yaml:"ipBlock,omitempty"`
+}
+
+// LabelSelector represents a Kubernetes label selector
+type LabelSelector struct {
+	MatchLabels      map[string]string          `yaml:"matchLabels,omitempty"`
+	MatchExpressions []LabelSelectorRequirement `yaml:"matchExpressions,omitempty"`
+}
+
+// LabelSelectorRequirement represents a single matchExpressions entry
+type LabelSelectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values,omitempty"`
+}
+
+// IPBlock represents a CIDR peer of a NetworkPolicy rule
+type IPBlock struct {
+	CIDR   string   `yaml:"cidr"`
+	Except []string `yaml:"except,omitempty"`
+}
+
+// Options holds the settings that shape a conversion
+type Options struct {
+	Namespace string
+	Naming    NameOptions
+	// ScopeLabels maps an NSX rule scope to namespace labels
+	ScopeLabels map[string]map[string]string
+	// ZeroMeansAll treats port 0 as all ports instead of rejecting it
+	ZeroMeansAll bool
+	// IPFamily limits the ipBlock peers to ipv4 or ipv6 addresses, or
+	// keeps both for dual
+	IPFamily string
+	// FullRangeAsAll treats a range over every port as all ports
+	FullRangeAsAll bool
+	// IncludeDisabled converts disabled rules too, instead of skipping them
+	IncludeDisabled bool
+	// TagLabels maps NSX tag scopes to the policy label keys they are copied to
+	TagLabels map[string]string
+	// ServiceLabels maps service display names to extra podSelector labels
+	ServiceLabels map[string]map[string]string
+	// VMLabels maps VM names or IDs to the labels of the pods they became
+	VMLabels map[string]map[string]string
+	// GroupLabels maps group paths or display names to the labels of their
+	// pods and namespaces
+	GroupLabels map[string]GroupLabels
+	// PeerGroups annotates rule policies with the groups of their peers
+	PeerGroups bool
+	// AllPods selects every pod in the namespace in the service policies
+	AllPods bool
+	// Overrides maps service display names to settings that replace the
+	// generated ones
+	Overrides map[string]ServiceOverride
+	// NamespaceLookup resolves the namespace of each service policy instead
+	// of Namespace when set
+	NamespaceLookup *NamespaceLookup
+}
+
+// NameOptions controls how display names are turned into DNS-1123 labels
+type NameOptions struct {
+	// Replacement is substituted for each run of invalid characters. The
+	// zero value strips them, while the command line default is "-".
+	Replacement string
+	// Collapse merges consecutive replacements into a single one
+	Collapse bool
+	// Transliterate romanizes accented characters before replacing them
+	Transliterate bool
+	// FromID names policies after the NSX id of their service or rule
+	// instead of its display name
+	FromID bool
+}
+
+// Main runs the command line converter
+func Main() {
+	// Command-line flags for the JSON file path and namespace
+	jsonFile := flag.String("f", "", "Path to the JSON file containing service data")
+	exportDir := flag.String("dir", "", "Directory of .json and .json.gz export files to merge and convert instead of -f")
+	namespace := flag.String("n", "default", "Kubernetes namespace for the NetworkPolicy")
+	namespaceFile := flag.String("output-namespace-from-file", "", "CSV or JSON file mapping application IDs to namespaces, resolving the namespace of each service policy (unmapped services use -n)")
+	namespaceIDTag := flag.String("namespace-id-tag", "", "With -output-namespace-from-file, scope of the service tag holding the application ID instead of the service ID")
+	nameFrom := flag.String("name-from", nameFromDisplayName, "Name policies after the NSX display-name or the id of their service or rule")
+	nameReplacement := flag.String("name-replacement", "-", "String substituted for invalid characters in policy names")
+	collapseReplacements := flag.Bool("collapse-replacements", false, "Merge consecutive replacements in policy names into one")
+	transliterateNames := flag.Bool("transliterate", false, "Romanize accented characters in display names before sanitizing, e.g. café becomes cafe")
+	var scopeLabels stringList
+	flag.Var(&scopeLabels, "scope-label", "Map a rule scope to a namespace label as scope:key=value (repeatable)")
+	var namespaceSelector stringList
+	flag.Var(&namespaceSelector, "namespace-label-selector", "Only allow traffic from and to namespaces with this key=value label in every rule (repeatable)")
+	var tagLabelScopes stringList
+	flag.Var(&tagLabelScopes, "tag-label", "Copy service tags with this scope into policy labels as scope or scope=key (repeatable)")
+	var egressAllowCIDRs stringList
+	flag.Var(&egressAllowCIDRs, "egress-allow-cidr", "Allow egress to this CIDR on every policy that restricts egress (repeatable)")
+	allowSameNamespaceFlag := flag.Bool("allow-same-namespace", false, "Allow egress to every pod of the policy's own namespace on every policy that restricts egress")
+	allowSameNamespaceIngress := flag.Bool("allow-same-namespace-ingress", false, "With -allow-same-namespace, also allow ingress from every pod of the policy's own namespace")
+	allowAPIServerFlag := flag.Bool("allow-apiserver", false, "Allow egress to the Kubernetes API server on every policy that restricts egress")
+	apiServerCIDR := flag.String("apiserver-cidr", "", "With -allow-apiserver, address or CIDR of the API server endpoints (optional with -output-kind cilium)")
+	apiServerPort := flag.Int("apiserver-port", defaultAPIServerPort, "With -allow-apiserver, TCP port of the API server endpoints")
+	labelPrefix := flag.String("label-prefix", "", "Prefix for the generated selector and metadata label keys, e.g. nsx.example.com/")
+	var globalSelector stringList
+	flag.Var(&globalSelector, "global-selector", "Select pods with this key=value label in every policy instead of the per-service app label (repeatable)")
+	splitDirection := flag.Bool("split-direction", false, "Emit separate -ingress and -egress policies for services with both directions")
+	overridesFile := flag.String("overrides", "", "YAML file of per-service overrides for namespace, podSelector, peers and direction")
+	allPods := flag.Bool("all-pods", false, "Select every pod in the namespace in the service policies instead of the per-service app label")
+	peerGroups := flag.Bool("annotate-peer-groups", false, "Annotate rule policies with the NSX groups their peers are built from")
+	groupLabelsFile := flag.String("group-labels", "", "YAML file mapping group paths or display names to the pod and namespace labels of their workloads")
+	vmLabelsFile := flag.String("vm-labels", "", "YAML file mapping VM names or IDs to pod labels, selected by the rules applied to those VMs")
+	serviceLabelsFile := flag.String("service-labels", "", "YAML file mapping service display names to extra podSelector labels")
+	policyTypesFlag := flag.String("policy-types", "", "Comma-separated policy types listed in every policy instead of the inferred ones: Ingress, Egress")
+	namespaceBaseline := flag.Bool("namespace-baseline", false, "Combine the policies of each namespace into one policy that selects all pods, denies both directions by default and allows the union of their rules")
+	mergeEgressFlag := flag.Bool("merge-egress", false, "Move all egress rules into one egress-only policy per namespace that selects all pods")
+	portNamesFile := flag.String("port-names", "", "YAML file mapping numeric ports to container port names")
+	zeroMeansAll := flag.Bool("zero-means-all", false, "Treat port 0 as all ports instead of rejecting it")
+	ipFamily := flag.String("ip-family", familyDual, "Address family of the ipBlock peers generated from group addresses: ipv4, ipv6 or dual")
+	fullRangeAsAll := flag.Bool("fullrange-as-all", false, "Treat a port range covering every port, such as 1-65535, as all ports of the protocol instead of an endPort range")
+	strictDNS := flag.Bool("strict-dns", false, "Fail if any service or rule display name is not already a valid DNS-1123 name")
+	failOnUnsupported := flag.Bool("fail-on-unsupported", false, "Fail if any NSX construct cannot be fully represented in the output kinds, listing each one")
+	strict := flag.Bool("strict", false, "Fail on data-quality problems in the export instead of warning")
+	mergeRangesFlag := flag.Bool("merge-ranges", false, "Coalesce overlapping and adjacent port ranges per protocol")
+	mergeRulesFlag := flag.Bool("merge-rules", false, "Combine the rules of each direction that have identical peers into one rule with all of their ports")
+	allowWideOpen := flag.Bool("allow-wide-open", false, "Emit policies that allow all traffic on any port instead of skipping them")
+	requirePeers := flag.Bool("require-peers", false, "Fail if any rule has no from or to peers and so is open to any source or destination")
+	convertRules := flag.Bool("rules", false, "Also generate NetworkPolicies from the security policy rules in the export")
+	servicesPath := flag.String("services-path", "", "JSONPath selecting the service objects of an export with a custom shape, e.g. $.data.items[*]")
+	namePath := flag.String("name-path", "", "JSONPath of a service's name within a service object (default "+defaultNamePath+")")
+	entriesPath := flag.String("entries-path", "", "JSONPath of a service's entries within a service object (default "+defaultEntriesPath+")")
+	portsPath := flag.String("ports-path", "", "JSONPath of an entry's ports within an entry object (default "+defaultPortsPath+")")
+	protocolPath := flag.String("protocol-path", "", "JSONPath of an entry's protocols within an entry object (default "+defaultProtocolPath+")")
+	basePolicyFile := flag.String("base-policy", "", "YAML NetworkPolicy whose rules are merged into every generated policy")
+	outputFormat := flag.String("format", formatYAML, "Format of the policies printed to stdout: yaml, or jsonl for one compact JSON object per line")
+	templateFile := flag.String("template", "", "Render each NetworkPolicy with this Go text/template file instead of YAML")
+	templateDir := flag.String("template-dir", "", "With -out-dir or -tar, render each NetworkPolicy with every Go text/template file of this directory, one file per template and policy, instead of YAML")
+	k8sValidate := flag.Bool("k8s-validate", false, "Validate each NetworkPolicy against the Kubernetes networking/v1 types (requires -tags k8svalidate)")
+	ownerAPIVersion := flag.String("owner-api-version", "", "API version of the object that owns the generated policies, e.g. apps/v1")
+	ownerKind := flag.String("owner-kind", "", "Kind of the object that owns the generated policies")
+	ownerName := flag.String("owner-name", "", "Name of the object that owns the generated policies")
+	ownerUID := flag.String("owner-uid", "", "UID of the object that owns the generated policies")
+	outputKind := flag.String("output-kind", kindNetworkPolicy, "Comma-separated kinds of policy to emit: networkpolicy, cilium, configmap")
+	argoApp := flag.String("argocd-app", "", "Annotate the emitted objects for tracking by this ArgoCD Application")
+	argoLabel := flag.Bool("argocd-instance-label", false, "With -argocd-app, also set the app.kubernetes.io/instance label to the Application name")
+	configMapPrefix := flag.String("configmap-prefix", defaultConfigMapPrefix, "With -output-kind configmap, prefix of the ConfigMap names")
+	var configMapLabels stringList
+	flag.Var(&configMapLabels, "configmap-label", "With -output-kind configmap, key=value label of the ConfigMaps (repeatable)")
+	yamlIndent := flag.Int("yaml-indent", defaultYAML.Indent, "Number of spaces per indentation level in YAML output")
+	yamlStyle := flag.String("yaml-style", defaultYAML.Style, "YAML style for lists and maps of scalars: block or flow")
+	outDir := flag.String("out-dir", "", "Write each NetworkPolicy to <out-dir>/<name>.yaml instead of stdout")
+	header := flag.String("header", "", "Comment to put at the top of the YAML output and of each -out-dir or -tar file, e.g. \"Generated by vmware-analyzer-to-netpol\"")
+	layout := flag.String("layout", layoutFlat, "Layout of the -out-dir or -tar files: flat, or namespace for <namespace>/<name>.yaml")
+	kustomize := flag.Bool("kustomize", false, "With -out-dir or -tar, also write a kustomization.yaml listing the policy files, one per namespace directory with -layout namespace")
+	tarFile := flag.String("tar", "", "Write the per-policy files into this tar archive instead of stdout (gzip-compressed for .tar.gz or .tgz, - for stdout)")
+	indexFile := flag.String("index", "", "With -out-dir or -tar, also write an index of the generated policies to this file in the directory (.json or .yaml)")
+	metricsFile := flag.String("metrics-file", "", "Write conversion metrics to this file in the Prometheus text format")
+	coverageFile := flag.String("coverage", "", "Write a report of which input services produced policy rules to this file (.json or .yaml)")
+	droppedFile := flag.String("dropped-rules", "", "Write a report of the service entries dropped because Kubernetes cannot express their protocol to this file (.json or .yaml)")
+	warningsFile := flag.String("warnings-file", "", "Write the warnings produced during the run to this file as JSON")
+	verbosity := flag.Int("v", verbosityWarnings, "Diagnostics to log: 0 for none, 1 for warnings, 2 for warnings and the defaults applied")
+	explain := flag.String("explain", "", "Print the decisions made for each entry of this service instead of emitting policies")
+	portUsageFormat := flag.String("port-usage", "", "Print how many services use each port and protocol of the export, as a table or csv, instead of emitting policies")
+	testScript := flag.String("gen-test-script", "", "Write a shell script probing every allowed ingress port from a test pod to this file")
+	testPod := flag.String("test-pod", "netpol-probe", "Name of the pod the -gen-test-script probes run from")
+	testPodNamespace := flag.String("test-pod-namespace", "default", "Namespace of the -test-pod")
+	generateName := flag.Bool("generate-name", false, "Set metadata.generateName from the policy name instead of metadata.name")
+	printApply := flag.Bool("print-apply", false, "Print the kubectl apply commands for the policies instead of the YAML")
+	since := flag.String("since", "", "Previous export to compare with: only emit policies that were added or changed since it")
+	deletionsFile := flag.String("deletions-file", "", "With -since, write a manifest of the policies deleted since the previous export to this file")
+	normalizeNames := flag.Bool("normalize-names", false, "Print the policy name generated for each NSX display name instead of emitting policies")
+	check := flag.Bool("check", false, "Only parse and validate the export, reporting problems instead of emitting policies")
+	checkLimit := flag.Int("check-limit", 20, "Number of problems -check lists (0 lists all)")
+	deletionsFormat := flag.String("deletions-format", deletionsDelete, "Format of the -deletions-file: delete for kubectl delete -f, or patch for kustomize $patch: delete patches")
+	explicitEndPort := flag.Bool("explicit-endport", false, "Give single numeric ports an endPort equal to the port, for controllers that expect ranges")
+	noEndPort := flag.Bool("no-endport", false, "Expand port ranges into single ports instead of using endPort, for clusters without endPort support")
+	noEndPortMax := flag.Int("no-endport-max", 32, "With -no-endport, the most ports a range is expanded into; larger ranges are dropped with a warning")
+	maxPortsPerRule := flag.Int("max-ports-per-rule", 0, "Split rules with more ports than this into several rules with the same peers (0 means unlimited)")
+	cacheDir := flag.String("cache-dir", "", "Reuse the YAML output of an earlier run with the same executable, flags and input files from this directory")
+	includeDisabled := flag.Bool("include-disabled", false, "With -rules, also convert the rules disabled in NSX, annotating their policies")
+	flowsFile := flag.String("flows", "", "YAML file of sample flows and whether they should be allowed, checked against the generated policies")
+	watchInput := flag.Bool("watch", false, "Convert again each time the -f file or the -dir export files change, until interrupted")
+	maxPolicies := flag.Int("max-policies", 0, "Abort if more than this many policies of each output kind would be generated (0 means unlimited)")
+	managedByFlag := flag.Bool("managed-by-label", false, "Label every policy "+managedByLabel+"="+managedByValue+", which -list-unmanaged looks for")
+	listUnmanaged := flag.Bool("list-unmanaged", false, "List the NetworkPolicies of the -n namespace in the cluster that lack the -managed-by-label label, instead of converting (requires -tags k8sclient)")
+	kubeconfig := flag.String("kubeconfig", "", "With -list-unmanaged, kubeconfig file of the cluster (default $KUBECONFIG or ~/.kube/config)")
+	maxOutputBytes := flag.Int("max-output-bytes", 0, "Abort if the output on stdout would be larger than this many bytes (0 means unlimited)")
+	flag.Parse()
+
+	// Before applying or pruning, show which policies other tools manage
+	if *listUnmanaged {
+		if !k8sClient {
+			log.Fatal("Error: -list-unmanaged requires building with -tags k8sclient")
+		}
+		listed, names, err := unmanagedPolicies(*kubeconfig, *namespace)
+		if err != nil {
+			log.Fatalf("Error listing NetworkPolicies: %v", err)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		log.Printf("%d NetworkPolicies in namespace %s lack the %s=%s label and are not managed by this tool", len(names), listed, managedByLabel, managedByValue)
+		return
+	}
+	if *kubeconfig != "" {
+		log.Fatal("Error: -kubeconfig requires -list-unmanaged")
+	}
+
+	if *jsonFile == "" && *exportDir == "" {
+		log.Fatal("Usage: go run main.go -f <path_to_json_file> -n <namespace>")
+	}
+	if *jsonFile != "" && *exportDir != "" {
+		log.Fatal("Error: -f cannot be combined with -dir")
+	}
+	if *maxPolicies < 0 {
+		log.Fatalf("Error: -max-policies must not be negative, got %d", *maxPolicies)
+	}
+
+	if *indexFile != "" && *outDir == "" && *tarFile == "" {
+		log.Fatal("Error: -index requires -out-dir or -tar")
+	}
+	if *tarFile != "" && (*outDir != "" || *templateFile != "" || *printApply) {
+		log.Fatal("Error: -tar cannot be combined with -out-dir, -template or -print-apply")
+	}
+	if *maxOutputBytes < 0 {
+		log.Fatalf("Error: -max-output-bytes must not be negative, got %d", *maxOutputBytes)
+	}
+	if *maxOutputBytes > 0 && (*outDir != "" || *tarFile != "" || *templateFile != "" || *templateDir != "" || *printApply) {
+		log.Fatal("Error: -max-output-bytes only applies to the YAML or JSON lines output on stdout, not to -out-dir, -tar, -template, -template-dir or -print-apply")
+	}
+	if *deletionsFormat != deletionsDelete && *deletionsFormat != deletionsPatch {
+		log.Fatalf("Error: -deletions-format %q must be %s or %s", *deletionsFormat, deletionsDelete, deletionsPatch)
+	}
+	if *portUsageFormat != "" && *portUsageFormat != usageTable && *portUsageFormat != usageCSV {
+		log.Fatalf("Error: -port-usage %q must be %s or %s", *portUsageFormat, usageTable, usageCSV)
+	}
+	if *ipFamily != familyIPv4 && *ipFamily != familyIPv6 && *ipFamily != familyDual {
+		log.Fatalf("Error: -ip-family %q must be %s, %s or %s", *ipFamily, familyIPv4, familyIPv6, familyDual)
+	}
+	if *allowSameNamespaceIngress && !*allowSameNamespaceFlag {
+		log.Fatal("Error: -allow-same-namespace-ingress requires -allow-same-namespace")
+	}
+	if *noEndPortMax < 1 {
+		log.Fatal("Error: -no-endport-max must be at least 1")
+	}
+	if *noEndPort && *explicitEndPort {
+		log.Fatal("Error: -no-endport cannot be combined with -explicit-endport")
+	}
+	if *maxPortsPerRule < 0 {
+		log.Fatal("Error: -max-ports-per-rule cannot be negative")
+	}
+	if *namespaceIDTag != "" && *namespaceFile == "" {
+		log.Fatal("Error: -namespace-id-tag requires -output-namespace-from-file")
+	}
+	if *deletionsFile != "" && *since == "" {
+		log.Fatal("Error: -deletions-file requires -since")
+	}
+	if *generateName {
+		log.Print("Warning: -generate-name policies get a new name each time they are created, so they cannot be applied idempotently and must be created with kubectl create")
+	}
+	if *printApply && *templateFile != "" {
+		log.Fatal("Error: -print-apply cannot be combined with -template")
+	}
+	if *header != "" && *templateFile != "" {
+		log.Fatal("Error: -header cannot be combined with -template")
+	}
+	if *outDir != "" && *templateFile != "" {
+		log.Fatal("Error: -out-dir cannot be combined with -template")
+	}
+	if *outputFormat != formatYAML && *outputFormat != formatJSONL {
+		log.Fatalf("Error: -format %q must be %s or %s", *outputFormat, formatYAML, formatJSONL)
+	}
+	if *outputFormat == formatJSONL && (*outDir != "" || *tarFile != "" || *templateFile != "" || *templateDir != "" || *printApply || *header != "") {
+		log.Fatal("Error: -format jsonl only applies to the policies printed to stdout, and cannot be combined with -out-dir, -tar, -template, -template-dir, -print-apply or -header")
+	}
+	if *layout != layoutFlat && *layout != layoutNamespace {
+		log.Fatalf("Error: -layout %q must be %s or %s", *layout, layoutFlat, layoutNamespace)
+	}
+	if (*layout != layoutFlat || *kustomize) && *outDir == "" && *tarFile == "" {
+		log.Fatal("Error: -layout and -kustomize require -out-dir or -tar")
+	}
+	if *templateDir != "" {
+		switch {
+		case *outDir == "" && *tarFile == "":
+			log.Fatal("Error: -template-dir requires -out-dir or -tar")
+		case *templateFile != "" || *header != "" || *indexFile != "" || *printApply || *layout != layoutFlat || *kustomize:
+			log.Fatal("Error: -template-dir cannot be combined with -template, -header, -index, -print-apply, -layout or -kustomize")
+		}
+	}
+	kinds, err := parseOutputKinds(*outputKind)
+	if err != nil {
+		log.Fatalf("Error parsing -output-kind: %v", err)
+	}
+	if (*templateFile != "" || *templateDir != "") && (len(kinds) != 1 || kinds[0] != kindNetworkPolicy) {
+		log.Fatal("Error: -template and -template-dir can only be used with -output-kind networkpolicy")
+	}
+	render := RenderOptions{
+		ArgoCD:    ArgoCDOptions{App: *argoApp, InstanceLabel: *argoLabel},
+		ConfigMap: ConfigMapOptions{Prefix: *configMapPrefix},
+	}
+	if err := render.ArgoCD.validate(); err != nil {
+		log.Fatalf("Error parsing ArgoCD flags: %v", err)
+	}
+	if render.ArgoCD.App != "" && *generateName {
+		log.Fatal("Error: -argocd-app cannot be combined with -generate-name, as ArgoCD tracks objects by name")
+	}
+	if err := render.ConfigMap.validate(); err != nil {
+		log.Fatalf("Error parsing -configmap-prefix: %v", err)
+	}
+	render.ConfigMap.Labels, err = parseLabels(configMapLabels)
+	if err != nil {
+		log.Fatalf("Error parsing -configmap-label: %v", err)
+	}
+	yamlOpts := YAMLOptions{Indent: *yamlIndent, Style: *yamlStyle}
+	if err := yamlOpts.validate(); err != nil {
+		log.Fatalf("Error parsing YAML flags: %v", err)
+	}
+	if *k8sValidate && !k8sValidation {
+		log.Fatal("Error: -k8s-validate requires building with -tags k8svalidate")
+	}
+	if !validNameReplacement.MatchString(*nameReplacement) {
+		log.Fatalf("Error: -name-replacement %q may only contain lowercase alphanumerics and '-'", *nameReplacement)
+	}
+	if *nameFrom != nameFromDisplayName && *nameFrom != nameFromID {
+		log.Fatalf("Error: -name-from must be %s or %s, got %q", nameFromDisplayName, nameFromID, *nameFrom)
+	}
+	scopes, err := parseScopeLabels(scopeLabels)
+	if err != nil {
+		log.Fatalf("Error parsing -scope-label: %v", err)
+	}
+	selectorLabels, err := parseLabels(globalSelector)
+	if err != nil {
+		log.Fatalf("Error parsing -global-selector: %v", err)
+	}
+	namespaceLabels, err := parseLabels(namespaceSelector)
+	if err != nil {
+		log.Fatalf("Error parsing -namespace-label-selector: %v", err)
+	}
+	tagScopes, err := parseTagLabels(tagLabelScopes)
+	if err != nil {
+		log.Fatalf("Error parsing -tag-label: %v", err)
+	}
+	owner, err := parseOwner(*ownerAPIVersion, *ownerKind, *ownerName, *ownerUID)
+	if err != nil {
+		log.Fatalf("Error parsing owner flags: %v", err)
+	}
+	egressCIDRs, err := parseCIDRs(egressAllowCIDRs)
+	if err != nil {
+		log.Fatalf("Error parsing -egress-allow-cidr: %v", err)
+	}
+	var apiServer *APIServerEgress
+	if *allowAPIServerFlag {
+		apiServer = &APIServerEgress{Port: *apiServerPort}
+		if *apiServerCIDR != "" {
+			// A single address is the usual case, so take it as a host CIDR
+			prefixes, err := parsePrefixes(*apiServerCIDR)
+			if err != nil || len(prefixes) != 1 {
+				log.Fatalf("Error: -apiserver-cidr %q must be an address or a CIDR", *apiServerCIDR)
+			}
+			apiServer.CIDR = prefixes[0].String()
+		} else if len(kinds) != 1 || kinds[0] != kindCilium {
+			log.Fatal("Error: -allow-apiserver needs -apiserver-cidr, except with -output-kind cilium")
+		}
+		if apiServer.Port < 1 || apiServer.Port > maxPort {
+			log.Fatalf("Error: -apiserver-port %d must be between 1 and %d", apiServer.Port, maxPort)
+		}
+	}
+	if *watchInput {
+		watch(*jsonFile, *exportDir)
+		return
+	}
+	opts := Options{
+		Namespace:       *namespace,
+		Naming:          NameOptions{Replacement: *nameReplacement, Collapse: *collapseReplacements, Transliterate: *transliterateNames, FromID: *nameFrom == nameFromID},
+		ScopeLabels:     scopes,
+		ZeroMeansAll:    *zeroMeansAll,
+		FullRangeAsAll:  *fullRangeAsAll,
+		IPFamily:        *ipFamily,
+		IncludeDisabled: *includeDisabled,
+		TagLabels:       tagScopes,
+		AllPods:         *allPods,
+		PeerGroups:      *peerGroups,
+	}
+	if *overridesFile != "" {
+		opts.Overrides, err = loadOverrides(*overridesFile)
+		if err != nil {
+			log.Fatalf("Error loading overrides: %v", err)
+		}
+	}
+	if *namespaceFile != "" {
+		namespaces, err := loadNamespaceLookup(*namespaceFile)
+		if err != nil {
+			log.Fatalf("Error loading namespace lookup: %v", err)
+		}
+		opts.NamespaceLookup = &NamespaceLookup{Tag: *namespaceIDTag, Namespaces: namespaces}
+	}
+	if *serviceLabelsFile != "" {
+		opts.ServiceLabels, err = loadServiceLabels(*serviceLabelsFile)
+		if err != nil {
+			log.Fatalf("Error loading service labels: %v", err)
+		}
+	}
+	if *vmLabelsFile != "" {
+		opts.VMLabels, err = loadVMLabels(*vmLabelsFile)
+		if err != nil {
+			log.Fatalf("Error loading VM labels: %v", err)
+		}
+	}
+	if *groupLabelsFile != "" {
+		opts.GroupLabels, err = loadGroupLabels(*groupLabelsFile)
+		if err != nil {
+			log.Fatalf("Error loading group labels: %v", err)
+		}
+	}
+	pipeline := Pipeline{
+		Options:              opts,
+		Rules:                *convertRules,
+		MergeRules:           *mergeRulesFlag,
+		MergeRanges:          *mergeRangesFlag,
+		NamespaceBaseline:    *namespaceBaseline,
+		GlobalSelector:       selectorLabels,
+		NamespaceSelector:    namespaceLabels,
+		AllowWideOpen:        *allowWideOpen,
+		RequirePeers:         *requirePeers,
+		SplitDirection:       *splitDirection,
+		MergeEgress:          *mergeEgressFlag,
+		EgressCIDRs:          egressCIDRs,
+		SameNamespace:        *allowSameNamespaceFlag,
+		SameNamespaceIngress: *allowSameNamespaceIngress,
+		APIServer:            apiServer,
+		MaxPortsPerRule:      *maxPortsPerRule,
+		ExplicitEndPort:      *explicitEndPort,
+		NoEndPort:            *noEndPort,
+		NoEndPortMax:         *noEndPortMax,
+		Owner:                owner,
+		ManagedBy:            *managedByFlag,
+	}
+	if *basePolicyFile != "" {
+		base, err := loadBasePolicy(*basePolicyFile)
+		if err != nil {
+			log.Fatalf("Error loading base policy: %v", err)
+		}
+		pipeline.Base = &base
+	}
+	pipeline.LabelPrefix, err = parseLabelPrefix(*labelPrefix)
+	if err != nil {
+		log.Fatalf("Error parsing -label-prefix: %v", err)
+	}
+	if *policyTypesFlag != "" {
+		pipeline.PolicyTypes, err = parsePolicyTypes(*policyTypesFlag)
+		if err != nil {
+			log.Fatalf("Error parsing -policy-types: %v", err)
+		}
+	}
+	if *portNamesFile != "" {
+		pipeline.PortNames, err = loadPortNames(*portNamesFile)
+		if err != nil {
+			log.Fatalf("Error loading port names: %v", err)
+		}
+	}
+
+	// Reuse the output of an identical earlier run
+	var cacheKeyValue string
+	var cached *bytes.Buffer
+	cachedFiles := map[string]string{}
+	out := io.Writer(os.Stdout)
+	if *cacheDir != "" {
+		for _, name := range fileFlags {
+			f := flag.Lookup(name)
+			switch {
+			case f.Value.String() == "":
+			case restoredFlags[name]:
+				cachedFiles[name] = f.Value.String()
+			default:
+				log.Fatalf("Error: -cache-dir cannot be combined with -%s", name)
+			}
+		}
+		cacheKeyValue, err = cacheKey()
+		if err != nil {
+			log.Fatalf("Error hashing cache inputs: %v", err)
+		}
+		if data, err := ioutil.ReadFile(cachePath(*cacheDir, cacheKeyValue)); err == nil {
+			log.Printf("Using cached output %s", cachePath(*cacheDir, cacheKeyValue))
+			if err := restoreCachedFiles(*cacheDir, cacheKeyValue, cachedFiles); err != nil {
+				log.Fatalf("Error restoring cached files: %v", err)
+			}
+			os.Stdout.Write(data)
+			return
+		}
+		cached = &bytes.Buffer{}
+		out = io.MultiWriter(os.Stdout, cached)
+	}
+
+	// Parse the JSON data, using the export paths if any are given
+	var paths *ExportPaths
+	if *servicesPath != "" || *namePath != "" || *entriesPath != "" || *portsPath != "" || *protocolPath != "" {
+		if *convertRules {
+			log.Fatal("Error: -rules cannot be combined with custom export paths")
+		}
+		paths = &ExportPaths{
+			Services: stringOr(*servicesPath, defaultServicesPath),
+			Name:     stringOr(*namePath, defaultNamePath),
+			Entries:  stringOr(*entriesPath, defaultEntriesPath),
+			Ports:    stringOr(*portsPath, defaultPortsPath),
+			Protocol: stringOr(*protocolPath, defaultProtocolPath),
+		}
+	}
+	var root Root
+	if *exportDir != "" {
+		// Merge the files of a split export
+		root, err = parseExportDir(*exportDir, paths)
+		if err != nil {
+			log.Fatalf("Error reading export directory: %v", err)
+		}
+	} else {
+		// Read the JSON file
+		data, err := ioutil.ReadFile(*jsonFile)
+		if err != nil {
+			log.Fatalf("Error reading file: %v", err)
+		}
+		root, err = parseExport(data, paths)
+		if err != nil {
+			log.Fatalf("Error parsing JSON: %v", err)
+		}
+	}
+
+	// Collect the warnings, infos and dropped entries of the run
+	diag := newDiagnostics(*verbosity)
+
+	// Require names to be fixed at the source instead of sanitized
+	if *strictDNS {
+		if names := unsanitizedNames(root, *convertRules, opts.Naming); len(names) > 0 {
+			for _, name := range names {
+				log.Printf("Error: display name %q is not a valid DNS-1123 name, it would become %q", name, sanitizeName(name, opts.Naming))
+			}
+			log.Fatalf("Error: %d display names need sanitization in -strict-dns mode", len(names))
+		}
+	}
+
+	// Explain the conversion of one service instead of emitting policies
+	if *explain != "" {
+		if err := explainService(diag, os.Stdout, root, *explain, opts); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		return
+	}
+
+	// Report the ports used across the export instead of emitting policies
+	if *portUsageFormat != "" {
+		if err := writePortUsage(os.Stdout, portUsage(root.Services, servicePolicies(diag, root, opts)), *portUsageFormat); err != nil {
+			log.Fatalf("Error writing port usage: %v", err)
+		}
+		return
+	}
+
+	// Generate NetworkPolicies
+	policies, coverage, err := pipeline.convert(root, diag)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if *coverageFile != "" {
+		if err := writeCoverage(*coverageFile, coverage); err != nil {
+			log.Fatalf("Error writing coverage report: %v", err)
+		}
+	}
+
+	if *droppedFile != "" {
+		if err := writeReport(*droppedFile, diag.dropped); err != nil {
+			log.Fatalf("Error writing dropped rules report: %v", err)
+		}
+	}
+
+	if *warningsFile != "" {
+		if err := diag.writeWarnings(*warningsFile); err != nil {
+			log.Fatalf("Error writing warnings file: %v", err)
+		}
+	}
+
+	// Preview the policy names for review
+	if *normalizeNames {
+		collisions, err := writeNameMapping(os.Stdout, policies)
+		if err != nil {
+			log.Fatalf("Error writing name mapping: %v", err)
+		}
+		for _, collision := range collisions {
+			log.Printf("Warning: policy %s", collision)
+		}
+		return
+	}
+
+	// Validate without emitting, failing on any problem
+	if *check {
+		problems := checkProblems(diag, policies, *maxPolicies)
+		writeCheckReport(os.Stdout, policies, problems, *checkLimit)
+		if len(problems) > 0 {
+			log.Fatalf("Error: %d problems found", len(problems))
+		}
+		return
+	}
+
+	// In strict mode data-quality warnings fail the run
+	if *strict {
+		if problems := diag.strictProblems(); len(problems) > 0 {
+			for _, problem := range problems {
+				log.Printf("Error: %s", problem.Message)
+			}
+			log.Fatalf("Error: %d data-quality problems found in strict mode", len(problems))
+		}
+	}
+
+	// Fail on lossy conversions, e.g. to gate CI
+	if *failOnUnsupported {
+		if problems := diag.unsupportedProblems(kinds); len(problems) > 0 {
+			for _, problem := range problems {
+				log.Printf("Error: %s: %s", problem.Type, problem.Message)
+			}
+			log.Fatalf("Error: %d NSX constructs cannot be fully represented in -output-kind %s", len(problems), *outputKind)
+		}
+	}
+
+	// Check each policy against the Kubernetes types before emitting anything
+	if *k8sValidate {
+		failed := 0
+		for _, policy := range policies {
+			if err := validateK8s(policy); err != nil {
+				log.Printf("Error: policy %s failed Kubernetes validation: %v", policy.Metadata.Name, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			log.Fatalf("Error: %d of %d NetworkPolicies failed Kubernetes validation", failed, len(policies))
+		}
+	}
+
+	// Check the policies against the expected outcome of sample flows
+	if *flowsFile != "" {
+		flows, err := loadFlows(*flowsFile, *namespace)
+		if err != nil {
+			log.Fatalf("Error loading flows: %v", err)
+		}
+		mismatches := verifyFlows(policies, flows)
+		for _, mismatch := range mismatches {
+			log.Printf("Error: %s", mismatch)
+		}
+		if len(mismatches) > 0 {
+			log.Fatalf("Error: %d of %d flows do not have the expected outcome", len(mismatches), len(flows))
+		}
+		log.Printf("Verified %d flows", len(flows))
+	}
+
+	// Guard against runaway conversions from a bad export
+	if *maxPolicies > 0 && len(policies) > *maxPolicies {
+		log.Fatalf("Error: conversion would generate %d policies of each output kind, exceeding -max-policies %d", len(policies), *maxPolicies)
+	}
+
+	if *metricsFile != "" {
+		if err := writeMetrics(*metricsFile, conversionMetrics(diag, coverage, policies, kinds)); err != nil {
+			log.Fatalf("Error writing metrics file: %v", err)
+		}
+	}
+
+	if *testScript != "" {
+		if err := writeTestScript(*testScript, policies, *testPod, *testPodNamespace); err != nil {
+			log.Fatalf("Error writing test script: %v", err)
+		}
+	}
+
+	// Only emit what changed since the previous export
+	if *since != "" {
+		previous, err := previousPolicies(*since, paths, pipeline)
+		if err != nil {
+			log.Fatalf("Error converting -since export: %v", err)
+		}
+		changes, err := diffPolicies(previous, policies, kinds, render)
+		if err != nil {
+			log.Fatalf("Error comparing with -since export: %v", err)
+		}
+		logChanges(*since, changes)
+		if *deletionsFile != "" {
+			if err := writeDeletions(*deletionsFile, changes.Deleted, kinds, render, *deletionsFormat); err != nil {
+				log.Fatalf("Error writing deletions file: %v", err)
+			}
+		}
+		policies = changes.emitted(policies)
+	}
+
+	if *generateName {
+		useGenerateName(policies)
+	}
+
+	// Render each policy with the custom template instead of YAML
+	if *templateFile != "" {
+		tmpl, err := loadTemplate(*templateFile, opts)
+		if err != nil {
+			log.Fatalf("Error loading template: %v", err)
+		}
+		for _, policy := range policies {
+			if err := tmpl.Execute(os.Stdout, policy); err != nil {
+				log.Fatalf("Error executing template for %s: %v", policy.Metadata.Name, err)
+			}
+		}
+		return
+	}
+
+	// Render each policy with every template of the directory
+	if *templateDir != "" {
+		templates, err := loadTemplateDir(*templateDir, opts)
+		if err != nil {
+			log.Fatalf("Error loading templates: %v", err)
+		}
+		files, err := templateFiles(templates, policies)
+		if err != nil {
+			log.Fatalf("Error executing templates: %v", err)
+		}
+		if *tarFile != "" {
+			err = writePolicyTar(*tarFile, files, nil, "")
+		} else {
+			err = writePolicyFiles(*outDir, files)
+		}
+		if err != nil {
+			log.Fatalf("Error writing template output: %v", err)
+		}
+		return
+	}
+
+	// Archive one file per policy, plus the optional index
+	if *tarFile != "" {
+		files, entries, err := policyFiles(policies, kinds, render, yamlOpts, *layout)
+		if err != nil {
+			log.Fatalf("Error marshaling to YAML: %v", err)
+		}
+		if *header != "" {
+			prependHeader(files, headerComment(*header))
+		}
+		if *kustomize {
+			kustomizations, err := kustomizationFiles(files, *layout, yamlOpts)
+			if err != nil {
+				log.Fatalf("Error marshaling to YAML: %v", err)
+			}
+			files = append(files, kustomizations...)
+		}
+		if err := writePolicyTar(*tarFile, files, entries, *indexFile); err != nil {
+			log.Fatalf("Error writing tar archive: %v", err)
+		}
+		return
+	}
+
+	// Write one file per policy, plus the optional index
+	if *outDir != "" {
+		files, entries, err := policyFiles(policies, kinds, render, yamlOpts, *layout)
+		if err != nil {
+			log.Fatalf("Error writing policies: %v", err)
+		}
+		if *header != "" {
+			prependHeader(files, headerComment(*header))
+		}
+		if *kustomize {
+			kustomizations, err := kustomizationFiles(files, *layout, yamlOpts)
+			if err != nil {
+				log.Fatalf("Error writing policies: %v", err)
+			}
+			files = append(files, kustomizations...)
+		}
+		if err := writePolicyFiles(*outDir, files); err != nil {
+			log.Fatalf("Error writing policies: %v", err)
+		}
+		if *indexFile != "" {
+			if err := writeIndex(filepath.Join(*outDir, *indexFile), entries); err != nil {
+				log.Fatalf("Error writing index: %v", err)
+			}
+		}
+		if *printApply {
+			printApplyFiles(os.Stdout, *outDir, entries, *generateName)
+		}
+		return
+	}
+
+	// Print the commands that would apply the policies instead of the YAML
+	if *printApply {
+		if err := printApplyHeredocs(os.Stdout, policies, kinds, render, yamlOpts); err != nil {
+			log.Fatalf("Error marshaling to YAML: %v", err)
+		}
+		return
+	}
+
+	// Hold the output back until its size is known
+	dest := out
+	var held *bytes.Buffer
+	if *maxOutputBytes > 0 {
+		held = &bytes.Buffer{}
+		out = held
+	}
+
+	switch *outputFormat {
+	case formatJSONL:
+		// Print each policy as a line of JSON for streaming consumers
+		for _, kind := range kinds {
+			for _, policy := range policies {
+				line, err := marshalJSONLine(renderPolicy(policy, kind, render))
+				if err != nil {
+					log.Fatalf("Error marshaling to JSON: %v", err)
+				}
+				fmt.Fprintf(out, "%s\n", line)
+			}
+		}
+	default:
+		// Convert to YAML and print
+		if *header != "" {
+			fmt.Fprint(out, headerComment(*header))
+		}
+		for _, kind := range kinds {
+			for _, policy := range policies {
+				yamlData, err := marshalYAML(renderPolicy(policy, kind, render), yamlOpts)
+				if err != nil {
+					log.Fatalf("Error marshaling to YAML: %v", err)
+				}
+
+				fmt.Fprintf(out, "---\n%s\n", string(yamlData))
+			}
+		}
+	}
+	if held != nil {
+		if held.Len() > *maxOutputBytes {
+			log.Fatalf("Error: the output is %d bytes, exceeding -max-output-bytes %d; use -out-dir or -tar to write one file per policy instead", held.Len(), *maxOutputBytes)
+		}
+		dest.Write(held.Bytes())
+	}
+	if cached != nil {
+		if err := storeCachedFiles(*cacheDir, cacheKeyValue, cachedFiles); err != nil {
+			log.Fatalf("Error writing cache: %v", err)
+		}
+		if err := writeCache(*cacheDir, cacheKeyValue, cached.Bytes()); err != nil {
+			log.Fatalf("Error writing cache: %v", err)
+		}
+	}
+}
+
+// parseExport parses an NSX export, or an export with a custom shape when
+// paths is set
+func parseExport(data []byte, paths *ExportPaths) (Root, error) {
+	if paths != nil {
+		return parseCustomRoot(data, *paths)
+	}
+	return parseRoot(data)
+}
+
+// parseRoot parses an NSX export
+func parseRoot(data []byte) (Root, error) {
+	var root Root
+	if err := json.Unmarshal(data, &root); err != nil {
+		return root, err
+	}
+
+	// Treat entries found under children like those under service_entries
+	for i, service := range root.Services {
+		for _, child := range service.Children {
+			if child.ServiceEntry != nil && !child.MarkedForDelete {
+				root.Services[i].ServiceEntries = append(root.Services[i].ServiceEntries, *child.ServiceEntry)
+			}
+		}
+	}
+	return root, nil
+}
+
+// servicePolicies generates one NetworkPolicy per NSX service
+func servicePolicies(diag *diagnostics, root Root, opts Options) []NetworkPolicy {
+	services := servicesByPath(root)
+	labelled := make([]string, 0, len(opts.ServiceLabels))
+	for name := range opts.ServiceLabels {
+		labelled = append(labelled, name)
+	}
+	checkServiceNames(diag, root, "-service-labels", labelled)
+	overridden := make([]string, 0, len(opts.Overrides))
+	for name := range opts.Overrides {
+		overridden = append(overridden, name)
+	}
+	checkServiceNames(diag, root, "-overrides", overridden)
+	policies := make([]NetworkPolicy, 0, len(root.Services))
+	for _, service := range root.Services {
+		policies = append(policies, servicePolicy(diag, service, services, opts))
+	}
+	return policies
+}
+
+// servicePolicy generates the NetworkPolicy for one NSX service, resolving
+// nested services through services
+func servicePolicy(diag *diagnostics, service Service, services map[string]Service, opts Options) NetworkPolicy {
+	// Sanitize display name to ensure it is a valid DNS-1123 label
+	sanitizedName := sanitizeName(service.DisplayName, opts.Naming)
+	namespace := opts.Namespace
+	if opts.NamespaceLookup != nil {
+		namespace = opts.NamespaceLookup.namespace(diag, service, opts.Namespace)
+	}
+	policy := newPolicy(serviceName(diag, service, opts.Naming), namespace)
+	// A service applied to all workloads keeps the empty podSelector, which
+	// selects every pod in the namespace
+	policy.allPods = service.appliesToAll() || opts.AllPods
+	if !policy.allPods {
+		policy.Spec.PodSelector = PodSelector{MatchLabels: map[string]string{"app": sanitizedName}}
+	}
+	for key, value := range opts.ServiceLabels[service.DisplayName] {
+		if policy.Spec.PodSelector.MatchLabels == nil {
+			policy.Spec.PodSelector.MatchLabels = map[string]string{}
+		}
+		policy.Spec.PodSelector.MatchLabels[key] = value
+	}
+	policy.Metadata.Labels = tagLabels(diag, service, opts.TagLabels)
+	policy.source = service.DisplayName
+
+	// Initialize ingress and egress sections. Nearly every entry has
+	// destination ports, so size ingress for one rule per entry.
+	entries := serviceEntries(diag, service, services, nil)
+	ingressRules := make([]Rule, 0, len(entries))
+	var egressRules []Rule
+
+	// Process service entries
+	for _, entry := range entries {
+		if entry.ResourceType == icmpServiceEntry {
+			if field, ok := entryICMP(diag, service.DisplayName, entry); ok {
+				policy.icmps = append(policy.icmps, field)
+			}
+			continue
+		}
+		protocols := entryProtocols(diag, service.DisplayName, entry)
+		if len(protocols) == 0 {
+			continue
+		}
+		// Create ingress rule if destination ports exist
+		if ports := entryPorts(diag, service.DisplayName, entry.DestinationPorts, protocols, opts); len(ports) > 0 {
+			ingressRules = append(ingressRules, Rule{Ports: ports})
+		}
+
+		// Create egress rule if source ports exist
+		if ports := entryPorts(diag, service.DisplayName, entry.SourcePorts, protocols, opts); len(ports) > 0 {
+			egressRules = append(egressRules, Rule{Ports: ports})
+		}
+	}
+
+	// Add rules to policy spec
+	if len(ingressRules) > 0 {
+		policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, "Ingress")
+		policy.Spec.Ingress = ingressRules
+	}
+	if len(egressRules) > 0 {
+		policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, "Egress")
+		policy.Spec.Egress = egressRules
+	}
+	if override, ok := opts.Overrides[service.DisplayName]; ok {
+		applyOverride(&policy, override)
+	}
+	annotateICMP(diag, &policy)
+	return policy
+}
+
+// servicesByPath indexes the services of an export by their policy path
+func servicesByPath(root Root) map[string]Service {
+	services := map[string]Service{}
+	for _, service := range root.Services {
+		services[service.Path] = service
+	}
+	return services
+}
+
+// serviceEntries returns the entries of a service, replacing nested service
+// entries of a service group with the entries of the services they reference
+func serviceEntries(diag *diagnostics, service Service, services map[string]Service, visiting map[string]bool) []ServiceEntry {
+	if visiting == nil {
+		visiting = map[string]bool{}
+	}
+	visiting[service.Path] = true
+	defer delete(visiting, service.Path)
+
+	var entries []ServiceEntry
+	for _, entry := range service.ServiceEntries {
+		if entry.ResourceType != "NestedServiceServiceEntry" {
+			entries = append(entries, entry)
+			continue
+		}
+		nested, found := services[entry.NestedServicePath]
+		if !found {
+			diag.warn(service.DisplayName, warnUnknownService, "service group %q references unknown service %q", service.DisplayName, entry.NestedServicePath)
+			continue
+		}
+		if visiting[nested.Path] {
+			diag.warn(service.DisplayName, warnServiceCycle, "service group %q contains itself through %q", service.DisplayName, entry.NestedServicePath)
+			continue
+		}
+		entries = append(entries, serviceEntries(diag, nested, services, visiting)...)
+	}
+	return entries
+}
+
+// splitDirections replaces every policy that has both ingress and egress rules
+// with one policy per direction
+func splitDirections(policies []NetworkPolicy) []NetworkPolicy {
+	var result []NetworkPolicy
+	for _, policy := range policies {
+		if !hasPolicyType(policy, "Ingress") || !hasPolicyType(policy, "Egress") {
+			result = append(result, policy)
+			continue
+		}
+
+		ingress := policy
+		ingress.Metadata.Name = suffixName(policy.Metadata.Name, "-ingress")
+		ingress.Spec.PodSelector.MatchLabels = copyLabels(policy.Spec.PodSelector.MatchLabels)
+		ingress.Spec.PolicyTypes = []string{"Ingress"}
+		ingress.Spec.Egress = nil
+
+		egress := policy
+		egress.Metadata.Name = suffixName(policy.Metadata.Name, "-egress")
+		egress.Spec.PodSelector.MatchLabels = copyLabels(policy.Spec.PodSelector.MatchLabels)
+		egress.Spec.PolicyTypes = []string{"Egress"}
+		egress.Spec.Ingress = nil
+
+		result = append(result, ingress, egress)
+	}
+	return result
+}
+
+// suffixName appends a suffix to a policy name, shortening the name so the
+// result stays within the DNS-1123 label limit
+func suffixName(name, suffix string) string {
+	if len(name)+len(suffix) > maxNameLength {
+		name = strings.TrimRight(name[:maxNameLength-len(suffix)], "-")
+	}
+	return name + suffix
+}
+
+// contains reports whether value is one of values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// copyLabels returns a copy of a label map
+func copyLabels(labels map[string]string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	result := make(map[string]string, len(labels))
+	for key, value := range labels {
+		result[key] = value
+	}
+	return result
+}
+
+// portKey identifies a port string and protocol pair
+type portKey struct {
+	port, protocol string
+}
+
+// entryPorts converts the port strings of a service entry into NetworkPolicy
+// ports, emitting each port once per protocol and dropping duplicates. A
+// port given with its protocol, e.g. "udp/53", is only emitted for that one.
+func entryPorts(diag *diagnostics, service string, ports []string, protocols []string, opts Options) []PortRule {
+	if len(ports) == 0 {
+		return nil
+	}
+	result := make([]PortRule, 0, len(ports)*len(protocols))
+
+	// Duplicates need more than one port/protocol pair, which most entries
+	// do not have, so only track them when they are possible
+	var seen map[portKey]bool
+	if cap(result) > 1 {
+		seen = make(map[portKey]bool, cap(result))
+	}
+	for _, port := range ports {
+		// A port such as "tcp/443" gives its own protocol
+		portProtocols := protocols
+		protocol, number, err := splitPortProtocol(port)
+		if err != nil {
+			diag.warn(service, warnInvalidPort, "service %q has invalid port %q, skipping it: %v", service, port, err)
+			continue
+		}
+		if protocol != "" {
+			portProtocols = []string{protocol}
+		}
+		start, end, err := parsePortRange(number)
+		if err != nil {
+			diag.warn(service, warnInvalidPort, "service %q has invalid port %q, skipping it: %v", service, port, err)
+			continue
+		}
+
+		// Port 0 is invalid in a NetworkPolicy. Some exports use it for all
+		// ports, which is expressed by leaving the port out.
+		portValue := &IntOrString{IntVal: start}
+		var endPort *int
+		if end > start {
+			endPort = &end
+		}
+		switch {
+		case opts.FullRangeAsAll && start <= 1 && end == maxPort:
+			// The range covers every port, so leave the port out as well
+			diag.info(service, infoAllPorts, "service %q has port range %q, treating it as all %s ports", service, port, strings.Join(portProtocols, "/"))
+			portValue, endPort = nil, nil
+		case start == 0 && end > start:
+			diag.warn(service, warnInvalidPort, "service %q has port range %q starting at 0, skipping it (use -fullrange-as-all to treat it as all ports)", service, port)
+			continue
+		case start == 0:
+			if !opts.ZeroMeansAll {
+				diag.warn(service, warnInvalidPort, "service %q has port 0, skipping it (use -zero-means-all to treat it as all ports)", service)
+				continue
+			}
+			diag.info(service, infoAllPorts, "service %q has port 0, treating it as all %s ports", service, strings.Join(portProtocols, "/"))
+			portValue = nil
+		}
+
+		for _, protocol := range portProtocols {
+			if seen != nil {
+				key := portKey{number, protocol}
+				if portValue == nil {
+					// Every spelling of all ports, e.g. "0" and
+					// "1-65535", is the same port rule
+					key.port = ""
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			result = append(result, PortRule{Port: portValue, EndPort: endPort, Protocol: protocol})
+		}
+	}
+	return result
+}
+
+// stringOr returns value, or fallback when value is empty
+func stringOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseScopeLabels parses scope:key=value mappings into namespace labels per scope
+func parseScopeLabels(values []string) (map[string]map[string]string, error) {
+	scopes := map[string]map[string]string{}
+	for _, value := range values {
+		scope, label, ok := strings.Cut(value, ":")
+		key, labelValue, hasValue := strings.Cut(label, "=")
+		if !ok || !hasValue || scope == "" || key == "" {
+			return nil, fmt.Errorf("%q is not in scope:key=value form", value)
+		}
+		if scopes[scope] == nil {
+			scopes[scope] = map[string]string{}
+		}
+		scopes[scope][key] = labelValue
+	}
+	return scopes, nil
+}
+
+// maxNameLength is the longest valid DNS-1123 label
+const maxNameLength = 63
+
+var (
+	invalidNameChars     = regexp.MustCompile(`[^a-z0-9-]+`)
+	validNameReplacement = regexp.MustCompile(`^[a-z0-9-]*$`)
+	// repeatedDashes matches runs of the default replacement
+	repeatedDashes = regexp.MustCompile(`-+`)
+)
+
+// repeatedReplacements caches, by replacement, the regexps that match runs
+// of the other replacements, so sanitizeName compiles each one once
+var repeatedReplacements sync.Map
+
+// repeatedReplacement returns the regexp matching runs of replacement
+func repeatedReplacement(replacement string) *regexp.Regexp {
+	if replacement == "-" {
+		return repeatedDashes
+	}
+	if cached, ok := repeatedReplacements.Load(replacement); ok {
+		return cached.(*regexp.Regexp)
+	}
+	repeated := regexp.MustCompile(`(` + regexp.QuoteMeta(replacement) + `)+`)
+	repeatedReplacements.Store(replacement, repeated)
+	return repeated
+}
+
+// unsanitizedNames returns the display names of the services, and of the rules
+// when includeRules is set, that sanitizeName would change
+func unsanitizedNames(root Root, includeRules bool, opts NameOptions) []string {
+	var names []string
+	seen := map[string]bool{}
+	check := func(name string) {
+		if !seen[name] && sanitizeName(name, opts) != name {
+			names = append(names, name)
+		}
+		seen[name] = true
+	}
+	for _, service := range root.Services {
+		check(service.DisplayName)
+	}
+	if includeRules {
+		for _, domain := range root.Domains {
+			for _, securityPolicy := range domain.Resources.SecurityPolicies {
+				for _, rule := range securityPolicy.Rules {
+					check(rule.DisplayName)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// sanitizeName ensures a name complies with DNS-1123 naming conventions
+func sanitizeName(name string, opts NameOptions) string {
+	if opts.Transliterate {
+		name = transliterate(name)
+	}
+	// Replace invalid characters with the configured replacement
+	name = strings.ToLower(name)
+	name = invalidNameChars.ReplaceAllString(name, opts.Replacement)
+	if opts.Collapse && opts.Replacement != "" {
+		name = repeatedReplacement(opts.Replacement).ReplaceAllString(name, opts.Replacement)
+	}
+	// Ensure it starts and ends with an alphanumeric character
+	name = strings.Trim(name, "-")
+	// DNS-1123 labels are limited to 63 characters
+	if len(name) > maxNameLength {
+		name = strings.TrimRight(name[:maxNameLength], "-")
+	}
+	return name
+}
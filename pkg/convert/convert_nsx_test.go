@@ -0,0 +1,136 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitDirections(t *testing.T) {
+	opts := testOptions()
+	opts.Namespace = "shop"
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: opts, SplitDirection: true})
+	var names []string
+	for _, policy := range policies {
+		names = append(names, policy.Metadata.Name)
+	}
+	if want := []string{"web", "client", "db-ingress", "db-egress"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("policies = %v, want %v", names, want)
+	}
+
+	ingress, egress := policies[2], policies[3]
+	if !reflect.DeepEqual(ingress.Spec.PolicyTypes, []string{"Ingress"}) || len(ingress.Spec.Ingress) != 1 || len(ingress.Spec.Egress) != 0 {
+		t.Errorf("ingress policy = %+v, want only the ingress rule", ingress.Spec)
+	}
+	if !reflect.DeepEqual(egress.Spec.PolicyTypes, []string{"Egress"}) || len(egress.Spec.Egress) != 1 || len(egress.Spec.Ingress) != 0 {
+		t.Errorf("egress policy = %+v, want only the egress rule", egress.Spec)
+	}
+	for _, policy := range []NetworkPolicy{ingress, egress} {
+		if policy.Metadata.Namespace != "shop" || !reflect.DeepEqual(policy.Spec.PodSelector.MatchLabels, map[string]string{"app": "db"}) {
+			t.Errorf("policy %s has namespace %q and selector %v, want those of db", policy.Metadata.Name, policy.Metadata.Namespace, policy.Spec.PodSelector.MatchLabels)
+		}
+	}
+	ingress.Spec.PodSelector.MatchLabels["app"] = "changed"
+	if egress.Spec.PodSelector.MatchLabels["app"] != "db" {
+		t.Error("the two policies share their selector labels")
+	}
+}
+
+func TestSuffixNameStaysWithinLimit(t *testing.T) {
+	name := suffixName(strings.Repeat("a", 55)+"-b", "-ingress")
+	if len(name) > maxNameLength || !strings.HasSuffix(name, "-ingress") || strings.Contains(name, "--") {
+		t.Errorf("suffixName = %q (%d characters)", name, len(name))
+	}
+}
+
+func TestEntryPortsZero(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	if ports := entryPorts(diag, "any", []string{"0"}, []string{"TCP"}, testOptions()); len(ports) != 0 {
+		t.Errorf("port 0 without -zero-means-all = %+v, want it skipped", ports)
+	}
+	if problems := diag.strictProblems(); len(problems) != 1 || problems[0].Type != warnInvalidPort {
+		t.Errorf("strict problems = %+v, want one invalid-port", problems)
+	}
+
+	diag = newDiagnostics(verbosityQuiet)
+	opts := testOptions()
+	opts.ZeroMeansAll = true
+	ports := entryPorts(diag, "any", []string{"0"}, []string{"TCP", "UDP"}, opts)
+	if len(ports) != 2 || ports[0].Port != nil || ports[0].Protocol != "TCP" || ports[1].Port != nil || ports[1].Protocol != "UDP" {
+		t.Errorf("port 0 with -zero-means-all = %+v, want all TCP and all UDP ports", ports)
+	}
+	if problems := diag.strictProblems(); len(problems) != 0 {
+		t.Errorf("strict problems = %+v, want none", problems)
+	}
+	if len(diag.infos) != 1 || diag.infos[0].Type != infoAllPorts {
+		t.Errorf("infos = %+v, want one all-ports", diag.infos)
+	}
+}
+
+func TestEntryPortsFullRange(t *testing.T) {
+	ranges := []string{"1-65535", "0-65535", "1024-65535"}
+	diag := newDiagnostics(verbosityQuiet)
+	ports := entryPorts(diag, "any", ranges, []string{"TCP"}, testOptions())
+	if want := []string{"TCP/1-65535", "TCP/1024-65535"}; !reflect.DeepEqual(portSummaries(ports), want) {
+		t.Errorf("full ranges without -fullrange-as-all = %v, want endPort ranges %v", portSummaries(ports), want)
+	}
+	if len(diag.warnings) != 1 || diag.warnings[0].Type != warnInvalidPort {
+		t.Errorf("warnings = %+v, want 0-65535 skipped as an invalid port", diag.warnings)
+	}
+
+	diag = newDiagnostics(verbosityQuiet)
+	opts := testOptions()
+	opts.FullRangeAsAll = true
+	ports = entryPorts(diag, "any", ranges, []string{"TCP"}, opts)
+	if len(ports) != 2 || ports[0] != (PortRule{Protocol: "TCP"}) {
+		t.Fatalf("full ranges with -fullrange-as-all = %+v, want an all-ports rule first", ports)
+	}
+	if got := portSummary(ports[1]); got != "TCP/1024-65535" {
+		t.Errorf("partial range with -fullrange-as-all = %s, want TCP/1024-65535", got)
+	}
+	if len(diag.infos) != 2 || diag.infos[0].Type != infoAllPorts || diag.infos[1].Type != infoAllPorts {
+		t.Errorf("infos = %+v, want all-ports for 1-65535 and 0-65535", diag.infos)
+	}
+	if len(diag.warnings) != 0 {
+		t.Errorf("warnings = %+v, want none", diag.warnings)
+	}
+}
+
+func TestUnsanitizedNames(t *testing.T) {
+	root := Root{
+		Services: []Service{{DisplayName: "web"}, {DisplayName: "Web Service"}, {DisplayName: "Web Service"}, {DisplayName: "db_primary"}},
+		Domains: []Domain{{Resources: DomainResources{SecurityPolicies: []SecurityPolicy{{Rules: []SecurityRule{
+			{DisplayName: "allow-web"}, {DisplayName: "Allow DB"},
+		}}}}}},
+	}
+	names := unsanitizedNames(root, false, NameOptions{Replacement: "-"})
+	if want := []string{"Web Service", "db_primary"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("unsanitizedNames without rules = %q, want %q", names, want)
+	}
+	names = unsanitizedNames(root, true, NameOptions{Replacement: "-"})
+	if want := []string{"Web Service", "db_primary", "Allow DB"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("unsanitizedNames with rules = %q, want %q", names, want)
+	}
+}
+
+func TestAllPodsPodSelector(t *testing.T) {
+	export := `{"services": [
+		{"display_name": "dns", "applied_to": ["ANY"], "service_entries": [{"display_name": "dns", "l4_protocol": "UDP", "destination_ports": ["53"]}]},
+		{"display_name": "web", "service_entries": [{"display_name": "http", "l4_protocol": "TCP", "destination_ports": ["80"]}]}
+	]}`
+	for _, allPods := range []bool{false, true} {
+		opts := testOptions()
+		opts.AllPods = allPods
+		policies, _ := convertExport(t, export, Pipeline{Options: opts})
+		for _, policy := range policies {
+			data, err := marshalYAML(renderPolicy(policy, kindNetworkPolicy, defaultRender), defaultYAML)
+			if err != nil {
+				t.Fatal(err)
+			}
+			selectsAll := strings.Contains(string(data), "\n  podSelector: {}\n")
+			if want := allPods || policy.Metadata.Name == "dns"; selectsAll != want {
+				t.Errorf("-all-pods %v: policy %s has podSelector {}: %v, want %v\n%s", allPods, policy.Metadata.Name, selectsAll, want, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package convert
+
+import "log"
+
+// Coverage statuses of an input service
+const (
+	coverageConverted = "converted"
+	coverageSkipped   = "skipped"
+	coverageError     = "error"
+)
+
+// CoverageEntry records what became of one NSX service
+type CoverageEntry struct {
+	Service string   `yaml:"service" json:"service"`
+	Policy  string   `yaml:"policy" json:"policy"`
+	Status  string   `yaml:"status" json:"status"`
+	Reasons []string `yaml:"reasons,omitempty" json:"reasons,omitempty"`
+}
+
+// serviceCoverage reports for each service whether its policy got any rules.
+// policies must be the output of servicePolicies for the same services.
+func serviceCoverage(diag *diagnostics, services []Service, policies []NetworkPolicy) []CoverageEntry {
+	entries := make([]CoverageEntry, 0, len(services))
+	for i, service := range services {
+		policy := policies[i]
+		entry := CoverageEntry{Service: service.DisplayName, Policy: policy.Metadata.Name}
+		for _, w := range diag.warnings {
+			if w.Service == service.DisplayName {
+				entry.Reasons = append(entry.Reasons, w.Message)
+			}
+		}
+		switch {
+		case len(policy.Spec.Ingress) > 0 || len(policy.Spec.Egress) > 0:
+			entry.Status = coverageConverted
+		case len(entry.Reasons) > 0:
+			entry.Status = coverageError
+		case len(service.ServiceEntries) == 0:
+			entry.Status = coverageSkipped
+			entry.Reasons = []string{"service has no entries"}
+		default:
+			entry.Status = coverageSkipped
+			entry.Reasons = skipReasons(service)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// skipReasons explains why a service with entries produced no rules
+func skipReasons(service Service) []string {
+	var reasons []string
+	seen := map[string]bool{}
+	for _, entry := range service.ServiceEntries {
+		if !supportedEntry(entry) && !seen[entry.ResourceType] {
+			seen[entry.ResourceType] = true
+			reasons = append(reasons, entry.ResourceType+" entries cannot be expressed in a NetworkPolicy")
+		}
+	}
+	if len(reasons) == 0 {
+		reasons = []string{"service entries have no destination or source ports"}
+	}
+	return reasons
+}
+
+// supportedEntry reports whether an entry is of a type that can produce ports
+func supportedEntry(entry ServiceEntry) bool {
+	switch entry.ResourceType {
+	case "", "L4PortSetServiceEntry", "NestedServiceServiceEntry":
+		return true
+	}
+	return false
+}
+
+// writeCoverage writes the coverage report and logs a one-line summary
+func writeCoverage(path string, entries []CoverageEntry) error {
+	counts := map[string]int{}
+	for _, entry := range entries {
+		counts[entry.Status]++
+	}
+	log.Printf("Coverage: %d services, %d converted, %d skipped, %d errors",
+		len(entries), counts[coverageConverted], counts[coverageSkipped], counts[coverageError])
+	return writeReport(path, entries)
+}
@@ -0,0 +1,42 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServiceCoverage(t *testing.T) {
+	root, err := parseRoot([]byte(`{"services": [
+		{"display_name": "web", "service_entries": [{"display_name": "http", "l4_protocol": "TCP", "destination_ports": ["80"]}]},
+		{"display_name": "empty"},
+		{"display_name": "alg", "service_entries": [{"display_name": "ftp", "resource_type": "ALGTypeServiceEntry"}]},
+		{"display_name": "portless", "service_entries": [{"display_name": "none", "l4_protocol": "TCP"}]},
+		{"display_name": "broken", "service_entries": [{"display_name": "bad", "l4_protocol": "TCP", "destination_ports": ["0"]}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Pipeline{Options: testOptions()}.ConvertWithDiagnostics(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, entry := range result.Coverage {
+		statuses = append(statuses, entry.Service+" "+entry.Status)
+	}
+	if want := []string{"web converted", "empty skipped", "alg skipped", "portless skipped", "broken error"}; !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("coverage = %v, want %v", statuses, want)
+	}
+	reasons := [][]string{
+		nil,
+		{"service has no entries"},
+		{"ALGTypeServiceEntry entries cannot be expressed in a NetworkPolicy"},
+		{"service entries have no destination or source ports"},
+		{`service "broken" has port 0, skipping it (use -zero-means-all to treat it as all ports)`},
+	}
+	for i, entry := range result.Coverage {
+		if !reflect.DeepEqual(entry.Reasons, reasons[i]) {
+			t.Errorf("reasons of %s = %q, want %q", entry.Service, entry.Reasons, reasons[i])
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package convert
+
+import (
+	"fmt"
+	"log"
+)
+
+// Diagnostic levels
+const (
+	levelWarning = "warning"
+	levelInfo    = "info"
+)
+
+// Info types, for the defaults applied during a conversion
+const (
+	infoDefaultDirection = "default-direction"
+	infoAllPorts         = "all-ports"
+	infoIPFamily         = "ip-family"
+	infoDisabledRule     = "disabled-rule"
+)
+
+// Verbosity levels selectable with -v
+const (
+	verbosityQuiet    = 0
+	verbosityWarnings = 1
+	verbosityInfo     = 2
+)
+
+// Diagnostic is a warning or info about an NSX service or rule
+type Diagnostic struct {
+	Level   string `json:"level"`
+	Service string `json:"service"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// Result is the outcome of a conversion
+type Result struct {
+	Policies    []NetworkPolicy
+	Coverage    []CoverageEntry
+	Diagnostics []Diagnostic
+}
+
+// diagnostics collects the warnings, infos and dropped entries of one
+// conversion
+type diagnostics struct {
+	// verbosity controls which diagnostics are logged as they are produced
+	verbosity int
+	warnings  []Warning
+	infos     []Warning
+	dropped   []DroppedRule
+	// ciliumEnforced holds the warnings about constructs that Cilium output
+	// represents, so they only count for the other output kinds
+	ciliumEnforced map[Warning]bool
+}
+
+// newDiagnostics returns an empty collector logging at the given verbosity
+func newDiagnostics(verbosity int) *diagnostics {
+	return &diagnostics{verbosity: verbosity, warnings: []Warning{}, infos: []Warning{}, dropped: []DroppedRule{}, ciliumEnforced: map[Warning]bool{}}
+}
+
+// info records a default applied to an NSX service or rule, logging it at
+// -v 2. Repeats of an identical info are dropped.
+func (d *diagnostics) info(service, infoType, format string, args ...interface{}) {
+	i := Warning{Service: service, Type: infoType, Message: fmt.Sprintf(format, args...)}
+	for _, existing := range d.infos {
+		if existing == i {
+			return
+		}
+	}
+	if d.verbosity >= verbosityInfo {
+		log.Printf("Info: %s", i.Message)
+	}
+	d.infos = append(d.infos, i)
+}
+
+// ParseExport parses an NSX export for ConvertWithDiagnostics
+func ParseExport(data []byte) (Root, error) {
+	return parseRoot(data)
+}
+
+// ConvertWithDiagnostics converts the services of an export with the given
+// options, returning the policies with the warnings and infos produced for
+// them. Security policy rules are not converted, as without -rules.
+func ConvertWithDiagnostics(root Root, opts Options) (Result, error) {
+	return Pipeline{Options: opts}.ConvertWithDiagnostics(root)
+}
+
+// ConvertWithDiagnostics runs the pipeline on an export, returning the
+// policies with the warnings and infos produced for them. Nothing is logged
+// and each call collects its own diagnostics, so conversions can run
+// concurrently. When the conversion fails, the result only holds the
+// diagnostics, e.g. the no-peers warnings of RequirePeers.
+func (p Pipeline) ConvertWithDiagnostics(root Root) (Result, error) {
+	diag := newDiagnostics(verbosityQuiet)
+	policies, coverage, err := p.convert(root, diag)
+	var result Result
+	if err == nil {
+		result = Result{Policies: policies, Coverage: coverage}
+	}
+	for _, w := range diag.warnings {
+		result.Diagnostics = append(result.Diagnostics, Diagnostic{Level: levelWarning, Service: w.Service, Type: w.Type, Message: w.Message})
+	}
+	for _, i := range diag.infos {
+		result.Diagnostics = append(result.Diagnostics, Diagnostic{Level: levelInfo, Service: i.Service, Type: i.Type, Message: i.Message})
+	}
+	return result, err
+}
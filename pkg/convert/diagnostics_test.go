@@ -0,0 +1,120 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// diagnosticsExport has one service with an invalid port, which is a
+// warning, and one with the port range 1-65535, which is an info
+func diagnosticsExport(t *testing.T, suffix string) Root {
+	t.Helper()
+	data := fmt.Sprintf(`{"services": [
+		{"display_name": "bad-%[1]s", "service_entries": [{"display_name": "bad", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["http"]}]},
+		{"display_name": "all-%[1]s", "service_entries": [{"display_name": "all", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["1-65535"]}]}
+	]}`, suffix)
+	root, err := ParseExport([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestConvertWithDiagnosticsLevels(t *testing.T) {
+	opts := Options{Namespace: "default", Naming: NameOptions{Replacement: "-"}, FullRangeAsAll: true}
+	result, err := ConvertWithDiagnostics(diagnosticsExport(t, "a"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range result.Diagnostics {
+		got = append(got, d.Level+" "+d.Type+" "+d.Service)
+	}
+	want := []string{"warning invalid-port bad-a", "info all-ports all-a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diagnostics = %q, want %q", got, want)
+	}
+	if len(result.Policies) != 2 {
+		t.Errorf("got %d policies, want 2", len(result.Policies))
+	}
+}
+
+func TestConvertWithDiagnosticsRequirePeers(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	pipeline := Pipeline{Options: Options{Namespace: "default", Naming: NameOptions{Replacement: "-"}, FullRangeAsAll: true}, RequirePeers: true}
+	result, err := pipeline.ConvertWithDiagnostics(diagnosticsExport(t, "a"))
+	if err == nil {
+		t.Fatal("peerless policies were accepted")
+	}
+	var got []string
+	for _, d := range result.Diagnostics {
+		if d.Type == warnNoPeers {
+			got = append(got, d.Service)
+		}
+	}
+	if want := []string{"all-a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("no-peers diagnostics for %q, want %q", got, want)
+	}
+	if logged.Len() > 0 {
+		t.Errorf("ConvertWithDiagnostics logged:\n%s", logged.String())
+	}
+}
+
+func TestConvertWithDiagnosticsWideOpen(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	result, err := Pipeline{Options: testOptions(), Rules: true}.ConvertWithDiagnostics(readExport(t, "wide-open.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Policies) != 0 {
+		t.Errorf("wide-open policies were emitted: %+v", result.Policies)
+	}
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Type != warnWideOpen {
+		t.Errorf("diagnostics = %+v, want one wide-open warning", result.Diagnostics)
+	}
+	if logged.Len() > 0 {
+		t.Errorf("ConvertWithDiagnostics logged:\n%s", logged.String())
+	}
+}
+
+func TestConvertWithDiagnosticsConcurrent(t *testing.T) {
+	opts := Options{Namespace: "default", Naming: NameOptions{Replacement: "-"}, FullRangeAsAll: true}
+	const calls = 8
+	results := make([]Result, calls)
+	errs := make([]error, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		root := diagnosticsExport(t, fmt.Sprint(i))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = ConvertWithDiagnostics(root, opts)
+		}(i)
+	}
+	wg.Wait()
+	for i, result := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if len(result.Diagnostics) != 2 {
+			t.Errorf("call %d got %d diagnostics, want 2: %v", i, len(result.Diagnostics), result.Diagnostics)
+			continue
+		}
+		for _, d := range result.Diagnostics {
+			if d.Service != "bad-"+fmt.Sprint(i) && d.Service != "all-"+fmt.Sprint(i) {
+				t.Errorf("call %d got diagnostic of another call: %v", i, d)
+			}
+		}
+	}
+}
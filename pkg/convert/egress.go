@@ -0,0 +1,203 @@
+package convert
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// parseCIDRs validates a list of CIDRs and returns them in canonical form
+func parseCIDRs(values []string) ([]string, error) {
+	var cidrs []string
+	for _, value := range values {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %v", value, err)
+		}
+		cidrs = append(cidrs, network.String())
+	}
+	return cidrs, nil
+}
+
+// allowEgressCIDRs appends a rule permitting all traffic to the given CIDRs to
+// every policy that restricts egress
+func allowEgressCIDRs(policies []NetworkPolicy, cidrs []string) {
+	if len(cidrs) == 0 {
+		return
+	}
+	var peers []NetworkPolicyPeer
+	for _, cidr := range cidrs {
+		peers = append(peers, NetworkPolicyPeer{IPBlock: &IPBlock{CIDR: cidr}})
+	}
+	for i := range policies {
+		if !hasPolicyType(policies[i], "Egress") {
+			continue
+		}
+		policies[i].Spec.Egress = append(policies[i].Spec.Egress, Rule{To: peers})
+	}
+}
+
+// allowSameNamespace appends a rule permitting all traffic to the pods of
+// the policy's own namespace to every policy that restricts egress, and
+// with ingress, one permitting all traffic from them to every policy that
+// restricts ingress
+func allowSameNamespace(policies []NetworkPolicy, ingress bool) {
+	for i := range policies {
+		peers := []NetworkPolicyPeer{{
+			PodSelector:       &LabelSelector{},
+			NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: policies[i].Metadata.Namespace}},
+		}}
+		if hasPolicyType(policies[i], "Egress") {
+			policies[i].Spec.Egress = append(policies[i].Spec.Egress, Rule{To: peers})
+		}
+		if ingress && hasPolicyType(policies[i], "Ingress") {
+			policies[i].Spec.Ingress = append(policies[i].Spec.Ingress, Rule{From: peers})
+		}
+	}
+}
+
+// mergedEgressName is the name of the consolidated egress policy of a
+// namespace
+const mergedEgressName = "merged-egress"
+
+// mergeEgress moves the egress rules of every policy into one egress-only
+// policy per namespace that selects all pods. Rules with the same peers have
+// their ports pooled, each rule keeps the HTTP rules of its policy, and the
+// annotations of the policies are combined. Policies left without a policy
+// type are dropped.
+func mergeEgress(policies []NetworkPolicy) []NetworkPolicy {
+	var kept []NetworkPolicy
+	merged := map[string]*NetworkPolicy{}
+	var namespaces []string
+	for _, policy := range policies {
+		if !hasPolicyType(policy, "Egress") {
+			kept = append(kept, policy)
+			continue
+		}
+		namespace := policy.Metadata.Namespace
+		egress, found := merged[namespace]
+		if !found {
+			// The empty podSelector of a new policy selects all pods
+			created := newPolicy(mergedEgressName, namespace)
+			created.source = "merged egress"
+			created.allPods = true
+			created.Spec.PolicyTypes = []string{"Egress"}
+			egress = &created
+			merged[namespace] = egress
+			namespaces = append(namespaces, namespace)
+		}
+		egress.Spec.Egress = mergeRules(egress.Spec.Egress, withHTTP(policy.Spec.Egress, policy.http))
+		mergeICMPs(egress, policy.icmps)
+		mergeAnnotations(egress, policy.Metadata.Annotations)
+
+		policy.Spec.Egress = nil
+		var policyTypes []string
+		for _, policyType := range policy.Spec.PolicyTypes {
+			if policyType != "Egress" {
+				policyTypes = append(policyTypes, policyType)
+			}
+		}
+		if len(policyTypes) == 0 {
+			continue
+		}
+		policy.Spec.PolicyTypes = policyTypes
+		kept = append(kept, policy)
+	}
+	for _, namespace := range namespaces {
+		kept = append(kept, *merged[namespace])
+	}
+	return kept
+}
+
+// parsePolicyTypes parses a comma-separated list of policy types, accepting
+// any case and returning them in the canonical Ingress, Egress order
+func parsePolicyTypes(value string) ([]string, error) {
+	requested := map[string]bool{}
+	for _, part := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(part)) {
+		case "ingress":
+			requested["Ingress"] = true
+		case "egress":
+			requested["Egress"] = true
+		default:
+			return nil, fmt.Errorf("unknown policy type %q, must be Ingress or Egress", part)
+		}
+	}
+	var policyTypes []string
+	for _, policyType := range []string{"Ingress", "Egress"} {
+		if requested[policyType] {
+			policyTypes = append(policyTypes, policyType)
+		}
+	}
+	return policyTypes, nil
+}
+
+// applyPolicyTypes sets the policy types of every policy instead of the
+// inferred ones. The rules are kept as they are, so a listed type without
+// rules denies all traffic in that direction.
+func applyPolicyTypes(policies []NetworkPolicy, policyTypes []string) {
+	if len(policyTypes) == 0 {
+		return
+	}
+	for i := range policies {
+		policies[i].Spec.PolicyTypes = append([]string(nil), policyTypes...)
+	}
+}
+
+// hasPolicyType reports whether the policy lists the given policy type
+func hasPolicyType(policy NetworkPolicy, policyType string) bool {
+	for _, t := range policy.Spec.PolicyTypes {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+// withHTTP copies rules, giving the rules without HTTP rules of their own
+// the HTTP rules of their policy
+func withHTTP(rules []Rule, http []CiliumHTTPRule) []Rule {
+	if len(http) == 0 {
+		return rules
+	}
+	copied := make([]Rule, len(rules))
+	for i, rule := range rules {
+		if rule.http == nil {
+			rule.http = http
+		}
+		copied[i] = rule
+	}
+	return copied
+}
+
+// annotationSeparator returns the separator between the values of an
+// annotation that lists several
+func annotationSeparator(key string) string {
+	if key == profileAnnotation {
+		return " | "
+	}
+	return ", "
+}
+
+// mergeAnnotations adds annotations to those of a policy. Values of the same
+// key are listed once each, in the order they were first seen.
+func mergeAnnotations(policy *NetworkPolicy, annotations map[string]string) {
+	for key, value := range annotations {
+		if policy.Metadata.Annotations == nil {
+			policy.Metadata.Annotations = map[string]string{}
+		}
+		existing, found := policy.Metadata.Annotations[key]
+		if !found {
+			policy.Metadata.Annotations[key] = value
+			continue
+		}
+		separator := annotationSeparator(key)
+		values := strings.Split(existing, separator)
+		for _, v := range strings.Split(value, separator) {
+			if !contains(values, v) {
+				values = append(values, v)
+			}
+		}
+		policy.Metadata.Annotations[key] = strings.Join(values, separator)
+	}
+}
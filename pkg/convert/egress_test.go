@@ -0,0 +1,184 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+// directionsExport has an ingress-only, an egress-only and a two-way service
+const directionsExport = `{"services": [
+	{"display_name": "web", "service_entries": [{"display_name": "http", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["80"]}]},
+	{"display_name": "client", "service_entries": [{"display_name": "ephemeral", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "source_ports": ["1024-65535"]}]},
+	{"display_name": "db", "service_entries": [{"display_name": "sql", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["5432"], "source_ports": ["5432"]}]}
+]}`
+
+func TestParseCIDRs(t *testing.T) {
+	cidrs, err := parseCIDRs([]string{"10.1.2.3/8", "fd00::1/64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"10.0.0.0/8", "fd00::/64"}; !reflect.DeepEqual(cidrs, want) {
+		t.Errorf("parseCIDRs = %v, want %v", cidrs, want)
+	}
+	if _, err := parseCIDRs([]string{"10.0.0.0"}); err == nil {
+		t.Error("parseCIDRs accepted an address without a prefix length")
+	}
+}
+
+func TestAllowEgressCIDRs(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	allowEgressCIDRs(policies, []string{"10.0.0.0/8", "192.168.0.0/16"})
+	want := []NetworkPolicyPeer{{IPBlock: &IPBlock{CIDR: "10.0.0.0/8"}}, {IPBlock: &IPBlock{CIDR: "192.168.0.0/16"}}}
+	for _, name := range []string{"client", "db"} {
+		egress := findPolicy(t, policies, name).Spec.Egress
+		if len(egress) != 2 || !reflect.DeepEqual(egress[1].To, want) || len(egress[1].Ports) != 0 {
+			t.Errorf("egress of %s = %+v, want the service rule followed by a rule to %+v", name, egress, want)
+		}
+	}
+	if web := findPolicy(t, policies, "web"); len(web.Spec.Egress) != 0 {
+		t.Errorf("ingress-only policy web got egress rules %+v", web.Spec.Egress)
+	}
+}
+
+func TestParsePolicyTypes(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"Ingress", []string{"Ingress"}},
+		{"egress", []string{"Egress"}},
+		{"Egress,Ingress", []string{"Ingress", "Egress"}},
+		{" ingress , EGRESS ,ingress", []string{"Ingress", "Egress"}},
+	}
+	for _, tt := range tests {
+		got, err := parsePolicyTypes(tt.value)
+		if err != nil {
+			t.Errorf("parsePolicyTypes(%q): %v", tt.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePolicyTypes(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+	for _, value := range []string{"", "Ingress,", "All"} {
+		if _, err := parsePolicyTypes(value); err == nil {
+			t.Errorf("parsePolicyTypes accepted %q", value)
+		}
+	}
+}
+
+func TestApplyPolicyTypes(t *testing.T) {
+	for _, policyTypes := range [][]string{{"Ingress"}, {"Egress"}, {"Ingress", "Egress"}} {
+		policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions(), PolicyTypes: policyTypes})
+		for _, policy := range policies {
+			if !reflect.DeepEqual(policy.Spec.PolicyTypes, policyTypes) {
+				t.Errorf("%v: policyTypes of %s = %v", policyTypes, policy.Metadata.Name, policy.Spec.PolicyTypes)
+			}
+		}
+		// The rules stay as generated, whatever the listed types
+		web, client := findPolicy(t, policies, "web"), findPolicy(t, policies, "client")
+		if len(web.Spec.Ingress) != 1 || len(web.Spec.Egress) != 0 || len(client.Spec.Ingress) != 0 || len(client.Spec.Egress) != 1 {
+			t.Errorf("%v: rules changed: web %+v, client %+v", policyTypes, web.Spec, client.Spec)
+		}
+	}
+}
+
+func TestMergeEgress(t *testing.T) {
+	opts := testOptions()
+	opts.Namespace = "shop"
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: opts, MergeEgress: true})
+	if got, want := policyNames(policies), []string{"web", "db", mergedEgressName}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("policies = %v, want %v", got, want)
+	}
+	db := findPolicy(t, policies, "db")
+	if !reflect.DeepEqual(db.Spec.PolicyTypes, []string{"Ingress"}) || len(db.Spec.Egress) != 0 || len(db.Spec.Ingress) != 1 {
+		t.Errorf("db = %+v, want only its ingress rule", db.Spec)
+	}
+
+	merged := policies[2]
+	if merged.Metadata.Namespace != "shop" || !reflect.DeepEqual(merged.Spec.PolicyTypes, []string{"Egress"}) {
+		t.Errorf("merged egress policy = %+v in %q", merged.Spec, merged.Metadata.Namespace)
+	}
+	if len(merged.Spec.PodSelector.MatchLabels) != 0 || !merged.allPods {
+		t.Errorf("merged egress policy selects %+v, want all pods", merged.Spec.PodSelector)
+	}
+	// Both egress rules have no peers, so their ports are pooled
+	if len(merged.Spec.Egress) != 1 {
+		t.Fatalf("merged egress rules = %+v, want one pooled rule", merged.Spec.Egress)
+	}
+	if got, want := portSummaries(merged.Spec.Egress[0].Ports), []string{"TCP/1024-65535", "TCP/5432"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged egress ports = %v, want %v", got, want)
+	}
+}
+
+func TestMergeEgressPerNamespace(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	policies[2].Metadata.Namespace = "billing"
+	merged := mergeEgress(policies)
+	var namespaces []string
+	for _, policy := range merged {
+		if policy.Metadata.Name == mergedEgressName {
+			namespaces = append(namespaces, policy.Metadata.Namespace)
+		}
+	}
+	if want := []string{"default", "billing"}; !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("merged egress policies in %v, want %v", namespaces, want)
+	}
+}
+
+func TestAllowSameNamespace(t *testing.T) {
+	for _, ingress := range []bool{false, true} {
+		opts := testOptions()
+		opts.Overrides = map[string]ServiceOverride{"db": {Namespace: "data"}}
+		policies, _ := convertExport(t, directionsExport, Pipeline{Options: opts, SameNamespace: true, SameNamespaceIngress: ingress})
+		self := func(namespace string) []NetworkPolicyPeer {
+			return []NetworkPolicyPeer{{
+				PodSelector:       &LabelSelector{},
+				NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: namespace}},
+			}}
+		}
+
+		web := findPolicy(t, policies, "web")
+		if len(web.Spec.Egress) != 0 {
+			t.Errorf("ingress: %v: ingress-only policy web got egress rules %+v", ingress, web.Spec.Egress)
+		}
+		if got := len(web.Spec.Ingress); (ingress && got != 2) || (!ingress && got != 1) {
+			t.Errorf("ingress: %v: web has %d ingress rules", ingress, got)
+		}
+		if ingress && !reflect.DeepEqual(web.Spec.Ingress[1], Rule{From: self("default")}) {
+			t.Errorf("web same-namespace ingress = %+v, want from %+v", web.Spec.Ingress[1], self("default"))
+		}
+
+		db := findPolicy(t, policies, "db")
+		if egress := db.Spec.Egress; len(egress) != 2 || !reflect.DeepEqual(egress[1], Rule{To: self("data")}) {
+			t.Errorf("ingress: %v: db egress = %+v, want the service rule followed by the pods of namespace data", ingress, egress)
+		}
+		client := findPolicy(t, policies, "client")
+		if egress := client.Spec.Egress; len(egress) != 2 || !reflect.DeepEqual(egress[1], Rule{To: self("default")}) {
+			t.Errorf("ingress: %v: client egress = %+v, want the service rule followed by the pods of namespace default", ingress, egress)
+		}
+		if len(client.Spec.Ingress) != 0 {
+			t.Errorf("ingress: %v: egress-only policy client got ingress rules %+v", ingress, client.Spec.Ingress)
+		}
+	}
+}
+
+func TestMergeEgressKeepsHTTPRules(t *testing.T) {
+	root := readExport(t, "http-rules.json")
+	rules := root.Domains[0].Resources.SecurityPolicies[0].Rules
+	for i := range rules {
+		rules[i].Direction = "OUT"
+	}
+	policies, _, err := (Pipeline{Options: testOptions(), Rules: true, MergeEgress: true}).convert(root, newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := findPolicy(t, policies, mergedEgressName)
+	if merged.Metadata.Annotations[profileAnnotation] == "" {
+		t.Errorf("merged egress annotations = %v, want the context profiles of the rule", merged.Metadata.Annotations)
+	}
+	egress := ciliumPolicy(merged).Spec.Egress
+	if len(egress) != 1 || len(egress[0].ToPorts) != 1 || egress[0].ToPorts[0].Rules == nil || len(egress[0].ToPorts[0].Rules.HTTP) == 0 {
+		t.Errorf("merged egress rules = %+v, want the HTTP rules of the context profile", egress)
+	}
+}
@@ -0,0 +1,98 @@
+package convert
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// explainService writes, for every service matching name, the decision made
+// for each of its entries. name matches the display name, id, path or policy
+// name of a service.
+func explainService(diag *diagnostics, w io.Writer, root Root, name string, opts Options) error {
+	services := servicesByPath(root)
+	found := false
+	for _, service := range root.Services {
+		policyName := serviceName(diag, service, opts.Naming)
+		if name != service.DisplayName && name != service.ID && name != service.Path && name != policyName {
+			continue
+		}
+		found = true
+
+		fmt.Fprintf(w, "Service %q (%s) -> policy %s\n", service.DisplayName, service.Path, policyName)
+		start := len(diag.warnings)
+		entries := serviceEntries(diag, service, services, nil)
+		printWarnings(w, "  ", diag.warnings[start:])
+		if len(entries) == 0 {
+			fmt.Fprintf(w, "  no service entries\n")
+		}
+		for _, entry := range entries {
+			explainEntry(diag, w, service, entry, opts)
+		}
+
+		policy := servicePolicy(diag, service, services, opts)
+		if len(policy.Spec.PolicyTypes) == 0 {
+			fmt.Fprintf(w, "  result: policy has no rules\n")
+		} else {
+			fmt.Fprintf(w, "  result: policyTypes %s, %d ingress and %d egress rules\n",
+				strings.Join(policy.Spec.PolicyTypes, ", "), len(policy.Spec.Ingress), len(policy.Spec.Egress))
+		}
+	}
+	if !found {
+		return fmt.Errorf("no service matches %q", name)
+	}
+	return nil
+}
+
+// explainEntry writes the decisions made for one service entry
+func explainEntry(diag *diagnostics, w io.Writer, service Service, entry ServiceEntry, opts Options) {
+	protocols := entry.protocols()
+	fmt.Fprintf(w, "  entry %q (%s)\n", entry.DisplayName, entry.ResourceType)
+	if !supportedEntry(entry) {
+		fmt.Fprintf(w, "    skipped: %s entries cannot be expressed in a NetworkPolicy\n", entry.ResourceType)
+		return
+	}
+	if len(protocols) == 1 && protocols[0] == "" {
+		fmt.Fprintf(w, "    entry has no L4 protocol\n")
+	} else {
+		fmt.Fprintf(w, "    protocols: %s\n", strings.Join(protocols, ", "))
+	}
+	supported := entryProtocols(diag, service.DisplayName, entry)
+	for _, protocol := range protocols {
+		if !contains(supported, protocol) {
+			fmt.Fprintf(w, "    dropped %s: NetworkPolicies cannot express it\n", strings.ToUpper(protocol))
+		}
+	}
+	if len(supported) == 0 {
+		return
+	}
+	explainPorts(diag, w, service, "ingress", "destination", entry.DestinationPorts, supported, opts)
+	explainPorts(diag, w, service, "egress", "source", entry.SourcePorts, supported, opts)
+}
+
+// explainPorts writes whether one direction of an entry produced a rule
+func explainPorts(diag *diagnostics, w io.Writer, service Service, direction, kind string, ports, protocols []string, opts Options) {
+	if len(ports) == 0 {
+		fmt.Fprintf(w, "    no %s ports, no %s rule\n", kind, direction)
+		return
+	}
+	start := len(diag.warnings)
+	rules := entryPorts(diag, service.DisplayName, ports, protocols, opts)
+	printWarnings(w, "    ", diag.warnings[start:])
+	if len(rules) == 0 {
+		fmt.Fprintf(w, "    skipped %s rule: none of the %s ports %s are usable\n", direction, kind, strings.Join(ports, ", "))
+		return
+	}
+	summaries := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		summaries = append(summaries, portSummary(rule))
+	}
+	fmt.Fprintf(w, "    %s rule created: %s\n", direction, strings.Join(summaries, ", "))
+}
+
+// printWarnings writes the warnings recorded for one step
+func printWarnings(w io.Writer, indent string, warnings []Warning) {
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "%swarning: %s\n", indent, warning.Message)
+	}
+}
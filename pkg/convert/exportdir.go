@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// readExportFile reads an export, decompressing it when the name ends in .gz
+func readExportFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil || !strings.HasSuffix(path, ".gz") {
+		return data, err
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
+
+// exportFiles returns the .json and .json.gz files of a directory in name order
+func exportFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.json.gz"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .json or .json.gz files in %s", dir)
+	}
+	return files, nil
+}
+
+// parseExportDir parses every export file of a directory and merges them
+// into one export. Domains with the same name are combined, so their groups
+// and security policies may come from different files. A service defined in
+// more than one file is an error.
+func parseExportDir(dir string, paths *ExportPaths) (Root, error) {
+	files, err := exportFiles(dir)
+	if err != nil {
+		return Root{}, err
+	}
+
+	var merged Root
+	definedIn := map[string]string{}
+	domains := map[string]int{}
+	for _, file := range files {
+		data, err := readExportFile(file)
+		if err != nil {
+			return Root{}, err
+		}
+		root, err := parseExport(data, paths)
+		if err != nil {
+			return Root{}, fmt.Errorf("%s: %v", file, err)
+		}
+		groups, securityPolicies := 0, 0
+		for _, domain := range root.Domains {
+			groups += len(domain.Resources.Groups)
+			securityPolicies += len(domain.Resources.SecurityPolicies)
+		}
+		log.Printf("Read %s: %d services, %d groups, %d security policies", file, len(root.Services), groups, securityPolicies)
+
+		for _, service := range root.Services {
+			key := stringOr(service.Path, service.DisplayName)
+			if previous, ok := definedIn[key]; ok {
+				return Root{}, fmt.Errorf("service %q is defined in both %s and %s", key, previous, file)
+			}
+			definedIn[key] = file
+			merged.Services = append(merged.Services, service)
+		}
+		for _, domain := range root.Domains {
+			i, ok := domains[domain.DisplayName]
+			if !ok {
+				domains[domain.DisplayName] = len(merged.Domains)
+				merged.Domains = append(merged.Domains, domain)
+				continue
+			}
+			resources := &merged.Domains[i].Resources
+			resources.SecurityPolicies = append(resources.SecurityPolicies, domain.Resources.SecurityPolicies...)
+			resources.Groups = append(resources.Groups, domain.Resources.Groups...)
+		}
+		merged.ContextProfiles = append(merged.ContextProfiles, root.ContextProfiles...)
+		merged.Segments = append(merged.Segments, root.Segments...)
+	}
+	return merged, nil
+}
@@ -0,0 +1,67 @@
+package convert
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExportDir(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	dir := filepath.Join(repoRoot, "json", "split-export")
+	root, err := parseExportDir(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Services) != 1 || root.Services[0].DisplayName != "HTTPS" {
+		t.Errorf("services = %+v, want HTTPS from services.json", root.Services)
+	}
+	if len(root.Domains) != 1 {
+		t.Fatalf("domains = %+v, want the default domain of both files merged", root.Domains)
+	}
+	if resources := root.Domains[0].Resources; len(resources.Groups) != 2 || len(resources.SecurityPolicies) != 1 {
+		t.Errorf("default domain has %d groups and %d security policies, want 2 and 1", len(resources.Groups), len(resources.SecurityPolicies))
+	}
+	for _, want := range []string{
+		"Read " + filepath.Join(dir, "groups.json") + ": 0 services, 2 groups, 0 security policies\n",
+		"Read " + filepath.Join(dir, "security-policies.json.gz") + ": 0 services, 0 groups, 1 security policies\n",
+		"Read " + filepath.Join(dir, "services.json") + ": 1 services, 0 groups, 0 security policies\n",
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs do not report %q:\n%s", want, logs.String())
+		}
+	}
+}
+
+func TestParseExportDirErrors(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	empty := t.TempDir()
+	if _, err := parseExportDir(empty, nil); err == nil || !strings.Contains(err.Error(), "no .json or .json.gz files") {
+		t.Errorf("empty directory: err = %v", err)
+	}
+
+	dir := t.TempDir()
+	data, err := ioutil.ReadFile(filepath.Join(repoRoot, "json", "split-export", "services.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = parseExportDir(dir, nil)
+	want := `service "/infra/services/HTTPS" is defined in both ` + filepath.Join(dir, "a.json") + " and " + filepath.Join(dir, "b.json")
+	if err == nil || err.Error() != want {
+		t.Errorf("duplicate service: err = %v, want %s", err, want)
+	}
+}
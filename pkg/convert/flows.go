@@ -0,0 +1,286 @@
+package convert
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/netip"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// namespaceNameLabel is the label Kubernetes gives every namespace with its
+// name
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// Flow is a connection of a -flows file with its expected outcome
+type Flow struct {
+	Name        string       `yaml:"name"`
+	Source      FlowEndpoint `yaml:"source"`
+	Destination FlowEndpoint `yaml:"destination"`
+	Port        int          `yaml:"port"`
+	Protocol    string       `yaml:"protocol"`
+	Allow       bool         `yaml:"allow"`
+}
+
+// FlowEndpoint is a pod, given by its labels and namespace, or an address
+// outside the cluster, given by ip alone
+type FlowEndpoint struct {
+	Labels          map[string]string `yaml:"labels"`
+	Namespace       string            `yaml:"namespace"`
+	NamespaceLabels map[string]string `yaml:"namespaceLabels"`
+	IP              string            `yaml:"ip"`
+}
+
+// pod reports whether the endpoint is a pod, which policies can select
+func (e FlowEndpoint) pod() bool {
+	return e.Labels != nil || e.IP == ""
+}
+
+// String describes the endpoint, e.g. "default/app=web" or "10.0.0.53"
+func (e FlowEndpoint) String() string {
+	if !e.pod() {
+		return e.IP
+	}
+	return e.Namespace + "/" + selectorString(PodSelector{MatchLabels: e.Labels})
+}
+
+// loadFlows reads a YAML list of flows. Endpoints without a namespace are
+// in the namespace of the policies, and flows without a protocol use TCP.
+func loadFlows(path, namespace string) ([]Flow, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var flows []Flow
+	if err := yaml.UnmarshalStrict(data, &flows); err != nil {
+		return nil, err
+	}
+	for i := range flows {
+		flow := &flows[i]
+		if flow.Name == "" {
+			flow.Name = fmt.Sprintf("flow %d", i+1)
+		}
+		if flow.Port < 1 || flow.Port > maxPort {
+			return nil, fmt.Errorf("%s: port %d is not between 1 and %d", flow.Name, flow.Port, maxPort)
+		}
+		flow.Protocol = strings.ToUpper(flow.Protocol)
+		switch flow.Protocol {
+		case "":
+			flow.Protocol = "TCP"
+		case "TCP", "UDP", "SCTP":
+		default:
+			return nil, fmt.Errorf("%s: unknown protocol %q", flow.Name, flow.Protocol)
+		}
+		for _, endpoint := range []*FlowEndpoint{&flow.Source, &flow.Destination} {
+			if endpoint.IP != "" {
+				if _, err := netip.ParseAddr(endpoint.IP); err != nil {
+					return nil, fmt.Errorf("%s: %v", flow.Name, err)
+				}
+			}
+			if endpoint.Namespace == "" {
+				endpoint.Namespace = namespace
+			}
+		}
+	}
+	return flows, nil
+}
+
+// verifyFlows evaluates each flow against the policies and describes the
+// flows whose outcome is not the expected one
+func verifyFlows(policies []NetworkPolicy, flows []Flow) []string {
+	var mismatches []string
+	for _, flow := range flows {
+		allowed, reason := flowAllowed(policies, flow)
+		if allowed != flow.Allow {
+			mismatches = append(mismatches, fmt.Sprintf("flow %q from %s to %s on %s/%d is %s, expected %s: %s",
+				flow.Name, flow.Source, flow.Destination, flow.Protocol, flow.Port, outcome(allowed), outcome(flow.Allow), reason))
+		}
+	}
+	return mismatches
+}
+
+// outcome names the result of a flow
+func outcome(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "denied"
+}
+
+// flowAllowed evaluates a flow like Kubernetes: it must be allowed by the
+// ingress policies of a destination pod and by the egress policies of a
+// source pod. A pod that no policy selects for a direction is not isolated
+// in that direction. The reason explains the outcome.
+func flowAllowed(policies []NetworkPolicy, flow Flow) (bool, string) {
+	if flow.Destination.pod() {
+		isolating, allowing := evaluateDirection(policies, flow, "Ingress")
+		if len(isolating) > 0 && len(allowing) == 0 {
+			return false, fmt.Sprintf("the ingress of the destination is restricted by %s, and none allows it", strings.Join(isolating, ", "))
+		}
+	}
+	if flow.Source.pod() {
+		isolating, allowing := evaluateDirection(policies, flow, "Egress")
+		if len(isolating) > 0 && len(allowing) == 0 {
+			return false, fmt.Sprintf("the egress of the source is restricted by %s, and none allows it", strings.Join(isolating, ", "))
+		}
+	}
+	return true, "no policy restricts it, or a policy in each restricted direction allows it"
+}
+
+// evaluateDirection returns the policies that select the flow's pod for a
+// direction, and those of them with a rule allowing the flow
+func evaluateDirection(policies []NetworkPolicy, flow Flow, policyType string) (isolating, allowing []string) {
+	subject, peer := flow.Destination, flow.Source
+	if policyType == "Egress" {
+		subject, peer = flow.Source, flow.Destination
+	}
+	for _, policy := range policies {
+		if policy.Metadata.Namespace != subject.Namespace || !restricts(policy, policyType) ||
+			!selectorMatches(policy.Spec.PodSelector.MatchLabels, policy.Spec.PodSelector.MatchExpressions, subject.Labels) {
+			continue
+		}
+		isolating = append(isolating, policyName(policy))
+		rules, peers := policy.Spec.Ingress, func(rule Rule) []NetworkPolicyPeer { return rule.From }
+		if policyType == "Egress" {
+			rules, peers = policy.Spec.Egress, func(rule Rule) []NetworkPolicyPeer { return rule.To }
+		}
+		for _, rule := range rules {
+			if peersMatch(peers(rule), peer, policy.Metadata.Namespace) && portsMatch(rule.Ports, flow) {
+				allowing = append(allowing, policyName(policy))
+				break
+			}
+		}
+	}
+	return isolating, allowing
+}
+
+// restricts reports whether a policy isolates the pods it selects in a
+// direction. Kubernetes infers Ingress for a policy without policy types,
+// and Egress when it has egress rules.
+func restricts(policy NetworkPolicy, policyType string) bool {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return policyType == "Ingress" || len(policy.Spec.Egress) > 0
+	}
+	return hasPolicyType(policy, policyType)
+}
+
+// peersMatch reports whether an endpoint is one of the peers of a rule. No
+// peers means any endpoint. A selector peer without namespaceSelector only
+// matches pods in the policy's namespace.
+func peersMatch(peers []NetworkPolicyPeer, endpoint FlowEndpoint, namespace string) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			if ipBlockMatches(*peer.IPBlock, endpoint.IP) {
+				return true
+			}
+			continue
+		}
+		if !endpoint.pod() {
+			continue
+		}
+		if peer.NamespaceSelector == nil {
+			if endpoint.Namespace != namespace {
+				continue
+			}
+		} else {
+			labels := copyLabels(endpoint.NamespaceLabels)
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[namespaceNameLabel] = endpoint.Namespace
+			if !selectorMatches(peer.NamespaceSelector.MatchLabels, peer.NamespaceSelector.MatchExpressions, labels) {
+				continue
+			}
+		}
+		if peer.PodSelector == nil || selectorMatches(peer.PodSelector.MatchLabels, peer.PodSelector.MatchExpressions, endpoint.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// ipBlockMatches reports whether an address is inside the CIDR of an
+// ipBlock and outside its exceptions
+func ipBlockMatches(block IPBlock, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	cidr, err := netip.ParsePrefix(block.CIDR)
+	if err != nil || !cidr.Contains(addr) {
+		return false
+	}
+	for _, except := range block.Except {
+		if prefix, err := netip.ParsePrefix(except); err == nil && prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// portsMatch reports whether the flow's port is one of the ports of a rule.
+// No ports means all ports. Named ports need the pod's container ports to
+// resolve, so they never match.
+func portsMatch(ports []PortRule, flow Flow) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, port := range ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		if protocol != flow.Protocol {
+			continue
+		}
+		switch {
+		case port.Port == nil:
+			return true
+		case port.Port.StrVal != "":
+		case port.EndPort != nil:
+			if flow.Port >= port.Port.IntVal && flow.Port <= *port.EndPort {
+				return true
+			}
+		case flow.Port == port.Port.IntVal:
+			return true
+		}
+	}
+	return false
+}
+
+// selectorMatches reports whether labels satisfy a label selector
+func selectorMatches(matchLabels map[string]string, exprs []LabelSelectorRequirement, labels map[string]string) bool {
+	for key, value := range matchLabels {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	for _, expr := range exprs {
+		value, ok := labels[expr.Key]
+		switch expr.Operator {
+		case "In":
+			if !ok || !contains(expr.Values, value) {
+				return false
+			}
+		case "NotIn":
+			if ok && contains(expr.Values, value) {
+				return false
+			}
+		case "Exists":
+			if !ok {
+				return false
+			}
+		case "DoesNotExist":
+			if ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package convert
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyFlows(t *testing.T) {
+	policies, _, err := Pipeline{Options: testOptions(), Rules: true}.convert(readExport(t, "ipset-group.json"), newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	flows, err := loadFlows(filepath.Join(repoRoot, "json", "flows.yaml"), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flows) != 4 || flows[2].Protocol != "UDP" || flows[0].Protocol != "TCP" || flows[0].Destination.Namespace != "default" {
+		t.Fatalf("flows = %+v, want four flows with defaulted protocol and namespace", flows)
+	}
+	if mismatches := verifyFlows(policies, flows); len(mismatches) != 0 {
+		t.Errorf("mismatches = %q, want none", mismatches)
+	}
+
+	for i := range flows {
+		flows[i].Allow = !flows[i].Allow
+	}
+	mismatches := verifyFlows(policies, flows)
+	if len(mismatches) != len(flows) {
+		t.Fatalf("mismatches with inverted expectations = %q, want one per flow", mismatches)
+	}
+	want := `flow "corp-to-web-http" from 10.10.4.20 to default/app=web on TCP/80 is denied, expected allowed: the ingress of the destination is restricted by `
+	if !strings.HasPrefix(mismatches[1], want) {
+		t.Errorf("mismatch = %q, want prefix %q", mismatches[1], want)
+	}
+}
+
+func TestLoadFlowsErrors(t *testing.T) {
+	tests := map[string]string{
+		"port":     "- source: {ip: 10.0.0.1}\n  destination: {labels: {app: web}}\n  port: 0\n",
+		"protocol": "- source: {ip: 10.0.0.1}\n  destination: {labels: {app: web}}\n  port: 80\n  protocol: ICMP\n",
+		"ip":       "- source: {ip: 10.0.0}\n  destination: {labels: {app: web}}\n  port: 80\n",
+		"field":    "- source: {ip: 10.0.0.1}\n  destination: {labels: {app: web}}\n  port: 80\n  expected: true\n",
+	}
+	for name, data := range tests {
+		path := filepath.Join(t.TempDir(), "flows.yaml")
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadFlows(path, "default"); err == nil {
+			t.Errorf("%s: loadFlows accepted\n%s", name, data)
+		}
+	}
+}
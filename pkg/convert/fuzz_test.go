@@ -0,0 +1,66 @@
+package convert
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+const maxSeedSize = 64 << 10
+
+var dns1123Label = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+func FuzzParseRoot(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join(repoRoot, "json/*.json"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		// The full exports are too large for the mutator to make progress
+		if len(data) > maxSeedSize {
+			continue
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(`{"services":[{"display_name":"x","service_entries":[{"destination_ports":["1-2"]}]}]}`))
+
+	log.SetOutput(io.Discard)
+	opts := Options{Namespace: "default", Naming: NameOptions{Replacement: "-"}}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		diag := newDiagnostics(verbosityQuiet)
+		root, err := parseRoot(data)
+		if err != nil {
+			return
+		}
+		servicePolicies(diag, root, opts)
+		rulePolicies(diag, root, opts)
+	})
+}
+
+func FuzzSanitizeName(f *testing.F) {
+	for _, name := range []string{"AD Server", "Web Service", "IMAP_SSL", "--", "café-svc", ""} {
+		f.Add(name, "-", false)
+	}
+	f.Add("SAP Lotus Domino - Connector", "-", true)
+	f.Add("IMAP_SSL", "", false)
+
+	f.Fuzz(func(t *testing.T, name, replacement string, collapse bool) {
+		if !validNameReplacement.MatchString(replacement) {
+			t.Skip()
+		}
+		sanitized := sanitizeName(name, NameOptions{Replacement: replacement, Collapse: collapse})
+		if sanitized == "" {
+			return
+		}
+		if len(sanitized) > maxNameLength || !dns1123Label.MatchString(sanitized) {
+			t.Fatalf("sanitizeName(%q) = %q is not a valid DNS-1123 label", name, sanitized)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package convert
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// repoRoot is where the examples and their expected outputs live, relative
+// to the package directory
+const repoRoot = "../.."
+
+// runBinary runs the program in the repository root and returns its stdout
+func runBinary(t *testing.T, binary string, args ...string) []byte {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = repoRoot
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%v: %v\n%s", args, err, stderr.String())
+	}
+	return stdout.Bytes()
+}
+
+// TestGoldens runs the examples and compares their output with the expected
+// output committed next to them
+func TestGoldens(t *testing.T) {
+	binary := buildBinary(t)
+	fieldOrder := []string{"-f", "json/scoped-tags.json", "-tag-label", "team", "-tag-label", "env=example.com/env", "-argocd-app", "netpol",
+		"-owner-api-version", "apps/v1", "-owner-kind", "Deployment", "-owner-name", "netpol-operator", "-owner-uid", "6f1c2a3e-8d4b-4f5a-9c7e-1b2d3e4f5a6b"}
+	tests := []struct {
+		name   string
+		args   []string
+		golden string
+	}{
+		{"demo", []string{"-f", "json/Example1.json", "-n", "demo"}, "demo-netpol.yaml"},
+		{"egress-only", []string{"-f", "json/egress-only.json"}, "json/egress-only.yaml"},
+		{"ipset-group", []string{"-f", "json/ipset-group.json", "-rules"}, "json/ipset-group.yaml"},
+		{"rule-ids", []string{"-f", "json/rule-ids.json", "-rules", "-normalize-names", "-name-from", "id"}, "json/rule-ids.txt"},
+		{"shared-peers", []string{"-f", "json/shared-peers.json", "-rules", "-annotate-peer-groups"}, "json/shared-peers.yaml"},
+		{"combined-peers", []string{"-f", "json/combined-peers.json", "-rules", "-group-labels", "json/group-labels.yaml", "-n", "payments-data", "-flows", "json/combined-peers-flows.yaml"}, "json/combined-peers.yaml"},
+		{"yaml-indent", []string{"-f", "json/Example2.json", "-n", "demo", "-yaml-indent", "4", "-yaml-style", "flow"}, "json/yaml-indent.yaml"},
+		{"field-order", fieldOrder, "json/field-order.yaml"},
+		{"field-order-jsonl", append(fieldOrder, "-format", "jsonl"), "json/field-order.jsonl"},
+		{"port-usage", []string{"-f", "json/port-usage.json", "-port-usage", "csv"}, "json/port-usage.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := ioutil.ReadFile(filepath.Join(repoRoot, tt.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := runBinary(t, binary, tt.args...); !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s:\n%s", tt.golden, got)
+			}
+		})
+	}
+
+	t.Run("namespace-layout", func(t *testing.T) {
+		want, err := ioutil.ReadFile(filepath.Join(repoRoot, "json/namespace-layout.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		runBinary(t, binary, "-f", "json/namespace-lookup.json", "-output-namespace-from-file", "json/namespace-lookup.csv", "-namespace-id-tag", "app-id",
+			"-out-dir", dir, "-layout", "namespace", "-kustomize")
+		var files []string
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			files = append(files, "./"+filepath.ToSlash(rel))
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(files)
+		if got := strings.Join(files, "\n") + "\n"; got != string(want) {
+			t.Errorf("files differ from json/namespace-layout.txt:\n%s", got)
+		}
+	})
+
+	want := runBinary(t, binary, "-f", "json/string-ports.json")
+	for _, example := range []string{"json/numeric-ports.json", "json/combined-ports.json"} {
+		t.Run(filepath.Base(example), func(t *testing.T) {
+			if got := runBinary(t, binary, "-f", example); !bytes.Equal(got, want) {
+				t.Errorf("%s and json/string-ports.json produce different policies:\n%s", example, got)
+			}
+		})
+	}
+}
+
+// TestExamplesHaveNoNulls checks that no example emits null fields, such as
+// creationTimestamp: null, which strict validators reject
+func TestExamplesHaveNoNulls(t *testing.T) {
+	binary := buildBinary(t)
+	files, err := filepath.Glob(filepath.Join(repoRoot, "json/*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		t.Run(name, func(t *testing.T) {
+			out := runBinary(t, binary, "-f", "json/"+name, "-rules")
+			for _, line := range strings.Split(string(out), "\n") {
+				if strings.HasSuffix(line, ": null") {
+					t.Errorf("null field in output: %q", line)
+				}
+			}
+		})
+	}
+}
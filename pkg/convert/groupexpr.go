@@ -0,0 +1,141 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NSX group expression resource types and conjunctions
+const (
+	conditionExpression   = "Condition"
+	conjunctionExpression = "ConjunctionOperator"
+	nestedExpression      = "NestedExpression"
+	conjunctionAnd        = "AND"
+	conjunctionOr         = "OR"
+)
+
+// groupSelectors converts the tag conditions of a group into pod selectors,
+// one per alternative: criteria joined by AND become a single selector, and
+// criteria joined by OR become separate selectors, which NetworkPolicy
+// peers OR together. AND binds tighter than OR. It returns false when the
+// group has no conditions, or one that has no label equivalent, so the
+// group is selected by its name instead.
+func groupSelectors(diag *diagnostics, group Group) ([]LabelSelector, bool) {
+	hasConditions := false
+	for _, expr := range group.Expression {
+		if expr.ResourceType == conditionExpression || expr.ResourceType == nestedExpression {
+			hasConditions = true
+		}
+	}
+	if !hasConditions {
+		return nil, false
+	}
+	terms, err := expressionTerms(group.Expression)
+	if err != nil {
+		diag.warn(group.DisplayName, warnUnsupportedExpression, "group %q: %v, selecting it as app=%s instead", group.DisplayName, err, group.DisplayName)
+		return nil, false
+	}
+	selectors := make([]LabelSelector, 0, len(terms))
+	for _, term := range terms {
+		selector := LabelSelector{}
+		for _, condition := range term {
+			if err := addCondition(&selector, condition); err != nil {
+				diag.warn(group.DisplayName, warnUnsupportedExpression, "group %q: %v, selecting it as app=%s instead", group.DisplayName, err, group.DisplayName)
+				return nil, false
+			}
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, true
+}
+
+// expressionTerms expands an expression list into the alternatives it
+// allows, each a list of conditions that must all hold
+func expressionTerms(exprs []GroupExpression) ([][]GroupExpression, error) {
+	var terms [][]GroupExpression
+	current := [][]GroupExpression{{}}
+	expectCriterion := true
+	for _, expr := range exprs {
+		if expr.ResourceType == conjunctionExpression {
+			if expectCriterion {
+				return nil, fmt.Errorf("conjunction %s has no criterion before it", expr.ConjunctionOperator)
+			}
+			switch strings.ToUpper(expr.ConjunctionOperator) {
+			case conjunctionAnd:
+			case conjunctionOr:
+				terms = append(terms, current...)
+				current = [][]GroupExpression{{}}
+			default:
+				return nil, fmt.Errorf("unknown conjunction %q", expr.ConjunctionOperator)
+			}
+			expectCriterion = true
+			continue
+		}
+		if !expectCriterion {
+			return nil, fmt.Errorf("criteria without a conjunction between them")
+		}
+		var criterion [][]GroupExpression
+		switch expr.ResourceType {
+		case conditionExpression:
+			criterion = [][]GroupExpression{{expr}}
+		case nestedExpression:
+			nested, err := expressionTerms(expr.Expressions)
+			if err != nil {
+				return nil, err
+			}
+			criterion = nested
+		default:
+			return nil, fmt.Errorf("%s criteria cannot be combined with conditions", expr.ResourceType)
+		}
+		// Each alternative so far combines with each alternative of the criterion
+		var combined [][]GroupExpression
+		for _, left := range current {
+			for _, right := range criterion {
+				combined = append(combined, append(append([]GroupExpression{}, left...), right...))
+			}
+		}
+		current = combined
+		expectCriterion = false
+	}
+	if expectCriterion {
+		return nil, fmt.Errorf("expression ends with a conjunction")
+	}
+	return append(terms, current...), nil
+}
+
+// addCondition adds a condition on a VM tag, given as "scope|tag", to a
+// selector as a requirement on the label named after the scope. A tag
+// without value only requires the label to exist.
+func addCondition(selector *LabelSelector, condition GroupExpression) error {
+	if condition.Key != "Tag" {
+		return fmt.Errorf("condition on %s has no label equivalent", condition.Key)
+	}
+	key, value, _ := strings.Cut(condition.Value, "|")
+	if key == "" {
+		return fmt.Errorf("tag %q has no scope to use as label key", condition.Value)
+	}
+	if err := validateLabel(key, value); err != nil {
+		return err
+	}
+	switch {
+	case condition.Operator == "EQUALS" && value == "":
+		selector.MatchExpressions = append(selector.MatchExpressions, LabelSelectorRequirement{Key: key, Operator: "Exists"})
+	case condition.Operator == "EQUALS":
+		if existing, ok := selector.MatchLabels[key]; ok && existing != value {
+			// Both must hold, which no pod can satisfy, as for NSX
+			selector.MatchExpressions = append(selector.MatchExpressions, LabelSelectorRequirement{Key: key, Operator: "In", Values: []string{value}})
+			return nil
+		}
+		if selector.MatchLabels == nil {
+			selector.MatchLabels = map[string]string{}
+		}
+		selector.MatchLabels[key] = value
+	case condition.Operator == "NOTEQUALS" && value == "":
+		selector.MatchExpressions = append(selector.MatchExpressions, LabelSelectorRequirement{Key: key, Operator: "DoesNotExist"})
+	case condition.Operator == "NOTEQUALS":
+		selector.MatchExpressions = append(selector.MatchExpressions, LabelSelectorRequirement{Key: key, Operator: "NotIn", Values: []string{value}})
+	default:
+		return fmt.Errorf("tag operator %s has no label equivalent", condition.Operator)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package convert
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// GroupLabels are the labels of the pods an NSX group's workloads became,
+// and optionally of the namespace they run in
+type GroupLabels struct {
+	Namespace map[string]string `yaml:"namespace"`
+	Pod       map[string]string `yaml:"pod"`
+}
+
+// loadGroupLabels reads a YAML map from group path or display name to the
+// pod labels, and optionally the namespace labels, of its workloads
+func loadGroupLabels(path string) (map[string]GroupLabels, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var groups map[string]GroupLabels
+	if err := yaml.UnmarshalStrict(data, &groups); err != nil {
+		return nil, err
+	}
+	for group, labels := range groups {
+		if len(labels.Pod) == 0 {
+			return nil, fmt.Errorf("group %q has no pod labels", group)
+		}
+		for _, set := range []map[string]string{labels.Namespace, labels.Pod} {
+			for key, value := range set {
+				if err := validateLabel(key, value); err != nil {
+					return nil, fmt.Errorf("group %q: %v", group, err)
+				}
+			}
+		}
+	}
+	return groups, nil
+}
+
+// mappedGroup returns the -group-labels mapping of a group, given by its
+// path or display name
+func mappedGroup(group Group, groups map[string]GroupLabels) (GroupLabels, bool) {
+	if labels, ok := groups[group.Path]; ok {
+		return labels, true
+	}
+	labels, ok := groups[group.DisplayName]
+	return labels, ok
+}
+
+// peer returns the peer that selects the group's pods. With namespace
+// labels, a single peer holds both selectors, so it only matches those pods
+// in those namespaces. Without, the pods are in the given namespaces, or in
+// the policy's namespace when namespaces is nil.
+func (l GroupLabels) peer(namespaces *LabelSelector) NetworkPolicyPeer {
+	peer := NetworkPolicyPeer{
+		PodSelector:       &LabelSelector{MatchLabels: copyLabels(l.Pod)},
+		NamespaceSelector: namespaces,
+	}
+	if len(l.Namespace) > 0 {
+		peer.NamespaceSelector = &LabelSelector{MatchLabels: copyLabels(l.Namespace)}
+	}
+	return peer
+}
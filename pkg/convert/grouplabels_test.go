@@ -0,0 +1,91 @@
+package convert
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCombinedSelectorPeer(t *testing.T) {
+	groups, err := loadGroupLabels(filepath.Join(repoRoot, "json", "group-labels.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions()
+	opts.Namespace = "payments-data"
+	opts.GroupLabels = groups
+	policies, _, err := Pipeline{Options: opts, Rules: true}.convert(readExport(t, "combined-peers.json"), newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := findPolicy(t, policies, "payments-api-to-db")
+	if want := map[string]string{"app": "postgres", "tier": "db"}; !reflect.DeepEqual(policy.Spec.PodSelector.MatchLabels, want) {
+		t.Errorf("podSelector = %v, want the payments-db labels %v", policy.Spec.PodSelector.MatchLabels, want)
+	}
+	want := []NetworkPolicyPeer{{
+		PodSelector:       &LabelSelector{MatchLabels: map[string]string{"app": "payments-api"}},
+		NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: "payments"}},
+	}}
+	if len(policy.Spec.Ingress) != 1 || !reflect.DeepEqual(policy.Spec.Ingress[0].From, want) {
+		t.Errorf("ingress = %+v, want a single peer with both selectors", policy.Spec.Ingress)
+	}
+}
+
+func TestCombinedSelectorPeerWithLabelPrefix(t *testing.T) {
+	groups, err := loadGroupLabels(filepath.Join(repoRoot, "json", "group-labels.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions()
+	opts.Namespace = "payments-data"
+	opts.GroupLabels = groups
+	policies, _, err := Pipeline{Options: opts, Rules: true, LabelPrefix: "nsx.example.com/"}.convert(readExport(t, "combined-peers.json"), newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := findPolicy(t, policies, "payments-api-to-db")
+	if want := map[string]string{"app": "postgres", "tier": "db"}; !reflect.DeepEqual(policy.Spec.PodSelector.MatchLabels, want) {
+		t.Errorf("podSelector = %v, want the payments-db labels %v as written", policy.Spec.PodSelector.MatchLabels, want)
+	}
+	want := []NetworkPolicyPeer{{
+		PodSelector:       &LabelSelector{MatchLabels: map[string]string{"app": "payments-api"}},
+		NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{namespaceNameLabel: "payments"}},
+	}}
+	if len(policy.Spec.Ingress) != 1 || !reflect.DeepEqual(policy.Spec.Ingress[0].From, want) {
+		t.Errorf("ingress = %+v, want the mapped labels without the prefix", policy.Spec.Ingress)
+	}
+}
+
+func TestGroupLabelsPeer(t *testing.T) {
+	pods := GroupLabels{Pod: map[string]string{"app": "postgres"}}
+	if peer := pods.peer(nil); peer.NamespaceSelector != nil {
+		t.Errorf("peer without namespace labels = %+v, want the policy's namespace", peer)
+	}
+	scoped := &LabelSelector{MatchLabels: map[string]string{"env": "prod"}}
+	if peer := pods.peer(scoped); peer.NamespaceSelector != scoped {
+		t.Errorf("peer = %+v, want the given namespaces", peer)
+	}
+	both := GroupLabels{Namespace: map[string]string{"team": "payments"}, Pod: pods.Pod}
+	if peer := both.peer(scoped); !reflect.DeepEqual(peer.NamespaceSelector.MatchLabels, both.Namespace) {
+		t.Errorf("peer = %+v, want the group's namespace labels to replace the given namespaces", peer)
+	}
+}
+
+func TestLoadGroupLabelsErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no pod labels": "web:\n  namespace:\n    team: web\n",
+		"invalid label": "web:\n  pod:\n    app: not valid\n",
+		"unknown field": "web:\n  pods:\n    app: web\n",
+	} {
+		path := filepath.Join(t.TempDir(), "group-labels.yaml")
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadGroupLabels(path); err == nil {
+			t.Errorf("%s: loadGroupLabels accepted\n%s", name, data)
+		}
+	}
+}
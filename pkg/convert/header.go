@@ -0,0 +1,28 @@
+package convert
+
+import "strings"
+
+// headerComment turns the -header text into a YAML comment block, one
+// comment line per line of text. Lines that already start with # are kept.
+func headerComment(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case strings.HasPrefix(line, "#"):
+		case line == "":
+			line = "#"
+		default:
+			line = "# " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// prependHeader puts the header comment at the top of every file
+func prependHeader(files []PolicyFile, header string) {
+	for i := range files {
+		files[i].Data = append([]byte(header), files[i].Data...)
+	}
+}
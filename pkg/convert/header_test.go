@@ -0,0 +1,38 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestHeaderComment(t *testing.T) {
+	got := headerComment("Generated by nsx-converter v1\n\n# already a comment  \nsee README\n")
+	want := "# Generated by nsx-converter v1\n#\n# already a comment\n# see README\n"
+	if got != want {
+		t.Errorf("headerComment = %q, want %q", got, want)
+	}
+}
+
+func TestPrependHeader(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	files, _, err := policyFiles(policies, []string{kindNetworkPolicy}, defaultRender, defaultYAML, layoutFlat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := headerComment("Generated by nsx-converter\nkind: not a field")
+	prependHeader(files, header)
+	for _, file := range files {
+		if !strings.HasPrefix(string(file.Data), header+"apiVersion: ") {
+			t.Errorf("%s does not start with the header:\n%s", file.Path, file.Data)
+		}
+		var policy map[string]interface{}
+		if err := yaml.Unmarshal(file.Data, &policy); err != nil {
+			t.Errorf("%s with the header is not valid YAML: %v", file.Path, err)
+		}
+		if policy["kind"] != "NetworkPolicy" {
+			t.Errorf("%s with the header has kind %v, want NetworkPolicy", file.Path, policy["kind"])
+		}
+	}
+}
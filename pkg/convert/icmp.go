@@ -0,0 +1,89 @@
+package convert
+
+import (
+	"fmt"
+	"strings"
+)
+
+// icmpServiceEntry is the resource type of NSX ICMP service entries
+const icmpServiceEntry = "ICMPTypeServiceEntry"
+
+// icmpAnnotation records the ICMP types of a service on output kinds that
+// cannot match them
+const icmpAnnotation = "nsx.vmware.com/icmp"
+
+// icmpFamilies maps the NSX ICMP protocols to Cilium families
+var icmpFamilies = map[string]string{
+	"ICMPv4": "IPv4",
+	"ICMPv6": "IPv6",
+}
+
+// CiliumICMPRule is an icmps entry of a Cilium rule
+type CiliumICMPRule struct {
+	Fields []CiliumICMPField `yaml:"fields"`
+}
+
+// CiliumICMPField matches an ICMP type of a family
+type CiliumICMPField struct {
+	Family string `yaml:"family"`
+	Type   int    `yaml:"type"`
+}
+
+// String describes the match, e.g. "ICMPv6 type 135"
+func (f CiliumICMPField) String() string {
+	return fmt.Sprintf("ICMP%s type %d", strings.TrimPrefix(f.Family, "IP"), f.Type)
+}
+
+// entryICMP returns the ICMP match of an ICMP service entry. Cilium matches
+// a type with any code, and cannot match every type of a family at once.
+func entryICMP(diag *diagnostics, service string, entry ServiceEntry) (CiliumICMPField, bool) {
+	family, ok := icmpFamilies[entry.Protocol]
+	if !ok {
+		diag.warn(service, warnUnsupportedICMP, "service %s: entry %s has unknown ICMP protocol %q", service, entry.DisplayName, entry.Protocol)
+		return CiliumICMPField{}, false
+	}
+	if entry.ICMPType == nil {
+		diag.warn(service, warnUnsupportedICMP, "service %s: entry %s allows every %s type, which Cilium cannot match", service, entry.DisplayName, entry.Protocol)
+		return CiliumICMPField{}, false
+	}
+	if entry.ICMPCode != nil && *entry.ICMPCode != 0 {
+		diag.warn(service, warnUnsupportedICMP, "service %s: entry %s matches ICMP code %d, but Cilium allows every code of type %d", service, entry.DisplayName, *entry.ICMPCode, *entry.ICMPType)
+	}
+	return CiliumICMPField{Family: family, Type: *entry.ICMPType}, true
+}
+
+// annotateICMP records the ICMP types of a policy, which only Cilium output
+// enforces
+func annotateICMP(diag *diagnostics, policy *NetworkPolicy) {
+	if len(policy.icmps) == 0 {
+		return
+	}
+	types := make([]string, len(policy.icmps))
+	for i, field := range policy.icmps {
+		types[i] = field.String()
+	}
+	if policy.Metadata.Annotations == nil {
+		policy.Metadata.Annotations = map[string]string{}
+	}
+	policy.Metadata.Annotations[icmpAnnotation] = strings.Join(types, ", ")
+	diag.warnUnlessCilium(policy.source, warnUnsupportedICMP, "service %s allows %s; NetworkPolicies cannot match ICMP, only -output-kind cilium enforces it", policy.source, strings.Join(types, ", "))
+}
+
+// mergeICMPs adds the ICMP types a policy does not allow yet
+func mergeICMPs(policy *NetworkPolicy, fields []CiliumICMPField) {
+	for _, field := range fields {
+		if !containsICMP(policy.icmps, field) {
+			policy.icmps = append(policy.icmps, field)
+		}
+	}
+}
+
+// containsICMP reports whether fields already allow an ICMP type
+func containsICMP(fields []CiliumICMPField, field CiliumICMPField) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ExportPaths locate services and their fields in an export whose shape
+// differs from the NSX Policy API. Each path is a small JSONPath subset:
+// $, .name, ['name'], [n] and [*].
+type ExportPaths struct {
+	// Services selects the service objects from the export root
+	Services string
+	// Name and Entries are evaluated against each service object
+	Name    string
+	Entries string
+	// Ports and Protocol are evaluated against each entry object, so each
+	// entry keeps its own ports and protocols
+	Ports    string
+	Protocol string
+}
+
+// Default export paths, matching the NSX Policy API shape
+const (
+	defaultServicesPath = "$.services[*]"
+	defaultNamePath     = "$.display_name"
+	defaultEntriesPath  = "$.service_entries[*]"
+	defaultPortsPath    = "$.destination_ports[*]"
+	defaultProtocolPath = "$.l4_protocol"
+)
+
+// pathStep is one step of a parsed path. An empty key with index -1 is [*].
+type pathStep struct {
+	key   string
+	index int
+}
+
+// parsePath parses a path into its steps
+func parsePath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path %q must start with $", path)
+	}
+	var steps []pathStep
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("path %q has an empty name", path)
+			}
+			steps = append(steps, pathStep{key: key})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("path %q has an unterminated ['name']", path)
+			}
+			steps = append(steps, pathStep{key: rest[2:end]})
+			rest = rest[end+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q has an unterminated index", path)
+			}
+			if rest[1:end] == "*" {
+				steps = append(steps, pathStep{index: -1})
+			} else {
+				index, err := strconv.Atoi(rest[1:end])
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("path %q has an invalid index %q", path, rest[1:end])
+				}
+				steps = append(steps, pathStep{index: index})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("path %q is invalid at %q", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// evalPath returns every value the steps select from doc. Missing keys and
+// out of range indexes select nothing.
+func evalPath(steps []pathStep, doc interface{}) []interface{} {
+	values := []interface{}{doc}
+	for _, step := range steps {
+		var next []interface{}
+		for _, value := range values {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				if step.key != "" {
+					if child, ok := v[step.key]; ok {
+						next = append(next, child)
+					}
+				}
+			case []interface{}:
+				switch {
+				case step.key != "":
+				case step.index < 0:
+					next = append(next, v...)
+				case step.index < len(v):
+					next = append(next, v[step.index])
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// pathStrings converts selected scalar values into strings. Arrays selected
+// by a path without [*] are flattened.
+func pathStrings(values []interface{}) []string {
+	var result []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			result = append(result, v)
+		case float64:
+			result = append(result, strconv.FormatFloat(v, 'f', -1, 64))
+		case []interface{}:
+			result = append(result, pathStrings(v)...)
+		}
+	}
+	return result
+}
+
+// parseCustomRoot builds the services of an export using paths. Each entry
+// of a service pairs its own ports with its own protocols.
+func parseCustomRoot(data []byte, paths ExportPaths) (Root, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Root{}, err
+	}
+	compiled := map[string][]pathStep{}
+	for flagName, path := range map[string]string{
+		"-services-path": paths.Services,
+		"-name-path":     paths.Name,
+		"-entries-path":  paths.Entries,
+		"-ports-path":    paths.Ports,
+		"-protocol-path": paths.Protocol,
+	} {
+		steps, err := parsePath(path)
+		if err != nil {
+			return Root{}, fmt.Errorf("%s: %v", flagName, err)
+		}
+		compiled[flagName] = steps
+	}
+
+	var root Root
+	for _, value := range evalPath(compiled["-services-path"], doc) {
+		names := pathStrings(evalPath(compiled["-name-path"], value))
+		if len(names) == 0 {
+			return Root{}, fmt.Errorf("service %d has no name at %s", len(root.Services), paths.Name)
+		}
+		service := Service{DisplayName: names[0]}
+		for _, entry := range evalPath(compiled["-entries-path"], value) {
+			var protocols []string
+			seen := map[string]bool{}
+			for _, protocol := range pathStrings(evalPath(compiled["-protocol-path"], entry)) {
+				protocol = strings.ToUpper(protocol)
+				if !seen[protocol] {
+					seen[protocol] = true
+					protocols = append(protocols, protocol)
+				}
+			}
+			service.ServiceEntries = append(service.ServiceEntries, ServiceEntry{
+				DisplayName:      names[0],
+				L4Protocols:      protocols,
+				DestinationPorts: pathStrings(evalPath(compiled["-ports-path"], entry)),
+			})
+		}
+		root.Services = append(root.Services, service)
+	}
+	return root, nil
+}
@@ -0,0 +1,76 @@
+package convert
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// ingressPorts lists the protocol/port pairs the ingress rules of a policy allow
+func ingressPorts(policy NetworkPolicy) []string {
+	var ports []string
+	for _, rule := range policy.Spec.Ingress {
+		for _, port := range rule.Ports {
+			ports = append(ports, portSummary(port))
+		}
+	}
+	sort.Strings(ports)
+	return ports
+}
+
+func TestParseCustomRootKeepsEntryPairs(t *testing.T) {
+	data := []byte(`{"items": [{"name": "Mixed", "rules": [
+		{"proto": "tcp", "ports": [80, "443"]},
+		{"proto": "udp", "ports": ["53"]}
+	]}]}`)
+	root, err := parseCustomRoot(data, ExportPaths{
+		Services: "$.items[*]",
+		Name:     "$.name",
+		Entries:  "$.rules[*]",
+		Ports:    "$.ports[*]",
+		Protocol: "$.proto",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := servicePolicies(newDiagnostics(verbosityQuiet), root, testOptions())
+	if len(policies) != 1 {
+		t.Fatalf("got %d policies, want 1", len(policies))
+	}
+	if got, want := ingressPorts(policies[0]), []string{"TCP/443", "TCP/80", "UDP/53"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ports = %v, want %v", got, want)
+	}
+}
+
+func TestParseCustomRootMatchesNSXShape(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join(repoRoot, "json/Example1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := parseRoot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := parseCustomRoot(data, ExportPaths{
+		Services: defaultServicesPath,
+		Name:     defaultNamePath,
+		Entries:  defaultEntriesPath,
+		Ports:    defaultPortsPath,
+		Protocol: defaultProtocolPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPolicies := servicePolicies(newDiagnostics(verbosityQuiet), want, testOptions())
+	gotPolicies := servicePolicies(newDiagnostics(verbosityQuiet), got, testOptions())
+	if len(gotPolicies) != len(wantPolicies) {
+		t.Fatalf("got %d policies, want %d", len(gotPolicies), len(wantPolicies))
+	}
+	for i := range wantPolicies {
+		if g, w := ingressPorts(gotPolicies[i]), ingressPorts(wantPolicies[i]); !reflect.DeepEqual(g, w) {
+			t.Errorf("%s: ports = %v, want %v", wantPolicies[i].Metadata.Name, g, w)
+		}
+	}
+}
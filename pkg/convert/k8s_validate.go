@@ -0,0 +1,143 @@
+//go:build k8svalidate
+
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+
+	goyaml "gopkg.in/yaml.v2"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+// k8sValidation reports whether -k8s-validate is available in this build
+const k8sValidation = true
+
+var supportedProtocols = []string{"TCP", "UDP", "SCTP"}
+
+// validateK8s decodes a generated policy into the Kubernetes networking/v1
+// types and checks the fields the hand-rolled struct does not constrain
+func validateK8s(policy NetworkPolicy) error {
+	data, err := goyaml.Marshal(&policy)
+	if err != nil {
+		return err
+	}
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	var np networkingv1.NetworkPolicy
+	if err := decoder.Decode(&np); err != nil {
+		return err
+	}
+	return validateNetworkPolicy(&np).ToAggregate()
+}
+
+// validateNetworkPolicy checks names, selectors, ports and peers of a decoded policy
+func validateNetworkPolicy(np *networkingv1.NetworkPolicy) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(np.Name) {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), np.Name, msg))
+	}
+
+	specPath := field.NewPath("spec")
+	errs = append(errs, metav1validation.ValidateLabelSelector(&np.Spec.PodSelector, metav1validation.LabelSelectorValidationOptions{}, specPath.Child("podSelector"))...)
+	for i, policyType := range np.Spec.PolicyTypes {
+		if policyType != networkingv1.PolicyTypeIngress && policyType != networkingv1.PolicyTypeEgress {
+			errs = append(errs, field.NotSupported(specPath.Child("policyTypes").Index(i), policyType, []string{"Ingress", "Egress"}))
+		}
+	}
+	for i, rule := range np.Spec.Ingress {
+		rulePath := specPath.Child("ingress").Index(i)
+		errs = append(errs, validatePorts(rule.Ports, rulePath.Child("ports"))...)
+		errs = append(errs, validatePeers(rule.From, rulePath.Child("from"))...)
+	}
+	for i, rule := range np.Spec.Egress {
+		rulePath := specPath.Child("egress").Index(i)
+		errs = append(errs, validatePorts(rule.Ports, rulePath.Child("ports"))...)
+		errs = append(errs, validatePeers(rule.To, rulePath.Child("to"))...)
+	}
+	return errs
+}
+
+// validatePorts checks protocols, port numbers or names and endPort ranges
+func validatePorts(ports []networkingv1.NetworkPolicyPort, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, port := range ports {
+		portPath := fldPath.Index(i)
+		if port.Protocol != nil && !contains(supportedProtocols, string(*port.Protocol)) {
+			errs = append(errs, field.NotSupported(portPath.Child("protocol"), *port.Protocol, supportedProtocols))
+		}
+		if port.Port != nil {
+			var msgs []string
+			if port.Port.Type == intstr.Int {
+				msgs = validation.IsValidPortNum(int(port.Port.IntVal))
+			} else {
+				msgs = validation.IsValidPortName(port.Port.StrVal)
+			}
+			for _, msg := range msgs {
+				errs = append(errs, field.Invalid(portPath.Child("port"), port.Port.String(), msg))
+			}
+		}
+		if port.EndPort != nil {
+			if port.Port == nil || port.Port.Type != intstr.Int {
+				errs = append(errs, field.Invalid(portPath.Child("endPort"), *port.EndPort, "may only be set with a numeric port"))
+			} else if *port.EndPort < port.Port.IntVal {
+				errs = append(errs, field.Invalid(portPath.Child("endPort"), *port.EndPort, "must be greater than or equal to port"))
+			}
+		}
+	}
+	return errs
+}
+
+// validatePeers checks that each peer is either an ipBlock or a set of selectors
+func validatePeers(peers []networkingv1.NetworkPolicyPeer, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	selectorOpts := metav1validation.LabelSelectorValidationOptions{}
+	for i, peer := range peers {
+		peerPath := fldPath.Index(i)
+		hasSelector := peer.PodSelector != nil || peer.NamespaceSelector != nil
+		switch {
+		case peer.IPBlock != nil && hasSelector:
+			errs = append(errs, field.Forbidden(peerPath, "may not specify both ipBlock and a selector"))
+		case peer.IPBlock == nil && !hasSelector:
+			errs = append(errs, field.Required(peerPath, "must specify a peer"))
+		}
+		if peer.PodSelector != nil {
+			errs = append(errs, metav1validation.ValidateLabelSelector(peer.PodSelector, selectorOpts, peerPath.Child("podSelector"))...)
+		}
+		if peer.NamespaceSelector != nil {
+			errs = append(errs, metav1validation.ValidateLabelSelector(peer.NamespaceSelector, selectorOpts, peerPath.Child("namespaceSelector"))...)
+		}
+		if peer.IPBlock != nil {
+			errs = append(errs, validateIPBlock(peer.IPBlock, peerPath.Child("ipBlock"))...)
+		}
+	}
+	return errs
+}
+
+// validateIPBlock checks the CIDR and that every exception lies inside it
+func validateIPBlock(block *networkingv1.IPBlock, fldPath *field.Path) field.ErrorList {
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath.Child("cidr"), block.CIDR, err.Error())}
+	}
+	var errs field.ErrorList
+	for i, except := range block.Except {
+		exceptIP, _, err := net.ParseCIDR(except)
+		if err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("except").Index(i), except, err.Error()))
+		} else if !cidr.Contains(exceptIP) {
+			errs = append(errs, field.Invalid(fldPath.Child("except").Index(i), except, "must be a strict subset of cidr"))
+		}
+	}
+	return errs
+}
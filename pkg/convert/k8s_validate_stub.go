@@ -0,0 +1,13 @@
+//go:build !k8svalidate
+
+package convert
+
+import "errors"
+
+// k8sValidation reports whether -k8s-validate is available in this build
+const k8sValidation = false
+
+// validateK8s is only available when built with -tags k8svalidate
+func validateK8s(policy NetworkPolicy) error {
+	return errors.New("built without the k8svalidate tag")
+}
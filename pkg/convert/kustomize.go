@@ -0,0 +1,72 @@
+package convert
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// kustomizationFile is the name kustomize looks for in a directory
+const kustomizationFile = "kustomization.yaml"
+
+// Kustomization is a kustomization.yaml listing the files of a directory
+type Kustomization struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Kind       string   `yaml:"kind"`
+	Namespace  string   `yaml:"namespace,omitempty"`
+	Resources  []string `yaml:"resources"`
+}
+
+// newKustomization returns a kustomization of the resources
+func newKustomization(namespace string, resources []string) Kustomization {
+	return Kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Namespace:  namespace,
+		Resources:  resources,
+	}
+}
+
+// kustomizationFiles returns the kustomization.yaml files for the policy
+// files. The flat layout gets one listing every file. The namespace layout
+// gets one per namespace directory, listing its files and setting its
+// namespace, and one at the top listing the namespace directories.
+func kustomizationFiles(files []PolicyFile, layout string, yamlOpts YAMLOptions) ([]PolicyFile, error) {
+	var kustomizations []Kustomization
+	var paths []string
+	if layout == layoutNamespace {
+		resources := map[string][]string{}
+		for _, file := range files {
+			namespace, rest, _ := strings.Cut(filepath.ToSlash(file.Path), "/")
+			resources[namespace] = append(resources[namespace], rest)
+		}
+		namespaces := make([]string, 0, len(resources))
+		for namespace := range resources {
+			namespaces = append(namespaces, namespace)
+		}
+		sort.Strings(namespaces)
+		for _, namespace := range namespaces {
+			kustomizations = append(kustomizations, newKustomization(namespace, resources[namespace]))
+			paths = append(paths, filepath.Join(namespace, kustomizationFile))
+		}
+		kustomizations = append(kustomizations, newKustomization("", namespaces))
+		paths = append(paths, kustomizationFile)
+	} else {
+		resources := make([]string, len(files))
+		for i, file := range files {
+			resources[i] = filepath.ToSlash(file.Path)
+		}
+		kustomizations = append(kustomizations, newKustomization("", resources))
+		paths = append(paths, kustomizationFile)
+	}
+
+	result := make([]PolicyFile, len(kustomizations))
+	for i, kustomization := range kustomizations {
+		data, err := marshalYAML(kustomization, yamlOpts)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = PolicyFile{Path: paths[i], Data: data}
+	}
+	return result, nil
+}
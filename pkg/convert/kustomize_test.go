@@ -0,0 +1,52 @@
+package convert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestNamespaceLayout(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	policies[0].Metadata.Namespace = "frontend"
+	files, _, err := policyFiles(policies, []string{kindNetworkPolicy}, defaultRender, defaultYAML, layoutNamespace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kustomizations, err := kustomizationFiles(files, layoutNamespace, defaultYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := writePolicyFiles(dir, append(files, kustomizations...)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"frontend/web.yaml", "default/client.yaml", "default/db.yaml"} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("policy file missing: %v", err)
+		}
+	}
+
+	want := map[string]Kustomization{
+		"default/kustomization.yaml":  newKustomization("default", []string{"client.yaml", "db.yaml"}),
+		"frontend/kustomization.yaml": newKustomization("frontend", []string{"web.yaml"}),
+		"kustomization.yaml":          newKustomization("", []string{"default", "frontend"}),
+	}
+	for path, kustomization := range want {
+		data, err := ioutil.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Errorf("kustomization missing: %v", err)
+			continue
+		}
+		var got Kustomization
+		if err := yaml.UnmarshalStrict(data, &got); err != nil {
+			t.Errorf("%s: %v", path, err)
+		} else if !reflect.DeepEqual(got, kustomization) {
+			t.Errorf("%s = %+v, want %+v", path, got, kustomization)
+		}
+	}
+}
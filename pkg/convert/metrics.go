@@ -0,0 +1,86 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ConversionMetrics are the counts written to the -metrics-file
+type ConversionMetrics struct {
+	Services        int
+	ServicesSkipped int
+	// Policies is the number of policies per output kind
+	Policies map[string]int
+	// Warnings is the number of warnings per warning type
+	Warnings map[string]int
+}
+
+// conversionMetrics counts the services, policies and warnings of a run
+func conversionMetrics(diag *diagnostics, coverage []CoverageEntry, policies []NetworkPolicy, kinds []string) ConversionMetrics {
+	metrics := ConversionMetrics{
+		Services: len(coverage),
+		Policies: map[string]int{},
+		Warnings: map[string]int{},
+	}
+	for _, entry := range coverage {
+		if entry.Status != coverageConverted {
+			metrics.ServicesSkipped++
+		}
+	}
+	for _, kind := range kinds {
+		metrics.Policies[kind] = len(policies)
+	}
+	for _, w := range diag.warnings {
+		metrics.Warnings[w.Type]++
+	}
+	return metrics
+}
+
+// writeMetrics writes the metrics in the Prometheus text format. The file is
+// replaced atomically so the node exporter textfile collector never reads a
+// partial file.
+func writeMetrics(path string, metrics ConversionMetrics) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# HELP services_total NSX services in the export.\n# TYPE services_total gauge\nservices_total %d\n", metrics.Services)
+	fmt.Fprintf(&buf, "# HELP services_skipped NSX services that produced no policy rules.\n# TYPE services_skipped gauge\nservices_skipped %d\n", metrics.ServicesSkipped)
+	fmt.Fprintf(&buf, "# HELP policies_generated Policies generated per output kind.\n# TYPE policies_generated gauge\n")
+	for _, kind := range sortedKeys(metrics.Policies) {
+		fmt.Fprintf(&buf, "policies_generated{kind=%q} %d\n", kind, metrics.Policies[kind])
+	}
+	fmt.Fprintf(&buf, "# HELP warnings_total Conversion warnings per warning type.\n# TYPE warnings_total gauge\n")
+	for _, warningType := range sortedKeys(metrics.Warnings) {
+		fmt.Fprintf(&buf, "warnings_total{type=%q} %d\n", warningType, metrics.Warnings[warningType])
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".metrics-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// sortedKeys returns the keys of a count map in order
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
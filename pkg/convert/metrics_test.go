@@ -0,0 +1,59 @@
+package convert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	root, err := parseRoot([]byte(`{"services": [
+		{"display_name": "web", "service_entries": [{"display_name": "http", "l4_protocol": "TCP", "destination_ports": ["80"]}]},
+		{"display_name": "broken", "service_entries": [{"display_name": "bad", "l4_protocol": "TCP", "destination_ports": ["http", "0"]}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diag := newDiagnostics(verbosityQuiet)
+	policies, coverage, err := Pipeline{Options: testOptions()}.convert(root, diag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "netpol.prom")
+	if err := writeMetrics(path, conversionMetrics(diag, coverage, policies, []string{kindNetworkPolicy, kindCilium})); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP services_total NSX services in the export.
+# TYPE services_total gauge
+services_total 2
+# HELP services_skipped NSX services that produced no policy rules.
+# TYPE services_skipped gauge
+services_skipped 1
+# HELP policies_generated Policies generated per output kind.
+# TYPE policies_generated gauge
+policies_generated{kind="cilium"} 2
+policies_generated{kind="networkpolicy"} 2
+# HELP warnings_total Conversion warnings per warning type.
+# TYPE warnings_total gauge
+warnings_total{type="invalid-port"} 2
+`
+	if string(data) != want {
+		t.Errorf("metrics file =\n%s\nwant\n%s", data, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("metrics file mode = %v, want 0644 for the textfile collector", info.Mode().Perm())
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, ".metrics-*")); len(files) != 0 {
+		t.Errorf("temporary files left behind: %v", files)
+	}
+}
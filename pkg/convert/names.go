@@ -0,0 +1,63 @@
+package convert
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// -name-from values
+const (
+	nameFromDisplayName = "display-name"
+	nameFromID          = "id"
+)
+
+// serviceName returns the policy name of a service: its sanitized display
+// name, or its sanitized id with -name-from id
+func serviceName(diag *diagnostics, service Service, opts NameOptions) string {
+	if opts.FromID {
+		if service.ID != "" {
+			return sanitizeName(service.ID, opts)
+		}
+		diag.warn(service.DisplayName, warnMissingID, "service %q has no id to name its policy after, using its display name", service.DisplayName)
+	}
+	return sanitizeName(service.DisplayName, opts)
+}
+
+// ruleName returns the policy name of a rule: its sanitized display name,
+// or with -name-from id its sanitized id, falling back to its numeric
+// rule_id
+func ruleName(diag *diagnostics, rule SecurityRule, opts NameOptions) string {
+	if opts.FromID {
+		switch {
+		case rule.ID != "":
+			return sanitizeName(rule.ID, opts)
+		case rule.RuleID != 0:
+			return sanitizeName(strconv.Itoa(rule.RuleID), opts)
+		}
+		diag.warn(rule.DisplayName, warnMissingID, "rule %q has no id or rule_id to name its policies after, using its display name", rule.DisplayName)
+	}
+	return sanitizeName(rule.DisplayName, opts)
+}
+
+// writeNameMapping writes a two-column table of the NSX display name each
+// policy is generated from and the policy's name, in output order. It
+// returns the policies whose namespace and name are also used by a policy
+// from another display name.
+func writeNameMapping(w io.Writer, policies []NetworkPolicy) ([]string, error) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DISPLAY NAME\tPOLICY NAME")
+	sources := map[string]string{}
+	var collisions []string
+	for _, policy := range policies {
+		key := policyKey(policy)
+		if source, ok := sources[key]; ok && source != policy.source {
+			collisions = append(collisions, fmt.Sprintf("%s is generated from both %q and %q", key, source, policy.source))
+		} else if !ok {
+			sources[key] = policy.source
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", policy.source, policyName(policy))
+	}
+	return collisions, tw.Flush()
+}
@@ -0,0 +1,61 @@
+package convert
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriteNameMapping(t *testing.T) {
+	opts := testOptions()
+	opts.Naming.Transliterate = true
+	policies, _, err := Pipeline{Options: opts}.convert(readExport(t, "name-collisions.json"), newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	collisions, err := writeNameMapping(&out, policies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `DISPLAY NAME                                                                            POLICY NAME
+Web Server                                                                              web-server
+web_server                                                                              web-server
+Café Überwachung                                                                        cafe-uberwachung
+A very long service display name that goes well beyond the sixty three character limit  a-very-long-service-display-name-that-goes-well-beyond-the-sixt
+`
+	if out.String() != want {
+		t.Errorf("name mapping =\n%s\nwant\n%s", out.String(), want)
+	}
+	if want := []string{`default/web-server is generated from both "Web Server" and "web_server"`}; !reflect.DeepEqual(collisions, want) {
+		t.Errorf("collisions = %q, want %q", collisions, want)
+	}
+}
+
+func TestNameFromID(t *testing.T) {
+	opts := testOptions().Naming
+	opts.FromID = true
+	diag := newDiagnostics(verbosityQuiet)
+	names := []string{
+		serviceName(diag, Service{ID: "Web_Server-01", DisplayName: "Web Server"}, opts),
+		serviceName(diag, Service{DisplayName: "No ID"}, opts),
+		ruleName(diag, SecurityRule{ID: "rule.uuid.7", RuleID: 1001, DisplayName: "allow web"}, opts),
+		ruleName(diag, SecurityRule{RuleID: 1002, DisplayName: "allow db"}, opts),
+		ruleName(diag, SecurityRule{DisplayName: "allow dns"}, opts),
+	}
+	if want := []string{"web-server-01", "no-id", "rule-uuid-7", "1002", "allow-dns"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+	want := []Warning{
+		{Service: "No ID", Type: warnMissingID, Message: `service "No ID" has no id to name its policy after, using its display name`},
+		{Service: "allow dns", Type: warnMissingID, Message: `rule "allow dns" has no id or rule_id to name its policies after, using its display name`},
+	}
+	if !reflect.DeepEqual(diag.warnings, want) {
+		t.Errorf("warnings = %+v, want %+v", diag.warnings, want)
+	}
+
+	opts.FromID = false
+	if name := serviceName(diag, Service{ID: "Web_Server-01", DisplayName: "Web Server"}, opts); name != "web-server" {
+		t.Errorf("display-name naming = %q, want web-server", name)
+	}
+}
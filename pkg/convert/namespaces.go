@@ -0,0 +1,86 @@
+package convert
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// NamespaceLookup maps the application IDs of services to the namespaces
+// their policies are generated in
+type NamespaceLookup struct {
+	// Tag is the scope of the service tag holding the application ID. The
+	// service ID is used when it is empty.
+	Tag        string
+	Namespaces map[string]string
+}
+
+// loadNamespaceLookup reads a lookup file from application ID to namespace:
+// a JSON object for .json files, or else CSV rows of ID and namespace with
+// an optional "id,namespace" header
+func loadNamespaceLookup(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := map[string]string{}
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, &namespaces); err != nil {
+			return nil, err
+		}
+	} else {
+		reader := csv.NewReader(strings.NewReader(string(data)))
+		reader.FieldsPerRecord = 2
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range records {
+			id, namespace := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+			if i == 0 && strings.EqualFold(id, "id") && strings.EqualFold(namespace, "namespace") {
+				continue
+			}
+			if existing, ok := namespaces[id]; ok && existing != namespace {
+				return nil, fmt.Errorf("line %d: ID %q is mapped to both %s and %s", i+1, id, existing, namespace)
+			}
+			namespaces[id] = namespace
+		}
+	}
+	for id, namespace := range namespaces {
+		if !namespacePattern.MatchString(namespace) {
+			return nil, fmt.Errorf("ID %q: namespace %q is not a valid namespace name", id, namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+// namespace returns the namespace the lookup maps a service to, falling
+// back to the default namespace with a warning
+func (l NamespaceLookup) namespace(diag *diagnostics, service Service, fallback string) string {
+	id := service.ID
+	if l.Tag != "" {
+		id = ""
+		for _, tag := range service.Tags {
+			if tag.Scope == l.Tag {
+				id = tag.Tag
+				break
+			}
+		}
+		if id == "" {
+			diag.warn(service.DisplayName, warnUnresolvedNamespace, "service %q has no %q tag to look up its namespace, using %s", service.DisplayName, l.Tag, fallback)
+			return fallback
+		}
+	} else if id == "" {
+		diag.warn(service.DisplayName, warnUnresolvedNamespace, "service %q has no ID to look up its namespace, using %s", service.DisplayName, fallback)
+		return fallback
+	}
+	namespace, ok := l.Namespaces[id]
+	if !ok {
+		diag.warn(service.DisplayName, warnUnresolvedNamespace, "service %q has application ID %q, which the namespace lookup does not map, using %s", service.DisplayName, id, fallback)
+		return fallback
+	}
+	return namespace
+}
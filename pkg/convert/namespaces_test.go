@@ -0,0 +1,95 @@
+package convert
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadNamespaceLookup(t *testing.T) {
+	csvLookup, err := loadNamespaceLookup(filepath.Join(repoRoot, "json", "namespace-lookup.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"APP-1001": "billing", "APP-2002": "inventory", "billing-api": "billing", "inventory": "inventory"}
+	if !reflect.DeepEqual(csvLookup, want) {
+		t.Errorf("CSV lookup = %v, want %v", csvLookup, want)
+	}
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"lookup.json":   `{"APP-1001": "billing"}`,
+		"invalid.json":  `{"APP-1001": "Billing_NS"}`,
+		"conflict.csv":  "APP-1001,billing\nAPP-1001,inventory\n",
+		"columns.csv":   "APP-1001,billing,extra\n",
+		"no-header.csv": "APP-1001, billing\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"lookup.json", "no-header.csv"} {
+		lookup, err := loadNamespaceLookup(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !reflect.DeepEqual(lookup, map[string]string{"APP-1001": "billing"}) {
+			t.Errorf("%s: lookup = %v", name, lookup)
+		}
+	}
+	for _, name := range []string{"invalid.json", "conflict.csv", "columns.csv"} {
+		if _, err := loadNamespaceLookup(filepath.Join(dir, name)); err == nil {
+			t.Errorf("%s: loadNamespaceLookup accepted it", name)
+		}
+	}
+}
+
+func TestNamespaceLookup(t *testing.T) {
+	namespaces, err := loadNamespaceLookup(filepath.Join(repoRoot, "json", "namespace-lookup.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		tag      string
+		want     map[string]string
+		warnings []string
+	}{
+		{"app-id",
+			map[string]string{"billing-api": "billing", "inventory": "inventory", "legacy-reports": "default", "untagged": "default"},
+			[]string{
+				`service "Legacy Reports" has application ID "APP-9999", which the namespace lookup does not map, using default`,
+				`service "Untagged" has no "app-id" tag to look up its namespace, using default`,
+			}},
+		{"",
+			map[string]string{"billing-api": "billing", "inventory": "inventory", "legacy-reports": "default", "untagged": "default"},
+			[]string{
+				`service "Legacy Reports" has application ID "legacy-reports", which the namespace lookup does not map, using default`,
+				`service "Untagged" has application ID "untagged", which the namespace lookup does not map, using default`,
+			}},
+	}
+	for _, tt := range tests {
+		opts := testOptions()
+		opts.NamespaceLookup = &NamespaceLookup{Tag: tt.tag, Namespaces: namespaces}
+		diag := newDiagnostics(verbosityQuiet)
+		policies, _, err := Pipeline{Options: opts}.convert(readExport(t, "namespace-lookup.json"), diag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]string{}
+		for _, policy := range policies {
+			got[policy.Metadata.Name] = policy.Metadata.Namespace
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tag %q: namespaces = %v, want %v", tt.tag, got, tt.want)
+		}
+		var warnings []string
+		for _, w := range diag.warnings {
+			if w.Type == warnUnresolvedNamespace {
+				warnings = append(warnings, w.Message)
+			}
+		}
+		if !reflect.DeepEqual(warnings, tt.warnings) {
+			t.Errorf("tag %q: warnings = %q, want %q", tt.tag, warnings, tt.warnings)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Output kinds selectable with -output-kind
+const (
+	kindNetworkPolicy = "networkpolicy"
+	kindCilium        = "cilium"
+	kindConfigMap     = "configmap"
+)
+
+// File layouts selectable with -layout
+const (
+	layoutFlat      = "flat"
+	layoutNamespace = "namespace"
+)
+
+// parseOutputKinds parses a comma-separated list of output kinds
+func parseOutputKinds(value string) ([]string, error) {
+	var kinds []string
+	seen := map[string]bool{}
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.TrimSpace(kind)
+		switch kind {
+		case kindNetworkPolicy, kindCilium, kindConfigMap:
+		default:
+			return nil, fmt.Errorf("unknown output kind %q, expected %s, %s or %s", kind, kindNetworkPolicy, kindCilium, kindConfigMap)
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, nil
+}
+
+// RenderOptions controls the objects emitted for the policies in the output
+// kinds
+type RenderOptions struct {
+	ArgoCD    ArgoCDOptions
+	ConfigMap ConfigMapOptions
+}
+
+// defaultRender emits the output kinds with the flag defaults
+var defaultRender = RenderOptions{ConfigMap: ConfigMapOptions{Prefix: defaultConfigMapPrefix}}
+
+// renderPolicy returns the object emitted for a policy in an output kind
+func renderPolicy(policy NetworkPolicy, kind string, render RenderOptions) interface{} {
+	switch kind {
+	case kindCilium:
+		cnp := ciliumPolicy(policy)
+		cnp.Metadata = trackMetadata(cnp.Metadata, kind, render.ArgoCD)
+		return &cnp
+	case kindConfigMap:
+		return policyConfigMap{policy, render}
+	}
+	policy.Metadata = trackMetadata(policy.Metadata, kind, render.ArgoCD)
+	return &policy
+}
+
+// resourceKind returns the Kubernetes kind emitted for an output kind
+func resourceKind(kind string) string {
+	switch kind {
+	case kindCilium:
+		return "CiliumNetworkPolicy"
+	case kindConfigMap:
+		return "ConfigMap"
+	}
+	return "NetworkPolicy"
+}
+
+// resourceAPIVersion returns the Kubernetes API version of an output kind
+func resourceAPIVersion(kind string) string {
+	switch kind {
+	case kindCilium:
+		return ciliumAPIVersion
+	case kindConfigMap:
+		return "v1"
+	}
+	return "networking.k8s.io/v1"
+}
+
+// resourceGroup returns the API group of an output kind, empty for the
+// core group
+func resourceGroup(kind string) string {
+	version := resourceAPIVersion(kind)
+	if i := strings.LastIndex(version, "/"); i >= 0 {
+		return version[:i]
+	}
+	return ""
+}
+
+// resourceName returns the name of the object emitted for a policy in an
+// output kind. ConfigMaps have the -configmap-prefix in front.
+func resourceName(policy NetworkPolicy, kind string, render RenderOptions) string {
+	if kind == kindConfigMap {
+		return render.ConfigMap.Prefix + policyName(policy)
+	}
+	return policyName(policy)
+}
+
+// useGenerateName makes the API server name every policy, using its name
+// followed by a dash as the prefix
+func useGenerateName(policies []NetworkPolicy) {
+	for i := range policies {
+		policies[i].Metadata.GenerateName = policies[i].Metadata.Name + "-"
+		policies[i].Metadata.Name = ""
+	}
+}
+
+// policyName returns the name of a policy, or its generateName prefix
+// without the trailing dash
+func policyName(policy NetworkPolicy) string {
+	if policy.Metadata.Name == "" {
+		return strings.TrimSuffix(policy.Metadata.GenerateName, "-")
+	}
+	return policy.Metadata.Name
+}
+
+// IndexEntry describes one generated policy in the -index manifest
+type IndexEntry struct {
+	Kind      string   `yaml:"kind" json:"kind"`
+	Name      string   `yaml:"name" json:"name"`
+	Namespace string   `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Source    string   `yaml:"source" json:"source"`
+	File      string   `yaml:"file" json:"file"`
+	Ingress   []string `yaml:"ingress,omitempty" json:"ingress,omitempty"`
+	Egress    []string `yaml:"egress,omitempty" json:"egress,omitempty"`
+}
+
+// PolicyFile is the YAML of one policy and the relative path it is written to
+type PolicyFile struct {
+	Path string
+	Data []byte
+}
+
+// policyFiles renders each policy to <name>.yaml and returns the files with
+// the index entries describing them. With several output kinds, each kind
+// goes to its own <kind> subdirectory. The namespace layout puts the files
+// of each namespace under a <namespace> directory.
+func policyFiles(policies []NetworkPolicy, kinds []string, render RenderOptions, yamlOpts YAMLOptions, layout string) ([]PolicyFile, []IndexEntry, error) {
+	var files []PolicyFile
+	var entries []IndexEntry
+	for _, kind := range kinds {
+		subdir := ""
+		if len(kinds) > 1 {
+			subdir = kind
+		}
+
+		written := map[string]string{}
+		for _, policy := range policies {
+			file := filepath.Join(subdir, policyName(policy)+".yaml")
+			if layout == layoutNamespace {
+				file = filepath.Join(policy.Metadata.Namespace, file)
+			}
+			if source, ok := written[file]; ok {
+				return nil, nil, fmt.Errorf("policies from %q and %q would both be written to %s", source, policy.source, file)
+			}
+			written[file] = policy.source
+
+			yamlData, err := marshalYAML(renderPolicy(policy, kind, render), yamlOpts)
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append(files, PolicyFile{Path: file, Data: yamlData})
+			entry := indexEntry(policy, file)
+			entry.Kind = resourceKind(kind)
+			entry.Name = resourceName(policy, kind, render)
+			entries = append(entries, entry)
+		}
+	}
+	return files, entries, nil
+}
+
+// writePolicyFiles writes the policy files under dir, creating the
+// namespace and kind subdirectories as needed
+func writePolicyFiles(dir string, files []PolicyFile) error {
+	for _, file := range files {
+		path := filepath.Join(dir, file.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, file.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexEntry summarizes a policy and the ports it allows per direction
+func indexEntry(policy NetworkPolicy, file string) IndexEntry {
+	entry := IndexEntry{
+		Name:      policyName(policy),
+		Namespace: policy.Metadata.Namespace,
+		Source:    policy.source,
+		File:      file,
+	}
+	for _, rule := range policy.Spec.Ingress {
+		for _, port := range rule.Ports {
+			entry.Ingress = append(entry.Ingress, portSummary(port))
+		}
+	}
+	for _, rule := range policy.Spec.Egress {
+		for _, port := range rule.Ports {
+			entry.Egress = append(entry.Egress, portSummary(port))
+		}
+	}
+	return entry
+}
+
+// portSummary formats a port as PROTOCOL/port or PROTOCOL/start-end, using *
+// for all ports
+func portSummary(port PortRule) string {
+	switch {
+	case port.Port == nil:
+		return port.Protocol + "/*"
+	case port.EndPort != nil:
+		return fmt.Sprintf("%s/%s-%d", port.Protocol, port.Port, *port.EndPort)
+	}
+	return port.Protocol + "/" + port.Port.String()
+}
+
+// writeIndex writes the index entries as JSON when the file ends in .json
+// and as YAML otherwise
+func writeIndex(path string, entries []IndexEntry) error {
+	return writeReport(path, entries)
+}
+
+// writeReport writes v as JSON when the file ends in .json and as YAML otherwise
+func writeReport(path string, v interface{}) error {
+	data, err := marshalReport(path, v)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// marshalReport encodes v as JSON when the file ends in .json and as YAML
+// otherwise
+func marshalReport(path string, v interface{}) ([]byte, error) {
+	if strings.HasSuffix(path, ".json") {
+		data, err := json.MarshalIndent(v, "", "  ")
+		return append(data, '\n'), err
+	}
+	return yaml.Marshal(v)
+}
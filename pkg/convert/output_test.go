@@ -0,0 +1,135 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIndexMatchesPolicyFiles(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	files, entries, err := policyFiles(policies, []string{kindNetworkPolicy}, defaultRender, defaultYAML, layoutFlat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "index.json")
+	if err := writeIndex(path, entries); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index []IndexEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []IndexEntry{
+		{Kind: "NetworkPolicy", Name: "web", Namespace: "default", Source: "web", File: "web.yaml", Ingress: []string{"TCP/80"}},
+		{Kind: "NetworkPolicy", Name: "client", Namespace: "default", Source: "client", File: "client.yaml", Egress: []string{"TCP/1024-65535"}},
+		{Kind: "NetworkPolicy", Name: "db", Namespace: "default", Source: "db", File: "db.yaml", Ingress: []string{"TCP/5432"}, Egress: []string{"TCP/5432"}},
+	}
+	if !reflect.DeepEqual(index, want) {
+		t.Errorf("index = %+v, want %+v", index, want)
+	}
+	for i, file := range files {
+		if file.Path != index[i].File {
+			t.Errorf("file %d is %s, but the index lists %s", i, file.Path, index[i].File)
+		}
+	}
+}
+
+func TestParseOutputKinds(t *testing.T) {
+	kinds, err := parseOutputKinds("networkpolicy, cilium,networkpolicy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{kindNetworkPolicy, kindCilium}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("parseOutputKinds = %v, want %v", kinds, want)
+	}
+	if _, err := parseOutputKinds("networkpolicy,calico"); err == nil {
+		t.Error("parseOutputKinds accepted an unknown kind")
+	}
+}
+
+func TestPolicyFilesOfTwoKinds(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	files, entries, err := policyFiles(policies[:1], []string{kindNetworkPolicy, kindCilium}, defaultRender, defaultYAML, layoutFlat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths, kinds []string
+	for i, file := range files {
+		paths = append(paths, file.Path)
+		kinds = append(kinds, entries[i].Kind)
+		if !strings.Contains(string(file.Data), "\nkind: "+entries[i].Kind+"\n") {
+			t.Errorf("%s is not a %s:\n%s", file.Path, entries[i].Kind, file.Data)
+		}
+	}
+	if want := []string{"networkpolicy/web.yaml", "cilium/web.yaml"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("files = %v, want %v", paths, want)
+	}
+	if want := []string{"NetworkPolicy", "CiliumNetworkPolicy"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds = %v, want %v", kinds, want)
+	}
+}
+
+func TestUseGenerateName(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	useGenerateName(policies)
+	data, err := marshalYAML(renderPolicy(policies[0], kindNetworkPolicy, defaultRender), defaultYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "metadata:\n  generateName: web-\n  namespace: default\nspec:\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("policy has no metadata %q:\n%s", want, data)
+	}
+	if strings.Contains(string(data), "  name:") {
+		t.Errorf("policy with generateName still has a name:\n%s", data)
+	}
+	if name := policyName(policies[0]); name != "web" {
+		t.Errorf("policyName = %q, want web for files and the index", name)
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	binary := buildBinary(t)
+	full := runBinary(t, binary, "-f", "json/Example1.json", "-v", "0")
+	run := func(limit int) (string, string, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(binary, "-f", "json/Example1.json", "-v", "0", "-max-output-bytes", strconv.Itoa(limit))
+		cmd.Dir = repoRoot
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+
+	out, logged, err := run(len(full))
+	if err != nil {
+		t.Fatalf("run within the budget failed: %v\n%s", err, logged)
+	}
+	if out != string(full) {
+		t.Error("output within the budget differs from the unlimited output")
+	}
+
+	out, logged, err = run(4096)
+	if err == nil {
+		t.Fatal("run over the budget succeeded")
+	}
+	if out != "" {
+		t.Errorf("run over the budget wrote %d bytes to stdout", len(out))
+	}
+	want := fmt.Sprintf("the output is %d bytes, exceeding -max-output-bytes 4096; use -out-dir or -tar", len(full))
+	if !strings.Contains(logged, want) {
+		t.Errorf("error does not report %q:\n%s", want, logged)
+	}
+}
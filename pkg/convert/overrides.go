@@ -0,0 +1,132 @@
+package convert
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// ServiceOverride corrects the policy generated for one service. Unset
+// fields keep the generated values.
+type ServiceOverride struct {
+	Namespace   string       `yaml:"namespace"`
+	PodSelector *PodSelector `yaml:"podSelector"`
+	// From and To are added as peers to every ingress or egress rule
+	From []NetworkPolicyPeer `yaml:"from"`
+	To   []NetworkPolicyPeer `yaml:"to"`
+	// Direction is IN, OUT or IN_OUT, as for NSX rules
+	Direction string `yaml:"direction"`
+}
+
+// loadOverrides reads a YAML map from service display name to its override
+func loadOverrides(path string) (map[string]ServiceOverride, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var overrides map[string]ServiceOverride
+	if err := yaml.UnmarshalStrict(data, &overrides); err != nil {
+		return nil, err
+	}
+	for service, override := range overrides {
+		if err := override.validate(); err != nil {
+			return nil, fmt.Errorf("service %q: %v", service, err)
+		}
+	}
+	return overrides, nil
+}
+
+// validate checks the namespace, labels, CIDRs and direction of an override
+func (o ServiceOverride) validate() error {
+	if o.Namespace != "" && !namespacePattern.MatchString(o.Namespace) {
+		return fmt.Errorf("namespace %q is not a valid DNS-1123 label", o.Namespace)
+	}
+	if o.PodSelector != nil {
+		for key, value := range o.PodSelector.MatchLabels {
+			if err := validateLabel(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	for _, peer := range append(append([]NetworkPolicyPeer{}, o.From...), o.To...) {
+		if peer.IPBlock != nil {
+			if _, _, err := net.ParseCIDR(peer.IPBlock.CIDR); err != nil {
+				return fmt.Errorf("invalid ipBlock CIDR %q", peer.IPBlock.CIDR)
+			}
+			continue
+		}
+		if peer.PodSelector == nil && peer.NamespaceSelector == nil {
+			return fmt.Errorf("peer needs a podSelector, namespaceSelector or ipBlock")
+		}
+	}
+	switch o.Direction {
+	case "", "IN", "OUT", "IN_OUT":
+		return nil
+	}
+	return fmt.Errorf("direction %q must be IN, OUT or IN_OUT", o.Direction)
+}
+
+// applyOverride replaces the generated settings of a service policy with
+// the overridden ones. A direction moves the destination port rules: IN
+// keeps them as ingress and drops egress, OUT turns them into egress, and
+// IN_OUT uses them for both.
+func applyOverride(policy *NetworkPolicy, override ServiceOverride) {
+	if override.Namespace != "" {
+		policy.Metadata.Namespace = override.Namespace
+	}
+	if override.PodSelector != nil {
+		policy.allPods = len(override.PodSelector.MatchLabels) == 0 && len(override.PodSelector.MatchExpressions) == 0
+		policy.Spec.PodSelector = PodSelector{
+			MatchLabels:      copyLabels(override.PodSelector.MatchLabels),
+			MatchExpressions: append([]LabelSelectorRequirement(nil), override.PodSelector.MatchExpressions...),
+		}
+	}
+
+	switch override.Direction {
+	case "IN":
+		policy.Spec.Egress = nil
+	case "OUT":
+		policy.Spec.Egress = append(policy.Spec.Egress, copyRules(policy.Spec.Ingress)...)
+		policy.Spec.Ingress = nil
+	case "IN_OUT":
+		policy.Spec.Egress = append(policy.Spec.Egress, copyRules(policy.Spec.Ingress)...)
+	}
+	if override.Direction != "" {
+		policy.Spec.PolicyTypes = nil
+		if len(policy.Spec.Ingress) > 0 {
+			policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, "Ingress")
+		}
+		if len(policy.Spec.Egress) > 0 {
+			policy.Spec.PolicyTypes = append(policy.Spec.PolicyTypes, "Egress")
+		}
+	}
+
+	for i := range policy.Spec.Ingress {
+		policy.Spec.Ingress[i].From = append(append([]NetworkPolicyPeer(nil), policy.Spec.Ingress[i].From...), override.From...)
+	}
+	for i := range policy.Spec.Egress {
+		policy.Spec.Egress[i].To = append(append([]NetworkPolicyPeer(nil), policy.Spec.Egress[i].To...), override.To...)
+	}
+}
+
+// copyRules copies rules with their peers, ports and HTTP rules, so a rule
+// moved or duplicated into the other direction shares nothing with the
+// rules it came from
+func copyRules(rules []Rule) []Rule {
+	copied := make([]Rule, len(rules))
+	for i, rule := range rules {
+		rule.From = append([]NetworkPolicyPeer(nil), rule.From...)
+		rule.To = append([]NetworkPolicyPeer(nil), rule.To...)
+		rule.Ports = append([]PortRule(nil), rule.Ports...)
+		if rule.http != nil {
+			rule.http = append([]CiliumHTTPRule{}, rule.http...)
+		}
+		copied[i] = rule
+	}
+	return copied
+}
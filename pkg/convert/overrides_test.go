@@ -0,0 +1,102 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadOverrides(t *testing.T) {
+	overrides, err := loadOverrides(repoRoot + "/json/overrides.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql := overrides["Microsoft SQL Server"]; sql.Namespace != "databases" || sql.PodSelector.MatchLabels["app"] != "mssql" || len(sql.From) != 1 {
+		t.Errorf("Microsoft SQL Server override = %+v", sql)
+	}
+	if dns := overrides["DNS-UDP"]; dns.Direction != "OUT" || len(dns.To) != 1 || dns.To[0].IPBlock.CIDR != "10.0.0.53/32" {
+		t.Errorf("DNS-UDP override = %+v", dns)
+	}
+}
+
+func TestValidateOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		override ServiceOverride
+	}{
+		{"namespace", ServiceOverride{Namespace: "Not_Valid"}},
+		{"label", ServiceOverride{PodSelector: &PodSelector{MatchLabels: map[string]string{"app": "bad value"}}}},
+		{"cidr", ServiceOverride{To: []NetworkPolicyPeer{{IPBlock: &IPBlock{CIDR: "10.0.0.1"}}}}},
+		{"empty peer", ServiceOverride{From: []NetworkPolicyPeer{{}}}},
+		{"direction", ServiceOverride{Direction: "BOTH"}},
+	}
+	for _, tt := range tests {
+		if err := tt.override.validate(); err == nil {
+			t.Errorf("%s: validate accepted %+v", tt.name, tt.override)
+		}
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	opts := testOptions()
+	peer := NetworkPolicyPeer{NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{"team": "web"}}}
+	opts.Overrides = map[string]ServiceOverride{
+		"web": {
+			Namespace:   "frontend",
+			PodSelector: &PodSelector{MatchLabels: map[string]string{"app": "nginx"}},
+			From:        []NetworkPolicyPeer{peer},
+			To:          []NetworkPolicyPeer{peer},
+			Direction:   "IN_OUT",
+		},
+		"db":      {Direction: "IN"},
+		"client":  {PodSelector: &PodSelector{}},
+		"missing": {Namespace: "other"},
+	}
+	policies, diag := convertExport(t, directionsExport, Pipeline{Options: opts})
+
+	web := findPolicy(t, policies, "web")
+	if web.Metadata.Namespace != "frontend" {
+		t.Errorf("web namespace = %q, want frontend", web.Metadata.Namespace)
+	}
+	if want := map[string]string{"app": "nginx"}; !reflect.DeepEqual(web.Spec.PodSelector.MatchLabels, want) {
+		t.Errorf("web podSelector = %+v, want %v", web.Spec.PodSelector, want)
+	}
+	if want := []string{"Ingress", "Egress"}; !reflect.DeepEqual(web.Spec.PolicyTypes, want) {
+		t.Errorf("web policyTypes = %v, want %v", web.Spec.PolicyTypes, want)
+	}
+	if len(web.Spec.Ingress) != 1 || len(web.Spec.Egress) != 1 {
+		t.Fatalf("web has %d ingress and %d egress rules, want one of each", len(web.Spec.Ingress), len(web.Spec.Egress))
+	}
+	if from := web.Spec.Ingress[0].From; len(from) == 0 || !reflect.DeepEqual(from[len(from)-1], peer) {
+		t.Errorf("web ingress peers = %+v, want the override peer last", from)
+	}
+	if to := web.Spec.Egress[0].To; len(to) == 0 || !reflect.DeepEqual(to[len(to)-1], peer) {
+		t.Errorf("web egress peers = %+v, want the override peer last", to)
+	}
+	if !reflect.DeepEqual(web.Spec.Egress[0].Ports, web.Spec.Ingress[0].Ports) {
+		t.Errorf("web egress ports = %+v, want the ingress ports %+v", web.Spec.Egress[0].Ports, web.Spec.Ingress[0].Ports)
+	}
+	web.Spec.Egress[0].Ports[0].Protocol = "SCTP"
+	if web.Spec.Ingress[0].Ports[0].Protocol == "SCTP" {
+		t.Error("web egress rule shares its ports with the ingress rule it was copied from")
+	}
+	if web.allPods {
+		t.Error("web podSelector with matchLabels is marked as selecting all pods")
+	}
+
+	db := findPolicy(t, policies, "db")
+	if len(db.Spec.Egress) != 0 || !reflect.DeepEqual(db.Spec.PolicyTypes, []string{"Ingress"}) {
+		t.Errorf("db with direction IN kept egress: %+v", db.Spec)
+	}
+	client := findPolicy(t, policies, "client")
+	if client.Metadata.Namespace != "default" {
+		t.Errorf("client without a namespace override moved to namespace %q", client.Metadata.Namespace)
+	}
+	if !client.allPods {
+		t.Error("client with an empty podSelector override is not marked as selecting all pods")
+	}
+
+	want := Warning{Service: "missing", Type: warnUnknownService, Message: `-overrides lists service "missing" which is not in the export`}
+	if !reflect.DeepEqual(diag.warnings, []Warning{want}) {
+		t.Errorf("warnings = %+v, want %+v", diag.warnings, want)
+	}
+}
@@ -0,0 +1,41 @@
+package convert
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// OwnerReference points a generated policy at the object that owns it
+type OwnerReference struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+	UID        string `yaml:"uid"`
+}
+
+// uidPattern matches the RFC 4122 form Kubernetes uses for object UIDs
+var uidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// parseOwner validates the owner flags. It returns nil when none are set.
+func parseOwner(apiVersion, kind, name, uid string) (*OwnerReference, error) {
+	if apiVersion == "" && kind == "" && name == "" && uid == "" {
+		return nil, nil
+	}
+	if apiVersion == "" || kind == "" || name == "" || uid == "" {
+		return nil, fmt.Errorf("-owner-api-version, -owner-kind, -owner-name and -owner-uid must be set together")
+	}
+	if !uidPattern.MatchString(uid) {
+		return nil, fmt.Errorf("owner uid %q is not a UUID", uid)
+	}
+	return &OwnerReference{APIVersion: apiVersion, Kind: kind, Name: name, UID: uid}, nil
+}
+
+// applyOwner adds the owner reference to every policy
+func applyOwner(policies []NetworkPolicy, owner *OwnerReference) {
+	if owner == nil {
+		return
+	}
+	for i := range policies {
+		policies[i].Metadata.OwnerReferences = append(policies[i].Metadata.OwnerReferences, *owner)
+	}
+}
@@ -0,0 +1,43 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const testOwnerUID = "6f1c2a3e-8d4b-4f5a-9c7e-1b2d3e4f5a6b"
+
+func TestParseOwner(t *testing.T) {
+	if owner, err := parseOwner("", "", "", ""); owner != nil || err != nil {
+		t.Errorf("parseOwner without flags = %v, %v, want nil, nil", owner, err)
+	}
+	if _, err := parseOwner("apps/v1", "Deployment", "netpol-operator", ""); err == nil {
+		t.Error("parseOwner accepted an owner without a uid")
+	}
+	if _, err := parseOwner("apps/v1", "Deployment", "netpol-operator", "not-a-uid"); err == nil {
+		t.Error("parseOwner accepted an invalid uid")
+	}
+}
+
+func TestOwnerReferences(t *testing.T) {
+	owner, err := parseOwner("apps/v1", "Deployment", "netpol-operator", testOwnerUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	applyOwner(policies, owner)
+	data, err := yaml.Marshal(&policies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: netpol-operator
+    uid: ` + testOwnerUID + "\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("policy has no ownerReferences block %q:\n%s", want, data)
+	}
+}
@@ -0,0 +1,145 @@
+package convert
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// peerGroupsAnnotation names the NSX groups the peers of a rule policy are
+// built from
+const peerGroupsAnnotation = "nsx.vmware.com/peer-groups"
+
+// peerBuilder resolves the group references of rules into endpoints. The
+// endpoints of a group are built on first use and shared by every rule that
+// references it, so a large IP set is only parsed and split into CIDRs
+// once. The later steps copy peers before changing them, which makes the
+// sharing safe.
+type peerBuilder struct {
+	groups map[string]Group
+	opts   Options
+	diag   *diagnostics
+	built  map[string]ruleEndpoints
+}
+
+// newPeerBuilder returns a builder for the groups of a domain, keyed by
+// display name, ID and path
+func newPeerBuilder(diag *diagnostics, groups map[string]Group, opts Options) *peerBuilder {
+	return &peerBuilder{groups: groups, opts: opts, diag: diag, built: map[string]ruleEndpoints{}}
+}
+
+// resolve splits a rule's group references into workload groups, which
+// become pod selectors, and IP-set groups, which become ipBlock peers
+func (b *peerBuilder) resolve(rule SecurityRule, refs []string) ruleEndpoints {
+	var endpoints ruleEndpoints
+	for _, ref := range refs {
+		if ref == "ANY" {
+			endpoints.any = true
+			continue
+		}
+		group, found := b.groups[ref]
+		if !found {
+			group, found = b.groups[lastPathElement(ref)]
+		}
+		if !found {
+			b.diag.warn(rule.DisplayName, warnUnknownGroup, "rule %q references unknown group %q", rule.DisplayName, ref)
+			continue
+		}
+		if _, mapped := mappedGroup(group, b.opts.GroupLabels); !mapped && group.segment && len(group.IPAddresses) == 0 {
+			b.diag.warn(rule.DisplayName, warnNoCIDR, "rule %q references segment %q which has no subnet CIDR", rule.DisplayName, group.DisplayName)
+			continue
+		}
+		built := b.group(group)
+		if built.empty() {
+			continue
+		}
+		endpoints.add(built)
+		if !contains(endpoints.groups, group.DisplayName) {
+			endpoints.groups = append(endpoints.groups, group.DisplayName)
+		}
+	}
+	return endpoints
+}
+
+// group returns the endpoints of one group, building them on first use
+func (b *peerBuilder) group(group Group) ruleEndpoints {
+	key := stringOr(group.Path, group.DisplayName)
+	if endpoints, ok := b.built[key]; ok {
+		return endpoints
+	}
+	endpoints := groupEndpoints(b.diag, group, b.opts)
+	b.built[key] = endpoints
+	return endpoints
+}
+
+// groupEndpoints builds the endpoints of a group: its -group-labels
+// mapping, the ipBlocks of its addresses, the selectors of its conditions,
+// or else its name as a workload
+func groupEndpoints(diag *diagnostics, group Group, opts Options) ruleEndpoints {
+	if labels, ok := mappedGroup(group, opts.GroupLabels); ok {
+		return ruleEndpoints{mapped: []GroupLabels{labels}}
+	}
+	if addresses := group.ipAddresses(); len(addresses) > 0 {
+		return ruleEndpoints{ipBlocks: exceptPeers(diag, group.DisplayName, ipBlockPeers(diag, group.DisplayName, addresses, opts.IPFamily), group.ExcludedAddresses, opts.IPFamily)}
+	}
+	if selectors, ok := groupSelectors(diag, group); ok {
+		return ruleEndpoints{selectors: selectors}
+	}
+	return ruleEndpoints{workloads: []string{sanitizeName(group.DisplayName, opts.Naming)}}
+}
+
+// annotatePeerGroups records the groups the peers of a rule policy come
+// from, which NetworkPolicies cannot reference by name
+func annotatePeerGroups(policy *NetworkPolicy, endpoints ruleEndpoints) {
+	if len(endpoints.groups) == 0 {
+		return
+	}
+	if policy.Metadata.Annotations == nil {
+		policy.Metadata.Annotations = map[string]string{}
+	}
+	policy.Metadata.Annotations[peerGroupsAnnotation] = strings.Join(endpoints.groups, ", ")
+}
+
+// peerlessRules returns the rules of a policy that have no peers and so
+// allow traffic from or to anywhere, as ingress[i] or egress[i]
+func peerlessRules(policy NetworkPolicy) []string {
+	var rules []string
+	for i, rule := range policy.Spec.Ingress {
+		if len(rule.From) == 0 {
+			rules = append(rules, fmt.Sprintf("ingress[%d]", i))
+		}
+	}
+	for i, rule := range policy.Spec.Egress {
+		if len(rule.To) == 0 {
+			rules = append(rules, fmt.Sprintf("egress[%d]", i))
+		}
+	}
+	return rules
+}
+
+// checkPeers reports the policies with peerless rules. When peers are
+// required, it warns about each of them and returns an error, and otherwise
+// logs a summary.
+func checkPeers(diag *diagnostics, policies []NetworkPolicy, require bool) error {
+	open := 0
+	for _, policy := range policies {
+		rules := peerlessRules(policy)
+		if len(rules) == 0 {
+			continue
+		}
+		open++
+		if require {
+			diag.warn(policy.source, warnNoPeers, "policy %s from %q has rules without peers: %s", policy.Metadata.Name, policy.source, strings.Join(rules, ", "))
+		}
+	}
+	if open == 0 {
+		return nil
+	}
+	if require {
+		return fmt.Errorf("%d of %d NetworkPolicies have rules without peers, which are open to any source or destination", open, len(policies))
+	}
+	if diag.verbosity >= verbosityWarnings {
+		log.Printf("Note: %d of %d NetworkPolicies have rules without peers, which are open to any source or destination (use -require-peers to reject them)", open, len(policies))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package convert
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPeerlessRules(t *testing.T) {
+	policy := newPolicy("p", "default")
+	peers := []NetworkPolicyPeer{{IPBlock: &IPBlock{CIDR: "10.0.0.0/8"}}}
+	policy.Spec.Ingress = []Rule{{From: peers}, {}}
+	policy.Spec.Egress = []Rule{{}, {To: peers}}
+	if got, want := peerlessRules(policy), []string{"ingress[1]", "egress[0]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("peerlessRules = %v, want %v", got, want)
+	}
+}
+
+func TestRequirePeers(t *testing.T) {
+	root := readExport(t, "ipset-group.json")
+	diag := newDiagnostics(verbosityQuiet)
+	policies := rulePolicies(diag, root, testOptions())
+	if len(policies) != 2 {
+		t.Fatalf("got %d rule policies, want one per rule", len(policies))
+	}
+	if err := checkPeers(diag, policies, true); err != nil {
+		t.Errorf("rules with peers were rejected: %v", err)
+	}
+
+	err := checkPeers(diag, servicePolicies(diag, root, testOptions()), true)
+	if err == nil || !strings.Contains(err.Error(), "2 of 2 NetworkPolicies have rules without peers") {
+		t.Errorf("peerless service policies got error %v", err)
+	}
+}
+
+func TestSharedPeerGroups(t *testing.T) {
+	opts := testOptions()
+	opts.PeerGroups = true
+	root := readExport(t, "shared-peers.json")
+	policies, _, err := Pipeline{Options: opts, Rules: true}.convert(root, newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies = policies[len(root.Services):]
+
+	annotations := map[string]string{}
+	var corpPeers [][]NetworkPolicyPeer
+	for _, policy := range policies {
+		annotations[policy.Metadata.Name] = policy.Metadata.Annotations[peerGroupsAnnotation]
+		var ipBlocks []NetworkPolicyPeer
+		for _, peer := range policy.Spec.Ingress[0].From {
+			if peer.IPBlock != nil {
+				ipBlocks = append(ipBlocks, peer)
+			}
+		}
+		corpPeers = append(corpPeers, ipBlocks)
+	}
+	want := map[string]string{"corp-to-web": "corp-networks", "corp-to-api": "corp-networks", "admins-to-bastion": "corp-networks, admins"}
+	if !reflect.DeepEqual(annotations, want) {
+		t.Errorf("peer-groups annotations = %v, want %v", annotations, want)
+	}
+	for i := 1; i < len(corpPeers); i++ {
+		if len(corpPeers[i]) == 0 || !reflect.DeepEqual(corpPeers[i], corpPeers[0]) {
+			t.Errorf("policy %s has corp-networks peers %+v, want %+v", policies[i].Metadata.Name, corpPeers[i], corpPeers[0])
+		}
+	}
+
+	opts.PeerGroups = false
+	policies, _, err = Pipeline{Options: opts, Rules: true}.convert(root, newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, policy := range policies {
+		if _, ok := policy.Metadata.Annotations[peerGroupsAnnotation]; ok {
+			t.Errorf("policy %s is annotated without -annotate-peer-groups", policy.Metadata.Name)
+		}
+	}
+}
+
+func TestPeerBuilderBuildsGroupsOnce(t *testing.T) {
+	root := readExport(t, "shared-peers.json")
+	groups := map[string]Group{}
+	for _, group := range root.Domains[0].Resources.Groups {
+		groups[group.Path] = group
+	}
+	b := newPeerBuilder(newDiagnostics(verbosityQuiet), groups, testOptions())
+	corp := "/infra/domains/default/groups/corp-networks"
+	first := b.resolve(SecurityRule{DisplayName: "a"}, []string{corp})
+	second := b.resolve(SecurityRule{DisplayName: "b"}, []string{corp, "/infra/domains/default/groups/admins"})
+	if len(b.built) != 2 {
+		t.Errorf("built %d groups, want corp-networks and admins once each", len(b.built))
+	}
+	if len(first.ipBlocks) == 0 || first.ipBlocks[0].IPBlock != second.ipBlocks[0].IPBlock {
+		t.Error("the corp-networks ipBlocks of the two rules were built separately")
+	}
+	if want := []string{"corp-networks", "admins"}; !reflect.DeepEqual(second.groups, want) {
+		t.Errorf("groups = %v, want %v", second.groups, want)
+	}
+}
@@ -0,0 +1,146 @@
+package convert
+
+// Pipeline holds the settings of every step that turns an export into
+// policies, so the same conversion can be applied to several exports
+type Pipeline struct {
+	Options Options
+	// Rules also converts the security policy rules of the export
+	Rules       bool
+	MergeRules  bool
+	MergeRanges bool
+	// NamespaceBaseline combines the policies of each namespace into one
+	// all-pods policy
+	NamespaceBaseline bool
+	// LabelPrefix is put in front of the generated label keys
+	LabelPrefix       string
+	GlobalSelector    map[string]string
+	NamespaceSelector map[string]string
+	// Base is the optional policy merged into every generated policy
+	Base           *NetworkPolicy
+	AllowWideOpen  bool
+	RequirePeers   bool
+	PortNames      map[string]string
+	SplitDirection bool
+	MergeEgress    bool
+	// PolicyTypes replaces the inferred policy types when set
+	PolicyTypes []string
+	EgressCIDRs []string
+	// SameNamespace allows egress to the policy's own namespace, and
+	// SameNamespaceIngress also ingress from it
+	SameNamespace        bool
+	SameNamespaceIngress bool
+	APIServer            *APIServerEgress
+	// MaxPortsPerRule splits longer port lists into several rules when set
+	MaxPortsPerRule int
+	// ExplicitEndPort gives single ports an endPort equal to the port
+	ExplicitEndPort bool
+	// NoEndPort expands ranges of up to NoEndPortMax ports into single ports
+	NoEndPort    bool
+	NoEndPortMax int
+	Owner        *OwnerReference
+	// ManagedBy labels every policy as managed by this tool
+	ManagedBy bool
+}
+
+// convert generates the policies for an export, along with the coverage of
+// its services
+func (p Pipeline) convert(root Root, diag *diagnostics) ([]NetworkPolicy, []CoverageEntry, error) {
+	// Generate NetworkPolicies
+	policies := servicePolicies(diag, root, p.Options)
+
+	// Report which services produced rules before anything else is added
+	coverage := serviceCoverage(diag, root.Services, policies)
+
+	// Generate NetworkPolicies from security policy rules
+	if p.Rules {
+		policies = append(policies, rulePolicies(diag, root, p.Options)...)
+	}
+
+	// Keep the widest of ranges that start at the same port
+	resolvePortConflicts(diag, policies)
+
+	// Keep the generated labels apart from existing workload labels
+	prefixLabels(policies, p.LabelPrefix, p.Options)
+
+	// Combine the rules of a direction that have the same peers
+	if p.MergeRules {
+		mergePeerRules(policies)
+	}
+
+	if p.MergeRanges {
+		mergeRanges(policies)
+	}
+
+	// Lock down each namespace with the union of its policies
+	if p.NamespaceBaseline {
+		policies = namespaceBaselines(policies)
+	}
+
+	// Select the same workloads in every policy
+	applyGlobalSelector(policies, p.GlobalSelector)
+
+	// Limit every rule to the selected namespaces
+	applyNamespaceSelector(policies, p.NamespaceSelector)
+
+	// Inherit the common rules of the base policy
+	if p.Base != nil {
+		mergeBasePolicy(diag, policies, *p.Base)
+	}
+
+	// Catch selectors that select all pods by accident
+	checkSelectors(diag, policies)
+
+	// Refuse to open workloads to everything unless asked to
+	policies = guardWideOpen(diag, policies, p.AllowWideOpen)
+
+	// Rules without peers allow traffic from or to anywhere
+	if err := checkPeers(diag, policies, p.RequirePeers); err != nil {
+		return nil, nil, err
+	}
+
+	// Reference container port names instead of numbers where mapped
+	if p.PortNames != nil {
+		applyPortNames(policies, p.PortNames)
+	}
+
+	if p.SplitDirection {
+		policies = splitDirections(policies)
+	}
+
+	// Centralize egress in one policy per namespace
+	if p.MergeEgress {
+		policies = mergeEgress(policies)
+	}
+
+	applyPolicyTypes(policies, p.PolicyTypes)
+
+	// Avoid endPort for clusters that do not support it
+	if p.NoEndPort {
+		expandEndPorts(diag, policies, p.NoEndPortMax)
+	}
+
+	// Keep port lists short for controllers that handle them poorly
+	splitPortLists(policies, p.MaxPortsPerRule)
+
+	if p.ExplicitEndPort {
+		explicitEndPorts(policies)
+	}
+
+	// Always permit egress to the allow-listed CIDRs
+	allowEgressCIDRs(policies, p.EgressCIDRs)
+
+	// Keep the pods of a namespace talking to each other
+	if p.SameNamespace {
+		allowSameNamespace(policies, p.SameNamespaceIngress)
+	}
+
+	// Let workloads that restrict egress reach the API server
+	allowAPIServer(policies, p.APIServer)
+
+	// Let the owning controller garbage collect the policies
+	applyOwner(policies, p.Owner)
+
+	// Tell the policies of this tool from those managed elsewhere
+	applyManagedBy(policies, p.ManagedBy)
+	return policies, coverage, nil
+}
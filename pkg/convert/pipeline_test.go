@@ -0,0 +1,40 @@
+package convert
+
+import "testing"
+
+// testOptions are the conversion options of the default flags
+func testOptions() Options {
+	return Options{Namespace: "default", Naming: NameOptions{Replacement: "-"}}
+}
+
+// convertExport runs a pipeline on an inline export, returning the
+// policies and the diagnostics of the conversion
+func convertExport(t *testing.T, export string, p Pipeline) ([]NetworkPolicy, *diagnostics) {
+	t.Helper()
+	root, err := parseRoot([]byte(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diag := newDiagnostics(verbosityQuiet)
+	policies, _, err := p.convert(root, diag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policies, diag
+}
+
+// findPolicy returns the policy with the given name
+func findPolicy(t *testing.T, policies []NetworkPolicy, name string) NetworkPolicy {
+	t.Helper()
+	for _, policy := range policies {
+		if policy.Metadata.Name == name {
+			return policy
+		}
+	}
+	var names []string
+	for _, policy := range policies {
+		names = append(names, policy.Metadata.Name)
+	}
+	t.Fatalf("no policy %s in %v", name, names)
+	return NetworkPolicy{}
+}
@@ -0,0 +1,229 @@
+package convert
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// maxPortNameLength is the longest valid container port name
+const maxPortNameLength = 15
+
+var portNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// IntOrString is a NetworkPolicy port given either by number or by name
+type IntOrString struct {
+	IntVal int
+	StrVal string
+}
+
+// MarshalYAML emits named ports as strings and numeric ports as integers
+func (p IntOrString) MarshalYAML() (interface{}, error) {
+	if p.StrVal != "" {
+		return p.StrVal, nil
+	}
+	return p.IntVal, nil
+}
+
+// UnmarshalYAML reads a port given as an integer or a name
+func (p *IntOrString) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var number int
+	if err := unmarshal(&number); err == nil {
+		*p = IntOrString{IntVal: number}
+		return nil
+	}
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+	*p = IntOrString{StrVal: name}
+	return nil
+}
+
+func (p IntOrString) String() string {
+	if p.StrVal != "" {
+		return p.StrVal
+	}
+	return strconv.Itoa(p.IntVal)
+}
+
+// loadPortNames reads a YAML map from "port" or "port/PROTOCOL" to the
+// container port name that should be emitted instead
+func loadPortNames(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for key, name := range raw {
+		port, protocol, hasProtocol := strings.Cut(key, "/")
+		if number, err := strconv.Atoi(port); err != nil || number < 1 || number > 65535 {
+			return nil, fmt.Errorf("%q is not a valid port", key)
+		}
+		if !validPortName(name) {
+			return nil, fmt.Errorf("%q is not a valid port name for port %s", name, key)
+		}
+		if hasProtocol {
+			key = port + "/" + strings.ToUpper(protocol)
+		}
+		names[key] = name
+	}
+	return names, nil
+}
+
+// validPortName reports whether a name is a valid IANA service name as
+// required for container port names
+func validPortName(name string) bool {
+	return len(name) <= maxPortNameLength &&
+		portNamePattern.MatchString(name) &&
+		!strings.Contains(name, "--") &&
+		strings.ContainsAny(name, "abcdefghijklmnopqrstuvwxyz")
+}
+
+// applyPortNames replaces numeric ports that have a mapped name with the
+// named port. A protocol-specific mapping wins over a port-only one. Port
+// ranges stay numeric, since endPort cannot be used with a named port.
+func applyPortNames(policies []NetworkPolicy, names map[string]string) {
+	if len(names) == 0 {
+		return
+	}
+	lookup := func(port *IntOrString, protocol string) *IntOrString {
+		if port == nil || port.StrVal != "" {
+			return port
+		}
+		number := strconv.Itoa(port.IntVal)
+		if name, ok := names[number+"/"+strings.ToUpper(protocol)]; ok {
+			return &IntOrString{StrVal: name}
+		}
+		if name, ok := names[number]; ok {
+			return &IntOrString{StrVal: name}
+		}
+		return port
+	}
+	for i := range policies {
+		for j := range policies[i].Spec.Ingress {
+			for k, port := range policies[i].Spec.Ingress[j].Ports {
+				if port.EndPort == nil {
+					policies[i].Spec.Ingress[j].Ports[k].Port = lookup(port.Port, port.Protocol)
+				}
+			}
+		}
+		for j := range policies[i].Spec.Egress {
+			for k, port := range policies[i].Spec.Egress[j].Ports {
+				if port.EndPort == nil {
+					policies[i].Spec.Egress[j].Ports[k].Port = lookup(port.Port, port.Protocol)
+				}
+			}
+		}
+	}
+}
+
+// explicitEndPorts sets the endPort of every single numeric port to the
+// port itself, for controllers that only accept ranges. Named ports cannot
+// have an endPort and are left alone.
+func explicitEndPorts(policies []NetworkPolicy) {
+	for i := range policies {
+		for _, rules := range [][]Rule{policies[i].Spec.Ingress, policies[i].Spec.Egress} {
+			for _, rule := range rules {
+				for k, port := range rule.Ports {
+					if port.Port == nil || port.Port.StrVal != "" || port.EndPort != nil {
+						continue
+					}
+					end := port.Port.IntVal
+					rule.Ports[k].EndPort = &end
+				}
+			}
+		}
+	}
+}
+
+// expandEndPorts replaces every port range with its individual ports, for
+// clusters that predate endPort. Ranges of more than max ports are dropped
+// with a warning, and so are rules left without ports, which would
+// otherwise allow every port.
+func expandEndPorts(diag *diagnostics, policies []NetworkPolicy, max int) {
+	for i := range policies {
+		policies[i].Spec.Ingress = expandRuleRanges(diag, policies[i], policies[i].Spec.Ingress, max)
+		policies[i].Spec.Egress = expandRuleRanges(diag, policies[i], policies[i].Spec.Egress, max)
+	}
+}
+
+// expandRuleRanges returns the rules of a policy with their ranges expanded
+func expandRuleRanges(diag *diagnostics, policy NetworkPolicy, rules []Rule, max int) []Rule {
+	if len(rules) == 0 {
+		return rules
+	}
+	var result []Rule
+	for _, rule := range rules {
+		if len(rule.Ports) == 0 {
+			result = append(result, rule)
+			continue
+		}
+		var ports []PortRule
+		for _, port := range rule.Ports {
+			if port.EndPort == nil {
+				if !containsPort(ports, port) {
+					ports = append(ports, port)
+				}
+				continue
+			}
+			start, end := port.Port.IntVal, *port.EndPort
+			if end-start+1 > max {
+				diag.warn(policy.source, warnUnexpandableRange, "policy %s from %q: range %d-%d/%s has %d ports, more than -no-endport-max %d, so it cannot be represented without endPort and is dropped", policy.Metadata.Name, policy.source, start, end, port.Protocol, end-start+1, max)
+				continue
+			}
+			for number := start; number <= end; number++ {
+				single := PortRule{Port: &IntOrString{IntVal: number}, Protocol: port.Protocol}
+				if !containsPort(ports, single) {
+					ports = append(ports, single)
+				}
+			}
+		}
+		if len(ports) == 0 {
+			diag.warn(policy.source, warnUnexpandableRange, "policy %s from %q: a rule has no ports left without endPort and is dropped, as it would allow every port", policy.Metadata.Name, policy.source)
+			continue
+		}
+		rule.Ports = ports
+		result = append(result, rule)
+	}
+	return result
+}
+
+// splitPortLists splits every rule with more than max ports into several
+// rules with the same peers and at most max ports each
+func splitPortLists(policies []NetworkPolicy, max int) {
+	if max <= 0 {
+		return
+	}
+	for i := range policies {
+		policies[i].Spec.Ingress = splitRulePorts(policies[i].Spec.Ingress, max)
+		policies[i].Spec.Egress = splitRulePorts(policies[i].Spec.Egress, max)
+	}
+}
+
+// splitRulePorts returns the rules with each port list cut into chunks of
+// at most max ports, keeping the order of the ports
+func splitRulePorts(rules []Rule, max int) []Rule {
+	var result []Rule
+	for _, rule := range rules {
+		ports := rule.Ports
+		for len(ports) > max {
+			chunk := rule
+			chunk.Ports = ports[:max:max]
+			result = append(result, chunk)
+			ports = ports[max:]
+		}
+		rule.Ports = ports
+		result = append(result, rule)
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPortLists(t *testing.T) {
+	export := `{"services": [{"display_name": "app", "service_entries": [
+		{"display_name": "tcp", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["22", "80", "443", "8080", "9090"]}
+	]}]}`
+	policies, _ := convertExport(t, export, Pipeline{Options: testOptions(), MaxPortsPerRule: 2})
+
+	ingress := findPolicy(t, policies, "app").Spec.Ingress
+	want := [][]string{{"TCP/22", "TCP/80"}, {"TCP/443", "TCP/8080"}, {"TCP/9090"}}
+	if len(ingress) != len(want) {
+		t.Fatalf("ingress has %d rules, want %d: %+v", len(ingress), len(want), ingress)
+	}
+	for i, rule := range ingress {
+		if got := portSummaries(rule.Ports); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("rule %d ports = %v, want %v", i, got, want[i])
+		}
+	}
+
+	unlimited, _ := convertExport(t, export, Pipeline{Options: testOptions()})
+	if rules := unlimited[0].Spec.Ingress; len(rules) != 1 || len(rules[0].Ports) != 5 {
+		t.Errorf("without a limit ingress = %+v, want one rule with 5 ports", rules)
+	}
+}
+
+func TestSplitRulePortsKeepsPeers(t *testing.T) {
+	peer := NetworkPolicyPeer{IPBlock: &IPBlock{CIDR: "10.0.0.0/8"}}
+	rules := splitRulePorts([]Rule{{From: []NetworkPolicyPeer{peer}, Ports: testPorts(t, "UDP", "53", "123", "161")}}, 2)
+	if len(rules) != 2 {
+		t.Fatalf("splitRulePorts returned %d rules, want 2", len(rules))
+	}
+	for i, rule := range rules {
+		if !reflect.DeepEqual(rule.From, []NetworkPolicyPeer{peer}) {
+			t.Errorf("rule %d peers = %+v, want %+v", i, rule.From, peer)
+		}
+	}
+	rules[0].Ports = append(rules[0].Ports, testPorts(t, "UDP", "514")...)
+	if got := portSummaries(rules[1].Ports); !reflect.DeepEqual(got, []string{"UDP/161"}) {
+		t.Errorf("appending to the first chunk changed the second to %v", got)
+	}
+}
+
+func TestExplicitEndPorts(t *testing.T) {
+	for _, explicit := range []bool{false, true} {
+		policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions(), ExplicitEndPort: explicit})
+		web := findPolicy(t, policies, "web").Spec.Ingress[0].Ports[0]
+		switch {
+		case explicit && (web.EndPort == nil || *web.EndPort != 80):
+			t.Errorf("-explicit-endport: web port = %+v, want endPort 80", web)
+		case !explicit && web.EndPort != nil:
+			t.Errorf("web port = %+v, want no endPort by default", web)
+		}
+		if got := portSummary(findPolicy(t, policies, "client").Spec.Egress[0].Ports[0]); got != "TCP/1024-65535" {
+			t.Errorf("explicit %v: client range = %s, want TCP/1024-65535", explicit, got)
+		}
+	}
+
+	named := []NetworkPolicy{{Spec: PolicySpec{Ingress: []Rule{{Ports: []PortRule{{Port: &IntOrString{StrVal: "http"}, Protocol: "TCP"}}}}}}}
+	explicitEndPorts(named)
+	if port := named[0].Spec.Ingress[0].Ports[0]; port.EndPort != nil {
+		t.Errorf("named port = %+v, want no endPort", port)
+	}
+}
+
+func TestExpandEndPorts(t *testing.T) {
+	export := `{"services": [
+		{"display_name": "app", "service_entries": [{"display_name": "tcp", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["8000-8003", "8002", "9000-9100"]}]},
+		{"display_name": "bulk", "service_entries": [{"display_name": "tcp", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["10000-20000"]}]}
+	]}`
+	policies, diag := convertExport(t, export, Pipeline{Options: testOptions(), NoEndPort: true, NoEndPortMax: 32})
+
+	app := findPolicy(t, policies, "app")
+	if want := []string{"TCP/8000", "TCP/8001", "TCP/8002", "TCP/8003"}; len(app.Spec.Ingress) != 1 || !reflect.DeepEqual(portSummaries(app.Spec.Ingress[0].Ports), want) {
+		t.Errorf("app ingress = %+v, want one rule with ports %v", app.Spec.Ingress, want)
+	}
+	if bulk := findPolicy(t, policies, "bulk"); len(bulk.Spec.Ingress) != 0 {
+		t.Errorf("bulk ingress = %+v, want the over-limit rule dropped", bulk.Spec.Ingress)
+	}
+
+	want := []string{
+		`policy app from "app": range 9000-9100/TCP has 101 ports, more than -no-endport-max 32, so it cannot be represented without endPort and is dropped`,
+		`policy bulk from "bulk": range 10000-20000/TCP has 10001 ports, more than -no-endport-max 32, so it cannot be represented without endPort and is dropped`,
+		`policy bulk from "bulk": a rule has no ports left without endPort and is dropped, as it would allow every port`,
+	}
+	var got []string
+	for _, w := range diag.warnings {
+		if w.Type == warnUnexpandableRange {
+			got = append(got, w.Message)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings = %q, want %q", got, want)
+	}
+}
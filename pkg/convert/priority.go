@@ -0,0 +1,71 @@
+package convert
+
+import (
+	"sort"
+	"strconv"
+)
+
+// priorityAnnotation records the position of the source rule in the NSX
+// evaluation order, starting at 1
+const priorityAnnotation = "nsx.vmware.com/priority"
+
+// categoryOrder is the order in which NSX evaluates the distributed firewall
+// categories. Unknown categories come last.
+var categoryOrder = map[string]int{
+	"Ethernet":       0,
+	"Emergency":      1,
+	"Infrastructure": 2,
+	"Environment":    3,
+	"Application":    4,
+}
+
+// ruleOrder is the position of a rule in the NSX evaluation order, and the
+// policies generated from it
+type ruleOrder struct {
+	category       int
+	policySequence int
+	ruleSequence   int
+	policies       []int
+}
+
+// sequence returns the sequence number of an NSX policy or rule, which some
+// exports call priority
+func sequence(sequenceNumber, priority *int) (int, bool) {
+	switch {
+	case sequenceNumber != nil:
+		return *sequenceNumber, true
+	case priority != nil:
+		return *priority, true
+	}
+	return 0, false
+}
+
+// annotatePriorities records the evaluation order of the rules on their
+// policies. NSX evaluates rules by category, then by the sequence number of
+// their security policy and their own. NetworkPolicies and
+// CiliumNetworkPolicies have no priority, so the ordering is lost and a
+// warning is logged. Nothing is done when no sequence numbers are set.
+func annotatePriorities(diag *diagnostics, policies []NetworkPolicy, orders []ruleOrder) {
+	if len(orders) == 0 {
+		return
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		if a.category != b.category {
+			return a.category < b.category
+		}
+		if a.policySequence != b.policySequence {
+			return a.policySequence < b.policySequence
+		}
+		return a.ruleSequence < b.ruleSequence
+	})
+	for rank, order := range orders {
+		for _, i := range order.policies {
+			if policies[i].Metadata.Annotations == nil {
+				policies[i].Metadata.Annotations = map[string]string{}
+			}
+			policies[i].Metadata.Annotations[priorityAnnotation] = strconv.Itoa(rank + 1)
+		}
+	}
+	diag.warn("", warnOrderingLost, "the export orders its rules by sequence number, but NetworkPolicies and CiliumNetworkPolicies have no priority and allow the union of their rules; the NSX order is kept in the %s annotation", priorityAnnotation)
+}
@@ -0,0 +1,94 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRulePriorities(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	policies, _, err := Pipeline{Options: testOptions(), Rules: true}.convert(readExport(t, "rule-priorities.json"), diag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		policy   string
+		priority string
+	}{
+		// Infrastructure comes before Application, whatever the sequence numbers
+		{"jump-to-web", "1"},
+		// Within a policy, the lower rule sequence number comes first
+		{"admins-to-web", "2"},
+		{"clients-to-web", "3"},
+		// Service policies have no NSX order
+		{"ssh", ""},
+		{"https", ""},
+	} {
+		if got := findPolicy(t, policies, tt.policy).Metadata.Annotations[priorityAnnotation]; got != tt.priority {
+			t.Errorf("%s priority = %q, want %q", tt.policy, got, tt.priority)
+		}
+	}
+	var lost []Warning
+	for _, w := range diag.warnings {
+		if w.Type == warnOrderingLost {
+			lost = append(lost, w)
+		}
+	}
+	if len(lost) != 1 {
+		t.Errorf("ordering-lost warnings = %+v, want one", lost)
+	}
+}
+
+func TestAnnotatePriorities(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		orders []ruleOrder
+		want   []string
+	}{
+		{
+			name:   "category first",
+			orders: []ruleOrder{{category: 4, policySequence: 1, ruleSequence: 1, policies: []int{0}}, {category: 1, policySequence: 9, ruleSequence: 9, policies: []int{1}}},
+			want:   []string{"2", "1"},
+		},
+		{
+			name:   "then policy sequence",
+			orders: []ruleOrder{{category: 4, policySequence: 20, ruleSequence: 1, policies: []int{0}}, {category: 4, policySequence: 10, ruleSequence: 5, policies: []int{1}}},
+			want:   []string{"2", "1"},
+		},
+		{
+			name:   "then rule sequence",
+			orders: []ruleOrder{{category: 4, policySequence: 10, ruleSequence: 7, policies: []int{0}}, {category: 4, policySequence: 10, ruleSequence: 3, policies: []int{1}}},
+			want:   []string{"2", "1"},
+		},
+		{
+			name:   "ties keep the export order",
+			orders: []ruleOrder{{category: 4, policies: []int{0}}, {category: 4, policies: []int{1}}},
+			want:   []string{"1", "2"},
+		},
+		{
+			name:   "policies of one rule share its rank",
+			orders: []ruleOrder{{category: 4, ruleSequence: 2, policies: []int{0, 1}}},
+			want:   []string{"1", "1"},
+		},
+	} {
+		policies := []NetworkPolicy{newPolicy("a", "default"), newPolicy("b", "default")}
+		diag := newDiagnostics(verbosityQuiet)
+		annotatePriorities(diag, policies, tt.orders)
+		var got []string
+		for _, policy := range policies {
+			got = append(got, policy.Metadata.Annotations[priorityAnnotation])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: priorities = %v, want %v", tt.name, got, tt.want)
+		}
+		if len(diag.warnings) != 1 || diag.warnings[0].Type != warnOrderingLost {
+			t.Errorf("%s: warnings = %+v, want one ordering-lost", tt.name, diag.warnings)
+		}
+	}
+
+	diag := newDiagnostics(verbosityQuiet)
+	annotatePriorities(diag, []NetworkPolicy{newPolicy("a", "default")}, nil)
+	if len(diag.warnings) != 0 {
+		t.Errorf("warnings without sequence numbers = %+v, want none", diag.warnings)
+	}
+}
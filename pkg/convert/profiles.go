@@ -0,0 +1,203 @@
+package convert
+
+import (
+	"sort"
+	"strings"
+)
+
+// profileAnnotation records the L7 context profiles of a rule on the
+// policies generated from it
+const profileAnnotation = "nsx.vmware.com/context-profiles"
+
+// httpAppID is the NSX app-id of plain HTTP, the only one Cilium output
+// can enforce
+const httpAppID = "HTTP"
+
+// ContextProfile is an NSX context profile, an L7 restriction by app-id,
+// domain name or URL category
+type ContextProfile struct {
+	DisplayName string             `json:"display_name"`
+	ID          string             `json:"id"`
+	Path        string             `json:"path"`
+	Attributes  []ProfileAttribute `json:"attributes"`
+}
+
+// ProfileAttribute is a single criterion of a context profile, e.g. APP_ID.
+// Sub-attributes narrow it down, e.g. HTTP_METHOD and HTTP_PATH for HTTP.
+type ProfileAttribute struct {
+	Key           string             `json:"key"`
+	Value         []string           `json:"value"`
+	SubAttributes []ProfileAttribute `json:"sub_attributes"`
+}
+
+// Sub-attributes of the HTTP app-id that Cilium output enforces
+const (
+	httpMethodKey = "HTTP_METHOD"
+	httpPathKey   = "HTTP_PATH"
+)
+
+// String describes the attribute, e.g. "APP_ID=HTTP(HTTP_METHOD=GET)"
+func (a ProfileAttribute) String() string {
+	s := a.Key + "=" + strings.Join(a.Value, ",")
+	if len(a.SubAttributes) == 0 {
+		return s
+	}
+	var subs []string
+	for _, sub := range a.SubAttributes {
+		subs = append(subs, sub.String())
+	}
+	return s + "(" + strings.Join(subs, "; ") + ")"
+}
+
+// String describes the profile, e.g. "web-http: APP_ID=HTTP"
+func (p ContextProfile) String() string {
+	var parts []string
+	for _, attr := range p.Attributes {
+		parts = append(parts, attr.String())
+	}
+	if len(parts) == 0 {
+		return p.DisplayName
+	}
+	return p.DisplayName + ": " + strings.Join(parts, "; ")
+}
+
+// httpRules returns the Cilium HTTP rules for the HTTP app-ids of the
+// profile, one per combination of its HTTP_METHOD and HTTP_PATH values. An
+// HTTP app-id without either allows any request. It warns about the
+// constraints of other app-ids, which are not enforced.
+func (p ContextProfile) httpRules(diag *diagnostics, rule string) []CiliumHTTPRule {
+	var rules []CiliumHTTPRule
+	for _, attr := range p.Attributes {
+		if attr.Key != "APP_ID" {
+			continue
+		}
+		http := false
+		for _, id := range attr.Value {
+			if id == httpAppID {
+				http = true
+			}
+		}
+		if !http {
+			if len(attr.SubAttributes) > 0 {
+				diag.warn(rule, warnProfileIgnored, "context profile %q constrains app-ids %s, only HTTP constraints can be enforced", p.DisplayName, strings.Join(attr.Value, ","))
+			}
+			continue
+		}
+		methods, paths := []string{""}, []string{""}
+		for _, sub := range attr.SubAttributes {
+			switch sub.Key {
+			case httpMethodKey:
+				methods = sub.Value
+			case httpPathKey:
+				paths = sub.Value
+			default:
+				diag.warn(rule, warnProfileIgnored, "context profile %q has HTTP constraint %s, which Cilium output cannot enforce", p.DisplayName, sub.Key)
+			}
+		}
+		for _, method := range methods {
+			for _, path := range paths {
+				rules = append(rules, CiliumHTTPRule{Method: method, Path: path})
+			}
+		}
+	}
+	return rules
+}
+
+// ciliumEnforced reports whether Cilium output enforces every constraint of
+// the profile: it only has HTTP app-ids, constrained by method and path
+func (p ContextProfile) ciliumEnforced() bool {
+	for _, attr := range p.Attributes {
+		if attr.Key != "APP_ID" {
+			return false
+		}
+		for _, id := range attr.Value {
+			if id != httpAppID {
+				return false
+			}
+		}
+		for _, sub := range attr.SubAttributes {
+			if sub.Key != httpMethodKey && sub.Key != httpPathKey {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// profilesByPath indexes context profiles by path, id and display name
+func profilesByPath(root Root) map[string]ContextProfile {
+	profiles := map[string]ContextProfile{}
+	for _, profile := range root.ContextProfiles {
+		profiles[profile.DisplayName] = profile
+		profiles[profile.ID] = profile
+		profiles[profile.Path] = profile
+	}
+	return profiles
+}
+
+// annotateProfiles documents the context profiles of a rule on its policies
+// and warns that NetworkPolicies cannot enforce them. The HTTP rules of the
+// profiles are kept on the policies so Cilium output can require them on
+// their ports.
+func annotateProfiles(diag *diagnostics, rule SecurityRule, profiles map[string]ContextProfile, policies []NetworkPolicy) {
+	if len(policies) == 0 {
+		return
+	}
+	var described []string
+	var http []CiliumHTTPRule
+	enforced := true
+	for _, ref := range rule.Profiles {
+		if ref == "ANY" {
+			continue
+		}
+		profile, found := profiles[ref]
+		if !found {
+			profile, found = profiles[lastPathElement(ref)]
+		}
+		if !found {
+			diag.warn(rule.DisplayName, warnUnknownProfile, "rule %q references unknown context profile %q", rule.DisplayName, ref)
+			continue
+		}
+		described = append(described, profile.String())
+		http = append(http, profile.httpRules(diag, rule.DisplayName)...)
+		enforced = enforced && profile.ciliumEnforced()
+	}
+	// A rule allowing any request makes the others redundant
+	for _, r := range http {
+		if r == (CiliumHTTPRule{}) {
+			http = []CiliumHTTPRule{r}
+			break
+		}
+	}
+	if len(described) == 0 {
+		return
+	}
+	sort.Strings(described)
+	summary := strings.Join(described, " | ")
+	if enforced {
+		diag.warnUnlessCilium(rule.DisplayName, warnProfileIgnored, "rule %q restricts traffic with context profiles %q, which NetworkPolicies cannot enforce", rule.DisplayName, summary)
+	} else {
+		diag.warn(rule.DisplayName, warnProfileIgnored, "rule %q restricts traffic with context profiles %q, which NetworkPolicies cannot enforce", rule.DisplayName, summary)
+	}
+	for i := range policies {
+		if policies[i].Metadata.Annotations == nil {
+			policies[i].Metadata.Annotations = map[string]string{}
+		}
+		policies[i].Metadata.Annotations[profileAnnotation] = summary
+		policies[i].http = http
+		if len(http) > 0 && !policyInspectable(policies[i]) {
+			diag.warn(rule.DisplayName, warnProfileIgnored, "rule %q has HTTP constraints, but policy %s has ports Cilium cannot inspect, so it stays L4-only", rule.DisplayName, policies[i].Metadata.Name)
+		}
+	}
+}
+
+// policyInspectable reports whether Cilium can apply L7 rules to every rule
+// of the policy
+func policyInspectable(policy NetworkPolicy) bool {
+	for _, rule := range append(append([]Rule{}, policy.Spec.Ingress...), policy.Spec.Egress...) {
+		if len(rule.Ports) == 0 || !httpInspectable(rule.Ports) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package convert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ipProtocolServiceEntry is the resource type of NSX entries that match an
+// IP protocol number instead of L4 ports
+const ipProtocolServiceEntry = "IPProtocolServiceEntry"
+
+// unsupportedProtocols are the IP protocols NSX can match that have no
+// NetworkPolicy equivalent, by protocol number
+var unsupportedProtocols = map[int]string{
+	4:   "IPIP",
+	41:  "IPv6",
+	47:  "GRE",
+	50:  "ESP",
+	51:  "AH",
+	89:  "OSPF",
+	103: "PIM",
+	112: "VRRP",
+	115: "L2TP",
+}
+
+// DroppedRule is a service entry, or a protocol of one, that was left out
+// because Kubernetes cannot express it
+type DroppedRule struct {
+	Service  string `yaml:"service" json:"service"`
+	Entry    string `yaml:"entry" json:"entry"`
+	Protocol string `yaml:"protocol" json:"protocol"`
+	Reason   string `yaml:"reason" json:"reason"`
+}
+
+// entryProtocols returns the L4 protocols of an entry that a NetworkPolicy
+// can match. Protocols of the unsupported list, and IP protocol entries,
+// are dropped with a warning and recorded in the dropped rules report.
+func entryProtocols(diag *diagnostics, service string, entry ServiceEntry) []string {
+	if entry.ResourceType == ipProtocolServiceEntry {
+		protocol := "protocol without number"
+		if entry.ProtocolNumber != nil {
+			protocol = ipProtocolName(*entry.ProtocolNumber)
+		}
+		diag.dropProtocol(service, entry, protocol)
+		return nil
+	}
+	protocols := entry.protocols()
+	supported := make([]string, 0, len(protocols))
+	for _, protocol := range protocols {
+		if isUnsupportedProtocol(protocol) {
+			diag.dropProtocol(service, entry, strings.ToUpper(protocol))
+			continue
+		}
+		supported = append(supported, protocol)
+	}
+	return supported
+}
+
+// ipProtocolName returns the name of an unsupported IP protocol number, or
+// the number itself, e.g. "GRE" or "protocol 253"
+func ipProtocolName(number int) string {
+	if name, ok := unsupportedProtocols[number]; ok {
+		return name
+	}
+	return "protocol " + strconv.Itoa(number)
+}
+
+// isUnsupportedProtocol reports whether an L4 protocol name is on the
+// unsupported list
+func isUnsupportedProtocol(protocol string) bool {
+	for _, name := range unsupportedProtocols {
+		if strings.EqualFold(protocol, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropProtocol warns about an entry protocol that NetworkPolicies cannot
+// match and records it once in the dropped rules report
+func (d *diagnostics) dropProtocol(service string, entry ServiceEntry, protocol string) {
+	reason := fmt.Sprintf("NetworkPolicies only match TCP, UDP and SCTP, not %s", protocol)
+	d.warn(service, warnUnsupportedProtocol, "service %q: entry %q matches %s, which NetworkPolicies cannot express, dropping it", service, entry.DisplayName, protocol)
+	dropped := DroppedRule{Service: service, Entry: entry.DisplayName, Protocol: protocol, Reason: reason}
+	for _, existing := range d.dropped {
+		if existing == dropped {
+			return
+		}
+	}
+	d.dropped = append(d.dropped, dropped)
+}
@@ -0,0 +1,30 @@
+package convert
+
+// The label -managed-by-label sets on every policy, which -list-unmanaged
+// looks for to tell the policies of this tool from those managed elsewhere
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "vmware-analyzer-to-netpol"
+)
+
+// applyManagedBy labels every policy as managed by this tool. The labels
+// are copied, as the policies' metadata may be shared.
+func applyManagedBy(policies []NetworkPolicy, enabled bool) {
+	if !enabled {
+		return
+	}
+	for i := range policies {
+		labels := copyLabels(policies[i].Metadata.Labels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[managedByLabel] = managedByValue
+		policies[i].Metadata.Labels = labels
+	}
+}
+
+// managedBy reports whether the labels of an object mark it as managed by
+// this tool
+func managedBy(labels map[string]string) bool {
+	return labels[managedByLabel] == managedByValue
+}
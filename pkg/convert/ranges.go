@@ -0,0 +1,264 @@
+package convert
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPort is the highest valid port number
+const maxPort = 65535
+
+// parsePortRange parses an NSX port, either "443" or a range such as
+// "49152-65535". end equals start for a single port. The only range that
+// may start at 0 is the full range "0-65535".
+func parsePortRange(port string) (start, end int, err error) {
+	first, last, isRange := strings.Cut(port, "-")
+	start, err = strconv.Atoi(first)
+	if err != nil || start < 0 || start > maxPort {
+		return 0, 0, fmt.Errorf("%q is not a port number", first)
+	}
+	if !isRange {
+		return start, start, nil
+	}
+	end, err = strconv.Atoi(last)
+	if err != nil || end < 1 || end > maxPort {
+		return 0, 0, fmt.Errorf("%q is not a port number", last)
+	}
+	if (start == 0 && end != maxPort) || start > end {
+		return 0, 0, fmt.Errorf("range must run from 1 or above to a port that is not lower")
+	}
+	return start, end, nil
+}
+
+// splitPortProtocol splits a Linux services style port such as "tcp/443" or
+// "udp/8000-8100" into its protocol and port. A port without a protocol is
+// returned as it is, with an empty protocol.
+func splitPortProtocol(port string) (protocol, rest string, err error) {
+	name, rest, combined := strings.Cut(port, "/")
+	if !combined {
+		return "", port, nil
+	}
+	protocol = strings.ToUpper(strings.TrimSpace(name))
+	switch protocol {
+	case "TCP", "UDP", "SCTP":
+		return protocol, strings.TrimSpace(rest), nil
+	}
+	return "", "", fmt.Errorf("%q is not a TCP, UDP or SCTP protocol", name)
+}
+
+// portRange is a numeric port range of one protocol
+type portRange struct {
+	start, end int
+}
+
+// mergeRanges coalesces overlapping and adjacent port ranges in every policy.
+// Rules of a direction that share the same peers are pooled first, and the
+// merged ports replace the first of them. Rules without ports allow all ports
+// and are never pooled. Named ports and all-ports entries are kept as they are.
+func mergeRanges(policies []NetworkPolicy) {
+	for i := range policies {
+		policies[i].Spec.Ingress = mergeRuleRanges(policies[i].Spec.Ingress)
+		policies[i].Spec.Egress = mergeRuleRanges(policies[i].Spec.Egress)
+	}
+}
+
+// mergeRuleRanges pools the ports of rules with identical peers and merges
+// their ranges per protocol
+func mergeRuleRanges(rules []Rule) []Rule {
+	merged := poolRules(rules)
+	for j := range merged {
+		merged[j].Ports = mergePorts(merged[j].Ports)
+	}
+	return merged
+}
+
+// mergePeerRules combines the rules of each direction that have identical peers
+// into one rule listing the ports of all of them
+func mergePeerRules(policies []NetworkPolicy) {
+	for i := range policies {
+		policies[i].Spec.Ingress = poolRules(policies[i].Spec.Ingress)
+		policies[i].Spec.Egress = poolRules(policies[i].Spec.Egress)
+	}
+}
+
+// poolRules moves the ports of every rule into the first rule with the same
+// peers, dropping repeated ports. Rules without ports allow all ports and
+// are never pooled.
+func poolRules(rules []Rule) []Rule {
+	var merged []Rule
+	for _, rule := range rules {
+		pooled := false
+		for j := range merged {
+			if len(rule.Ports) == 0 || len(merged[j].Ports) == 0 {
+				continue
+			}
+			if reflect.DeepEqual(merged[j].From, rule.From) && reflect.DeepEqual(merged[j].To, rule.To) {
+				for _, port := range rule.Ports {
+					if !containsPort(merged[j].Ports, port) {
+						merged[j].Ports = append(merged[j].Ports, port)
+					}
+				}
+				pooled = true
+				break
+			}
+		}
+		if !pooled {
+			merged = append(merged, Rule{From: rule.From, To: rule.To, Ports: append([]PortRule{}, rule.Ports...)})
+		}
+	}
+	return merged
+}
+
+// mergePorts merges the numeric ports of each protocol into the fewest
+// ranges. Protocols keep the order in which they first appear.
+func mergePorts(ports []PortRule) []PortRule {
+	if len(ports) < 2 {
+		return ports
+	}
+	var protocols []string
+	ranges := map[string][]portRange{}
+	var other []PortRule
+	for _, port := range ports {
+		if port.Port == nil || port.Port.StrVal != "" {
+			other = append(other, port)
+			continue
+		}
+		if _, ok := ranges[port.Protocol]; !ok {
+			protocols = append(protocols, port.Protocol)
+		}
+		r := portRange{start: port.Port.IntVal, end: port.Port.IntVal}
+		if port.EndPort != nil {
+			r.end = *port.EndPort
+		}
+		ranges[port.Protocol] = append(ranges[port.Protocol], r)
+	}
+
+	result := make([]PortRule, 0, len(ports))
+	for _, protocol := range protocols {
+		list := ranges[protocol]
+		sort.Slice(list, func(a, b int) bool { return list[a].start < list[b].start })
+		current := list[0]
+		for _, r := range list[1:] {
+			if r.start <= current.end+1 {
+				if r.end > current.end {
+					current.end = r.end
+				}
+				continue
+			}
+			result = append(result, rangePort(current, protocol))
+			current = r
+		}
+		result = append(result, rangePort(current, protocol))
+	}
+	return append(result, other...)
+}
+
+// rangePort converts a range back into a NetworkPolicy port
+func rangePort(r portRange, protocol string) PortRule {
+	port := PortRule{Port: &IntOrString{IntVal: r.start}, Protocol: protocol}
+	if r.end > r.start {
+		end := r.end
+		port.EndPort = &end
+	}
+	return port
+}
+
+// resolvePortConflicts finds ports of a direction that start at the same port
+// with the same protocol and peers, but end at different ports. The widest
+// range is kept in place of the first occurrence and the others are dropped,
+// with a port-conflict warning.
+func resolvePortConflicts(diag *diagnostics, policies []NetworkPolicy) {
+	for i := range policies {
+		policies[i].Spec.Ingress = resolveRuleConflicts(diag, policies[i], policies[i].Spec.Ingress)
+		policies[i].Spec.Egress = resolveRuleConflicts(diag, policies[i], policies[i].Spec.Egress)
+	}
+}
+
+// portLocation is the position of a port within the rules of a direction
+type portLocation struct {
+	rule, port int
+}
+
+// portGroup is the first occurrence of a port start and the widest end seen
+type portGroup struct {
+	first portLocation
+	end   int
+}
+
+// resolveRuleConflicts resolves the conflicting ranges of one direction
+func resolveRuleConflicts(diag *diagnostics, policy NetworkPolicy, rules []Rule) []Rule {
+	var groups []*portGroup
+	dropped := map[portLocation]bool{}
+	for i, rule := range rules {
+		for k, port := range rule.Ports {
+			if port.Port == nil || port.Port.StrVal != "" {
+				continue
+			}
+			var group *portGroup
+			for _, candidate := range groups {
+				other := rules[candidate.first.rule]
+				first := other.Ports[candidate.first.port]
+				if first.Protocol == port.Protocol && first.Port.IntVal == port.Port.IntVal &&
+					reflect.DeepEqual(other.From, rule.From) && reflect.DeepEqual(other.To, rule.To) {
+					group = candidate
+					break
+				}
+			}
+			if group == nil {
+				groups = append(groups, &portGroup{first: portLocation{i, k}, end: portEnd(port)})
+				continue
+			}
+			first := rules[group.first.rule].Ports[group.first.port]
+			if portEnd(port) == portEnd(first) {
+				continue
+			}
+			group.end = max(group.end, portEnd(port))
+			diag.warn(policy.source, warnPortConflict, "policy %s lists %s and %s, which start at the same port, keeping %s/%d-%d",
+				policy.Metadata.Name, portSummary(first), portSummary(port), port.Protocol, port.Port.IntVal, group.end)
+			dropped[portLocation{i, k}] = true
+		}
+	}
+	if len(dropped) == 0 {
+		return rules
+	}
+
+	widened := map[portLocation]int{}
+	for _, group := range groups {
+		widened[group.first] = group.end
+	}
+	resolved := make([]Rule, 0, len(rules))
+	for i, rule := range rules {
+		if len(rule.Ports) == 0 {
+			resolved = append(resolved, rule)
+			continue
+		}
+		var ports []PortRule
+		for k, port := range rule.Ports {
+			location := portLocation{i, k}
+			if dropped[location] {
+				continue
+			}
+			if end, ok := widened[location]; ok && end > portEnd(port) {
+				port.EndPort = &end
+			}
+			ports = append(ports, port)
+		}
+		// A rule left without ports would allow all ports
+		if len(ports) > 0 {
+			rule.Ports = ports
+			resolved = append(resolved, rule)
+		}
+	}
+	return resolved
+}
+
+// portEnd returns the last port covered by a numeric port
+func portEnd(port PortRule) int {
+	if port.EndPort != nil {
+		return *port.EndPort
+	}
+	return port.Port.IntVal
+}
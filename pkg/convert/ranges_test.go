@@ -0,0 +1,137 @@
+package convert
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testPorts converts NSX port strings of one protocol into port rules
+func testPorts(t *testing.T, protocol string, ports ...string) []PortRule {
+	t.Helper()
+	diag := newDiagnostics(verbosityQuiet)
+	rules := entryPorts(diag, "test", ports, []string{protocol}, testOptions())
+	if len(diag.warnings) > 0 {
+		t.Fatalf("ports %q: %v", ports, diag.warnings)
+	}
+	return rules
+}
+
+// portSummaries formats port rules as PROTOCOL/port strings
+func portSummaries(ports []PortRule) []string {
+	summaries := make([]string, 0, len(ports))
+	for _, port := range ports {
+		summaries = append(summaries, portSummary(port))
+	}
+	return summaries
+}
+
+func TestMergePorts(t *testing.T) {
+	tests := []struct {
+		name  string
+		ports []PortRule
+		want  []string
+	}{
+		{"overlapping", testPorts(t, "TCP", "80-90", "85-100"), []string{"TCP/80-100"}},
+		{"adjacent", testPorts(t, "TCP", "80-90", "91-95"), []string{"TCP/80-95"}},
+		{"contained", testPorts(t, "TCP", "80-100", "85"), []string{"TCP/80-100"}},
+		{"single ports", testPorts(t, "TCP", "81", "80"), []string{"TCP/80-81"}},
+		{"separate", testPorts(t, "TCP", "80-90", "92-95"), []string{"TCP/80-90", "TCP/92-95"}},
+		{"per protocol", append(testPorts(t, "TCP", "80-90"), testPorts(t, "UDP", "85-100")...), []string{"TCP/80-90", "UDP/85-100"}},
+		{"all ports kept", append(testPorts(t, "TCP", "80", "81"), PortRule{Protocol: "UDP"}), []string{"TCP/80-81", "UDP/*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := portSummaries(mergePorts(tt.ports)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePorts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePortConflicts(t *testing.T) {
+	policies, diag := convertExport(t, `{"services": [
+		{"display_name": "app", "service_entries": [
+			{"display_name": "a", "l4_protocol": "TCP", "destination_ports": ["8000-8100", "9000"]},
+			{"display_name": "b", "l4_protocol": "TCP", "destination_ports": ["8000-8200"]},
+			{"display_name": "c", "l4_protocol": "TCP", "destination_ports": ["8000"]},
+			{"display_name": "d", "l4_protocol": "UDP", "destination_ports": ["8000-8300"]}
+		]}
+	]}`, Pipeline{Options: testOptions()})
+	var ports []string
+	for _, rule := range policies[0].Spec.Ingress {
+		ports = append(ports, portSummaries(rule.Ports)...)
+	}
+	if want := []string{"TCP/8000-8200", "TCP/9000", "UDP/8000-8300"}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("ports = %v, want the widest TCP range in place of the first", ports)
+	}
+	problems := diag.strictProblems()
+	if len(problems) != 2 {
+		t.Fatalf("strict problems = %+v, want a port-conflict per dropped range", problems)
+	}
+	for _, problem := range problems {
+		if problem.Type != warnPortConflict {
+			t.Errorf("strict problem %+v is not a port-conflict", problem)
+		}
+	}
+}
+
+func TestPoolRules(t *testing.T) {
+	web := []NetworkPolicyPeer{{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "web"}}}}
+	batch := []NetworkPolicyPeer{{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "batch"}}}}
+	rules := poolRules([]Rule{
+		{From: web, Ports: testPorts(t, "TCP", "80")},
+		{From: batch, Ports: testPorts(t, "TCP", "80")},
+		{From: web, Ports: testPorts(t, "TCP", "443", "80")},
+		{From: web},
+	})
+	if len(rules) != 3 {
+		t.Fatalf("poolRules returned %d rules, want 3: %+v", len(rules), rules)
+	}
+	want := []struct {
+		from  []NetworkPolicyPeer
+		ports []string
+	}{
+		{web, []string{"TCP/80", "TCP/443"}},
+		{batch, []string{"TCP/80"}},
+		{web, []string{}},
+	}
+	for i, w := range want {
+		if !reflect.DeepEqual(rules[i].From, w.from) {
+			t.Errorf("rule %d peers = %+v, want %+v", i, rules[i].From, w.from)
+		}
+		if got := portSummaries(rules[i].Ports); !reflect.DeepEqual(got, w.ports) {
+			t.Errorf("rule %d ports = %v, want %v", i, got, w.ports)
+		}
+	}
+}
+
+func TestSplitPortProtocol(t *testing.T) {
+	tests := []struct {
+		port, protocol, rest string
+	}{
+		{"tcp/443", "TCP", "443"},
+		{"UDP/53", "UDP", "53"},
+		{" sctp / 9100-9200", "SCTP", "9100-9200"},
+		{"8080", "", "8080"},
+	}
+	for _, tt := range tests {
+		protocol, rest, err := splitPortProtocol(tt.port)
+		if err != nil || protocol != tt.protocol || rest != tt.rest {
+			t.Errorf("splitPortProtocol(%q) = %q, %q, %v, want %q, %q", tt.port, protocol, rest, err, tt.protocol, tt.rest)
+		}
+	}
+	if _, _, err := splitPortProtocol("icmp/8"); err == nil {
+		t.Error("splitPortProtocol accepted icmp/8")
+	}
+}
+
+func TestEntryPortsCombinedProtocol(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	ports := entryPorts(diag, "dns", []string{"53", "udp/53", "tcp/9100-9200", "icmp/8"}, []string{"TCP"}, testOptions())
+	if want := []string{"TCP/53", "UDP/53", "TCP/9100-9200"}; !reflect.DeepEqual(portSummaries(ports), want) {
+		t.Errorf("ports = %v, want %v", portSummaries(ports), want)
+	}
+	if len(diag.warnings) != 1 || diag.warnings[0].Type != warnInvalidPort {
+		t.Errorf("warnings = %+v, want icmp/8 skipped as an invalid port", diag.warnings)
+	}
+}
@@ -0,0 +1,630 @@
+package convert
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"strings"
+)
+
+// Address families selectable with -ip-family
+const (
+	familyIPv4 = "ipv4"
+	familyIPv6 = "ipv6"
+	familyDual = "dual"
+)
+
+// maxRangeCIDRs caps how many CIDRs an IP range is split into before falling
+// back to a single covering CIDR
+const maxRangeCIDRs = 8
+
+// Domain represents an NSX policy domain
+type Domain struct {
+	DisplayName string          `json:"display_name"`
+	Resources   DomainResources `json:"resources"`
+}
+
+// DomainResources holds the security policies and groups of a domain
+type DomainResources struct {
+	SecurityPolicies []SecurityPolicy `json:"security_policies"`
+	Groups           []Group          `json:"groups"`
+}
+
+// SecurityPolicy represents an NSX distributed firewall policy
+type SecurityPolicy struct {
+	DisplayName string         `json:"display_name"`
+	Category    string         `json:"category"`
+	Scope       []string       `json:"scope"`
+	Rules       []SecurityRule `json:"rules"`
+	// SequenceNumber orders the policies of a category, lowest first
+	SequenceNumber *int `json:"sequence_number"`
+	Priority       *int `json:"priority"`
+}
+
+// SecurityRule represents a single rule of a security policy
+type SecurityRule struct {
+	DisplayName       string   `json:"display_name"`
+	ID                string   `json:"id"`
+	RuleID            int      `json:"rule_id"`
+	Action            string   `json:"action"`
+	Direction         string   `json:"direction"`
+	SourceGroups      []string `json:"source_groups"`
+	DestinationGroups []string `json:"destination_groups"`
+	Services          []string `json:"services"`
+	// ServiceEntries are ports given inline in the rule instead of by service
+	ServiceEntries []ServiceEntry `json:"service_entries"`
+	Scope          []string       `json:"scope"`
+	// Schedule is set on time-based rules
+	Schedule *RuleSchedule `json:"schedule"`
+	// Profiles are the paths of the rule's L7 context profiles
+	Profiles []string `json:"profiles"`
+	// SequenceNumber orders the rules of a policy, lowest first
+	SequenceNumber *int `json:"sequence_number"`
+	Priority       *int `json:"priority"`
+	// Disabled is set on rules turned off in NSX. Some exports give
+	// enabled instead.
+	Disabled bool  `json:"disabled"`
+	Enabled  *bool `json:"enabled"`
+}
+
+// disabled reports whether the rule is turned off in NSX
+func (r SecurityRule) disabled() bool {
+	return r.Disabled || (r.Enabled != nil && !*r.Enabled)
+}
+
+// disabledAnnotation marks the policies of rules disabled in NSX
+const disabledAnnotation = "nsx.vmware.com/disabled"
+
+// Group represents an NSX group of VMs or IP addresses
+type Group struct {
+	DisplayName string            `json:"display_name"`
+	Path        string            `json:"path"`
+	Members     []GroupMember     `json:"members"`
+	Expression  []GroupExpression `json:"expression"`
+	IPAddresses []string          `json:"ip_addresses"`
+	IPRanges    []string          `json:"ip_ranges"`
+	// ExcludedAddresses are carved out of the group's CIDRs as ipBlock
+	// exceptions
+	ExcludedAddresses []string `json:"excluded_addresses"`
+
+	// segment is set for the groups standing in for NSX segments
+	segment bool
+}
+
+// GroupMember represents a VM that belongs to a group
+type GroupMember struct {
+	DisplayName string `json:"display_name"`
+	ID          string `json:"id"`
+}
+
+// GroupExpression represents a membership criterion of a group
+type GroupExpression struct {
+	ResourceType string   `json:"resource_type"`
+	IPAddresses  []string `json:"ip_addresses"`
+	// MemberType, Key, Operator and Value describe a Condition, e.g. VMs
+	// whose Tag EQUALS "tier|web"
+	MemberType string `json:"member_type"`
+	Key        string `json:"key"`
+	Operator   string `json:"operator"`
+	Value      string `json:"value"`
+	// ConjunctionOperator joins the criteria around it with AND or OR
+	ConjunctionOperator string `json:"conjunction_operator"`
+	// Expressions are the criteria of a NestedExpression
+	Expressions []GroupExpression `json:"expressions"`
+}
+
+// ipAddresses returns every address, CIDR and range that defines the group
+func (g Group) ipAddresses() []string {
+	addresses := append([]string{}, g.IPAddresses...)
+	addresses = append(addresses, g.IPRanges...)
+	for _, expr := range g.Expression {
+		if expr.ResourceType == "IPAddressExpression" {
+			addresses = append(addresses, expr.IPAddresses...)
+		}
+	}
+	return addresses
+}
+
+// ruleEndpoints is the resolved form of a rule's source or destination groups
+type ruleEndpoints struct {
+	any       bool
+	workloads []string
+	// selectors select the workloads of groups defined by tag conditions
+	selectors []LabelSelector
+	// mapped are the groups with -group-labels
+	mapped   []GroupLabels
+	ipBlocks []NetworkPolicyPeer
+	// groups are the display names of the groups the endpoints come from
+	groups []string
+}
+
+// add appends the endpoints of another group
+func (e *ruleEndpoints) add(other ruleEndpoints) {
+	e.workloads = append(e.workloads, other.workloads...)
+	e.selectors = append(e.selectors, other.selectors...)
+	e.mapped = append(e.mapped, other.mapped...)
+	e.ipBlocks = append(e.ipBlocks, other.ipBlocks...)
+}
+
+// empty reports whether no endpoint could be resolved
+func (e ruleEndpoints) empty() bool {
+	return !e.any && len(e.workloads) == 0 && len(e.selectors) == 0 && len(e.mapped) == 0 && len(e.ipBlocks) == 0
+}
+
+// selectsWorkloads reports whether the endpoints can be a podSelector
+func (e ruleEndpoints) selectsWorkloads() bool {
+	return e.any || len(e.workloads) > 0 || len(e.selectors) > 0 || len(e.mapped) > 0
+}
+
+// rulePolicies generates NetworkPolicies from the rules of every security policy
+func rulePolicies(diag *diagnostics, root Root, opts Options) []NetworkPolicy {
+	services := servicesByPath(root)
+	profiles := profilesByPath(root)
+
+	var policies []NetworkPolicy
+	var orders []ruleOrder
+	sequenced := false
+	skipped := 0
+	for _, domain := range root.Domains {
+		groups := map[string]Group{}
+		for _, group := range append(segmentGroups(root), domain.Resources.Groups...) {
+			groups[group.DisplayName] = group
+			groups[lastPathElement(group.Path)] = group
+			groups[group.Path] = group
+		}
+		peers := newPeerBuilder(diag, groups, opts)
+		for _, securityPolicy := range domain.Resources.SecurityPolicies {
+			category, known := categoryOrder[securityPolicy.Category]
+			if !known {
+				category = len(categoryOrder)
+			}
+			policySequence, ok := sequence(securityPolicy.SequenceNumber, securityPolicy.Priority)
+			sequenced = sequenced || ok
+			for _, rule := range securityPolicy.Rules {
+				if rule.disabled() && !opts.IncludeDisabled {
+					diag.info(rule.DisplayName, infoDisabledRule, "rule %q is disabled, skipping it", rule.DisplayName)
+					skipped++
+					continue
+				}
+				rule.Scope = effectiveScope(securityPolicy.Scope, rule.Scope)
+				converted := convertRule(diag, rule, peers, services, opts)
+				annotateDisabled(rule, converted)
+				annotateSchedule(diag, rule, converted)
+				annotateProfiles(diag, rule, profiles, converted)
+
+				ruleSequence, ok := sequence(rule.SequenceNumber, rule.Priority)
+				sequenced = sequenced || ok
+				order := ruleOrder{category: category, policySequence: policySequence, ruleSequence: ruleSequence}
+				for _, policy := range converted {
+					order.policies = append(order.policies, len(policies))
+					policies = append(policies, policy)
+				}
+				if len(converted) > 0 {
+					orders = append(orders, order)
+				}
+			}
+		}
+	}
+	if sequenced {
+		annotatePriorities(diag, policies, orders)
+	}
+	if skipped > 0 && diag.verbosity >= verbosityWarnings {
+		log.Printf("Note: skipped %d disabled rules (use -include-disabled to convert them)", skipped)
+	}
+	return policies
+}
+
+// annotateDisabled marks the policies of a rule disabled in NSX, which
+// -include-disabled converts anyway
+func annotateDisabled(rule SecurityRule, policies []NetworkPolicy) {
+	if !rule.disabled() {
+		return
+	}
+	for i := range policies {
+		if policies[i].Metadata.Annotations == nil {
+			policies[i].Metadata.Annotations = map[string]string{}
+		}
+		policies[i].Metadata.Annotations[disabledAnnotation] = "true"
+	}
+}
+
+// convertRule translates an ALLOW rule into an ingress policy on its workload
+// destinations and an egress policy towards its IP-set destinations
+func convertRule(diag *diagnostics, rule SecurityRule, peers *peerBuilder, services map[string]Service, opts Options) []NetworkPolicy {
+	// Only ALLOW rules translate into NetworkPolicy allow-lists
+	if rule.Action != "ALLOW" {
+		if rule.Action == "DROP" || rule.Action == "REJECT" {
+			diag.warn(rule.DisplayName, warnUnsupportedAction, "rule %q has action %s, which NetworkPolicies cannot express as they only allow traffic, skipping it", rule.DisplayName, rule.Action)
+		}
+		return nil
+	}
+
+	ports, ok := rulePorts(diag, rule, services, opts)
+	if !ok {
+		return nil
+	}
+	sources := peers.resolve(rule, rule.SourceGroups)
+	destinations := peers.resolve(rule, rule.DestinationGroups)
+	if sources.empty() {
+		diag.warn(rule.DisplayName, warnNoSources, "rule %q has no resolvable source groups, skipping", rule.DisplayName)
+		return nil
+	}
+	if destinations.empty() {
+		// Unknown destination groups have already been warned about
+		return nil
+	}
+
+	// IN rules only restrict traffic arriving at the destinations and OUT
+	// rules only traffic leaving the sources. IN_OUT, the NSX default,
+	// restricts both ends.
+	direction := rule.Direction
+	if direction == "" {
+		direction = "IN_OUT"
+		diag.info(rule.DisplayName, infoDefaultDirection, "rule %q has no direction, using IN_OUT", rule.DisplayName)
+	}
+	ingressWanted := direction != "OUT"
+	egressWanted := direction != "IN"
+
+	var policies []NetworkPolicy
+	name := ruleName(diag, rule, opts.Naming)
+	namespaces := scopeSelector(diag, rule, opts)
+	// A rule applied to mapped VMs is enforced on the pods the VMs became
+	vms, vmScoped := vmSelectors(diag, rule, opts)
+
+	if ingressWanted && destinations.selectsWorkloads() {
+		selectors := podSelectors(destinations)
+		if vmScoped {
+			selectors = vms
+		}
+		for i, selector := range selectors {
+			policy := newPolicy(selectorName(name, i, len(selectors)), opts.Namespace)
+			policy.source = rule.DisplayName
+			policy.allPods = destinations.any && !vmScoped
+			policy.Spec.PodSelector = selector
+			policy.Spec.PolicyTypes = []string{"Ingress"}
+			policy.Spec.Ingress = append(policy.Spec.Ingress, Rule{From: rulePeers(sources, namespaces), Ports: ports})
+			if opts.PeerGroups {
+				annotatePeerGroups(&policy, sources)
+			}
+			policies = append(policies, policy)
+		}
+	}
+
+	// An egress policy needs source workloads to select. Restricting the
+	// egress of every pod for an ANY source would cut off all other traffic,
+	// so that is only done when IP set destinations leave no alternative.
+	if egressWanted && (len(sources.workloads) > 0 || len(sources.selectors) > 0 || len(sources.mapped) > 0 || sources.any && len(destinations.ipBlocks) > 0) {
+		selectors := podSelectors(sources)
+		if vmScoped {
+			selectors = vms
+		}
+		for i, selector := range selectors {
+			policy := newPolicy(selectorName(suffixName(name, "-egress"), i, len(selectors)), opts.Namespace)
+			policy.source = rule.DisplayName
+			policy.allPods = sources.any && !vmScoped
+			policy.Spec.PodSelector = selector
+			policy.Spec.PolicyTypes = []string{"Egress"}
+			policy.Spec.Egress = append(policy.Spec.Egress, Rule{To: rulePeers(destinations, namespaces), Ports: ports})
+			if opts.PeerGroups {
+				annotatePeerGroups(&policy, destinations)
+			}
+			policies = append(policies, policy)
+		}
+	}
+
+	if len(policies) == 0 {
+		diag.warn(rule.DisplayName, warnUnsupportedRule, "rule %q has no workloads to select for direction %s and cannot be expressed as a NetworkPolicy", rule.DisplayName, direction)
+	}
+	return policies
+}
+
+// rulePeers converts the peer side of a rule into NetworkPolicy peers. ANY
+// is no peers at all, or only the scope namespaces when the rule is scoped.
+func rulePeers(endpoints ruleEndpoints, namespaces *LabelSelector) []NetworkPolicyPeer {
+	switch {
+	case endpoints.any && namespaces != nil:
+		return []NetworkPolicyPeer{{NamespaceSelector: namespaces}}
+	case endpoints.any:
+		return nil
+	}
+	return append(workloadPeers(endpoints, namespaces), endpoints.ipBlocks...)
+}
+
+// newPolicy returns an empty NetworkPolicy with its type and metadata filled in
+func newPolicy(name, namespace string) NetworkPolicy {
+	return NetworkPolicy{
+		APIVersion: "networking.k8s.io/v1",
+		Kind:       "NetworkPolicy",
+		Metadata:   ObjectMeta{Name: name, Namespace: namespace},
+	}
+}
+
+// rulePorts resolves the services referenced by a rule, and the service
+// entries inlined in it, into NetworkPolicy ports. A nil slice with ok set
+// means the rule applies to all ports.
+func rulePorts(diag *diagnostics, rule SecurityRule, services map[string]Service, opts Options) (ports []PortRule, ok bool) {
+	if len(rule.Services) == 0 && len(rule.ServiceEntries) == 0 {
+		return nil, true
+	}
+	for _, ref := range rule.Services {
+		if ref == "ANY" {
+			return nil, true
+		}
+	}
+	// addPorts adds the ports of a service's entries that are not listed yet,
+	// as referenced services and inline entries may overlap
+	addPorts := func(service Service) {
+		for _, entry := range serviceEntries(diag, service, services, nil) {
+			protocols := entryProtocols(diag, service.DisplayName, entry)
+			if len(protocols) == 0 {
+				continue
+			}
+			for _, port := range entryPorts(diag, service.DisplayName, entry.DestinationPorts, protocols, opts) {
+				if !containsPort(ports, port) {
+					ports = append(ports, port)
+				}
+			}
+		}
+	}
+	for _, ref := range rule.Services {
+		service, found := services[ref]
+		if !found {
+			diag.warn(rule.DisplayName, warnUnknownService, "rule %q references unknown service %q", rule.DisplayName, ref)
+			continue
+		}
+		addPorts(service)
+	}
+	if len(rule.ServiceEntries) > 0 {
+		addPorts(Service{DisplayName: rule.DisplayName, ServiceEntries: rule.ServiceEntries})
+	}
+	if len(ports) == 0 {
+		diag.warn(rule.DisplayName, warnNoPorts, "rule %q has no convertible service ports, skipping", rule.DisplayName)
+		return nil, false
+	}
+	return ports, true
+}
+
+// podSelectors returns the podSelectors that select the given endpoints.
+// The workload groups share one selector, but a podSelector cannot OR the
+// selectors of condition groups, so each of them needs its own policy.
+func podSelectors(endpoints ruleEndpoints) []PodSelector {
+	if endpoints.any {
+		// An empty podSelector selects every pod in the namespace
+		return []PodSelector{{}}
+	}
+	var selectors []PodSelector
+	switch {
+	case len(endpoints.workloads) == 1:
+		selectors = append(selectors, PodSelector{MatchLabels: map[string]string{"app": endpoints.workloads[0]}})
+	case len(endpoints.workloads) > 1:
+		selectors = append(selectors, PodSelector{MatchExpressions: []LabelSelectorRequirement{
+			{Key: "app", Operator: "In", Values: endpoints.workloads},
+		}})
+	}
+	for _, selector := range endpoints.selectors {
+		selectors = append(selectors, PodSelector{MatchLabels: copyLabels(selector.MatchLabels), MatchExpressions: selector.MatchExpressions})
+	}
+	// A policy only selects pods of its own namespace, so the namespace
+	// labels of mapped groups only apply to peers
+	for _, labels := range endpoints.mapped {
+		selectors = append(selectors, PodSelector{MatchLabels: copyLabels(labels.Pod)})
+	}
+	return selectors
+}
+
+// selectorName numbers the policies of a rule that needs several
+// podSelectors, keeping the plain name when one is enough
+func selectorName(name string, i, count int) string {
+	if count == 1 {
+		return name
+	}
+	return suffixName(name, fmt.Sprintf("-%d", i+1))
+}
+
+// workloadPeers returns a podSelector peer for each workload group,
+// restricted to the given namespaces when a selector is set. Groups mapped
+// to namespace labels by -group-labels keep their own namespaces.
+func workloadPeers(endpoints ruleEndpoints, namespaces *LabelSelector) []NetworkPolicyPeer {
+	var peers []NetworkPolicyPeer
+	for _, workload := range endpoints.workloads {
+		peers = append(peers, NetworkPolicyPeer{
+			PodSelector:       &LabelSelector{MatchLabels: map[string]string{"app": workload}},
+			NamespaceSelector: namespaces,
+		})
+	}
+	for _, selector := range endpoints.selectors {
+		selector := selector
+		peers = append(peers, NetworkPolicyPeer{PodSelector: &selector, NamespaceSelector: namespaces})
+	}
+	for _, labels := range endpoints.mapped {
+		peers = append(peers, labels.peer(namespaces))
+	}
+	return peers
+}
+
+// effectiveScope returns the rule's scope, falling back to the scope of its
+// security policy when the rule applies everywhere
+func effectiveScope(policyScope, ruleScope []string) []string {
+	for _, scope := range ruleScope {
+		if scope != "ANY" {
+			return ruleScope
+		}
+	}
+	return policyScope
+}
+
+// scopeSelector maps a rule's scope onto a namespaceSelector using the
+// -scope-label mappings. It returns nil when the rule is not zone-scoped.
+// VMs in the scope are selected by vmSelectors instead.
+func scopeSelector(diag *diagnostics, rule SecurityRule, opts Options) *LabelSelector {
+	labels := map[string]string{}
+	for _, scope := range rule.Scope {
+		if scope == "ANY" || isVMScope(scope, opts.VMLabels) {
+			continue
+		}
+		mapped, found := opts.ScopeLabels[scope]
+		if !found {
+			mapped, found = opts.ScopeLabels[lastPathElement(scope)]
+		}
+		if !found {
+			diag.warn(rule.DisplayName, warnUnmappedScope, "rule %q is scoped to %q which has no -scope-label mapping", rule.DisplayName, scope)
+			continue
+		}
+		for key, value := range mapped {
+			labels[key] = value
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return &LabelSelector{MatchLabels: labels}
+}
+
+// ipBlockPeers converts the addresses, CIDRs and ranges of an IP-set group
+// into ipBlock peers, dropping those of the family the -ip-family excludes
+func ipBlockPeers(diag *diagnostics, groupName string, addresses []string, family string) []NetworkPolicyPeer {
+	var peers []NetworkPolicyPeer
+	for _, address := range addresses {
+		prefixes, err := parsePrefixes(address)
+		if err != nil {
+			diag.warn(groupName, warnInvalidIPAddress, "group %q has invalid IP address %q: %v", groupName, address, err)
+			continue
+		}
+		if !inFamily(prefixes[0].Addr(), family) {
+			diag.info(groupName, infoIPFamily, "group %q has address %q outside -ip-family %s, dropping it", groupName, address, family)
+			continue
+		}
+		if len(prefixes) > maxRangeCIDRs {
+			covering := coveringPrefix(prefixes[0].Addr(), lastAddr(prefixes[len(prefixes)-1]))
+			diag.warn(groupName, warnCoveringCIDR, "range %q in group %q spans %d CIDRs, using covering CIDR %s", address, groupName, len(prefixes), covering)
+			prefixes = []netip.Prefix{covering}
+		}
+		for _, prefix := range prefixes {
+			peers = append(peers, NetworkPolicyPeer{IPBlock: &IPBlock{CIDR: prefix.String()}})
+		}
+	}
+	return peers
+}
+
+// exceptPeers adds the excluded addresses of a group to the except list of
+// the ipBlock peer whose CIDR contains them. Kubernetes rejects an except
+// that is not strictly inside its cidr, so an excluded address outside
+// every CIDR is dropped.
+func exceptPeers(diag *diagnostics, groupName string, peers []NetworkPolicyPeer, excluded []string, family string) []NetworkPolicyPeer {
+	for _, address := range excluded {
+		prefixes, err := parsePrefixes(address)
+		if err != nil {
+			diag.warn(groupName, warnInvalidIPAddress, "group %q has invalid excluded address %q: %v", groupName, address, err)
+			continue
+		}
+		if !inFamily(prefixes[0].Addr(), family) {
+			diag.info(groupName, infoIPFamily, "group %q has excluded address %q outside -ip-family %s, dropping it", groupName, address, family)
+			continue
+		}
+		for _, prefix := range prefixes {
+			found := false
+			for _, peer := range peers {
+				cidr, err := netip.ParsePrefix(peer.IPBlock.CIDR)
+				if err == nil && cidr.Bits() < prefix.Bits() && cidr.Contains(prefix.Addr()) {
+					peer.IPBlock.Except = append(peer.IPBlock.Except, prefix.String())
+					found = true
+					break
+				}
+			}
+			if !found {
+				diag.warn(groupName, warnExceptOutsideCIDR, "group %q excludes %s, which is not strictly inside any of its CIDRs, dropping it", groupName, prefix)
+			}
+		}
+	}
+	return peers
+}
+
+// inFamily reports whether an address belongs to an -ip-family
+func inFamily(addr netip.Addr, family string) bool {
+	switch family {
+	case familyIPv4:
+		return addr.Is4()
+	case familyIPv6:
+		return addr.Is6()
+	}
+	return true
+}
+
+// parsePrefixes parses a single address, a CIDR or a start-end range into
+// the list of CIDRs that exactly covers it
+func parsePrefixes(address string) ([]netip.Prefix, error) {
+	address = strings.TrimSpace(address)
+	if start, end, isRange := strings.Cut(address, "-"); isRange {
+		startAddr, err := netip.ParseAddr(strings.TrimSpace(start))
+		if err != nil {
+			return nil, err
+		}
+		endAddr, err := netip.ParseAddr(strings.TrimSpace(end))
+		if err != nil {
+			return nil, err
+		}
+		if startAddr.BitLen() != endAddr.BitLen() || startAddr.Compare(endAddr) > 0 {
+			return nil, fmt.Errorf("invalid range")
+		}
+		return rangeToPrefixes(startAddr, endAddr), nil
+	}
+	if strings.Contains(address, "/") {
+		prefix, err := netip.ParsePrefix(address)
+		if err != nil {
+			return nil, err
+		}
+		return []netip.Prefix{prefix.Masked()}, nil
+	}
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return nil, err
+	}
+	return []netip.Prefix{netip.PrefixFrom(addr, addr.BitLen())}, nil
+}
+
+// rangeToPrefixes splits an inclusive address range into the minimal list of
+// CIDRs that covers exactly that range
+func rangeToPrefixes(start, end netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for start.IsValid() && start.Compare(end) <= 0 {
+		bits := start.BitLen()
+		// Widen the block while it stays aligned and inside the range
+		for bits > 0 {
+			wider := netip.PrefixFrom(start, bits-1).Masked()
+			if wider.Addr() != start || lastAddr(wider).Compare(end) > 0 {
+				break
+			}
+			bits--
+		}
+		prefix := netip.PrefixFrom(start, bits)
+		prefixes = append(prefixes, prefix)
+		start = lastAddr(prefix).Next()
+	}
+	return prefixes
+}
+
+// coveringPrefix returns the smallest CIDR containing both addresses
+func coveringPrefix(start, end netip.Addr) netip.Prefix {
+	for bits := start.BitLen(); bits > 0; bits-- {
+		prefix := netip.PrefixFrom(start, bits).Masked()
+		if prefix.Contains(end) {
+			return prefix
+		}
+	}
+	return netip.PrefixFrom(start, 0).Masked()
+}
+
+// lastAddr returns the highest address inside a CIDR
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(bytes)*8; i++ {
+		bytes[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// lastPathElement returns the final segment of an NSX policy path
+func lastPathElement(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
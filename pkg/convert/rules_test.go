@@ -0,0 +1,219 @@
+package convert
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// peerCIDRs returns the CIDRs of ipBlock peers
+func peerCIDRs(peers []NetworkPolicyPeer) []string {
+	cidrs := []string{}
+	for _, peer := range peers {
+		cidrs = append(cidrs, peer.IPBlock.CIDR)
+	}
+	return cidrs
+}
+
+func TestIPBlockPeersFamily(t *testing.T) {
+	addresses := []string{"10.0.0.1", "fd00::/64", "192.168.1.0/24", "2001:db8::1"}
+	tests := []struct {
+		family  string
+		want    []string
+		dropped int
+	}{
+		{familyDual, []string{"10.0.0.1/32", "fd00::/64", "192.168.1.0/24", "2001:db8::1/128"}, 0},
+		{familyIPv4, []string{"10.0.0.1/32", "192.168.1.0/24"}, 2},
+		{familyIPv6, []string{"fd00::/64", "2001:db8::1/128"}, 2},
+	}
+	for _, tt := range tests {
+		diag := newDiagnostics(verbosityQuiet)
+		peers := ipBlockPeers(diag, "mixed", addresses, tt.family)
+		if got := peerCIDRs(peers); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("-ip-family %s: peers = %v, want %v", tt.family, got, tt.want)
+		}
+		if len(diag.infos) != tt.dropped || len(diag.warnings) != 0 {
+			t.Errorf("-ip-family %s: infos = %+v, warnings = %+v, want %d dropped addresses", tt.family, diag.infos, diag.warnings, tt.dropped)
+		}
+		for _, info := range diag.infos {
+			if info.Type != infoIPFamily {
+				t.Errorf("-ip-family %s: info %+v is not an ip-family info", tt.family, info)
+			}
+		}
+	}
+}
+
+func TestExceptPeersFamily(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	peers := ipBlockPeers(diag, "mixed", []string{"10.0.0.0/8"}, familyIPv4)
+	peers = exceptPeers(diag, "mixed", peers, []string{"10.1.0.0/16", "fd00::1"}, familyIPv4)
+	if len(peers) != 1 || !reflect.DeepEqual(peers[0].IPBlock.Except, []string{"10.1.0.0/16"}) {
+		t.Errorf("peers = %+v, want 10.0.0.0/8 except 10.1.0.0/16", peers)
+	}
+	if len(diag.infos) != 1 || diag.infos[0].Type != infoIPFamily {
+		t.Errorf("infos = %+v, want the IPv6 exception dropped", diag.infos)
+	}
+}
+
+func TestExceptPeers(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	peers := ipBlockPeers(diag, "corp-networks", []string{"10.0.0.0/8"}, familyDual)
+	peers = exceptPeers(diag, "corp-networks", peers, []string{"10.20.0.0/16", "192.168.0.0/24", "10.0.0.0/8"}, familyDual)
+	want := []NetworkPolicyPeer{{IPBlock: &IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.20.0.0/16"}}}}
+	if !reflect.DeepEqual(peers, want) {
+		t.Errorf("peers = %+v, want %+v", peers, want)
+	}
+	wantWarnings := []Warning{
+		{Service: "corp-networks", Type: warnExceptOutsideCIDR, Message: `group "corp-networks" excludes 192.168.0.0/24, which is not strictly inside any of its CIDRs, dropping it`},
+		{Service: "corp-networks", Type: warnExceptOutsideCIDR, Message: `group "corp-networks" excludes 10.0.0.0/8, which is not strictly inside any of its CIDRs, dropping it`},
+	}
+	if !reflect.DeepEqual(diag.warnings, wantWarnings) {
+		t.Errorf("warnings = %+v, want %+v", diag.warnings, wantWarnings)
+	}
+}
+
+func TestRangeToPrefixes(t *testing.T) {
+	tests := []struct {
+		start, end string
+		want       []string
+	}{
+		{"10.0.0.5", "10.0.0.5", []string{"10.0.0.5/32"}},
+		{"10.0.0.0", "10.0.0.255", []string{"10.0.0.0/24"}},
+		{"172.16.0.10", "172.16.0.17", []string{"172.16.0.10/31", "172.16.0.12/30", "172.16.0.16/31"}},
+		{"10.0.0.255", "10.0.1.0", []string{"10.0.0.255/32", "10.0.1.0/32"}},
+		{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+		{"fd00::", "fd00::3", []string{"fd00::/126"}},
+		{"fd00::1", "fd00::4", []string{"fd00::1/128", "fd00::2/127", "fd00::4/128"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, prefix := range rangeToPrefixes(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end)) {
+			got = append(got, prefix.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s-%s: prefixes = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestCoveringPrefix(t *testing.T) {
+	tests := []struct {
+		start, end, want string
+	}{
+		{"10.0.1.1", "10.0.1.254", "10.0.1.0/24"},
+		{"10.0.0.255", "10.0.1.0", "10.0.0.0/23"},
+		{"10.0.0.5", "10.0.0.5", "10.0.0.5/32"},
+		{"1.0.0.0", "200.0.0.0", "0.0.0.0/0"},
+		{"fd00::1", "fd00::ff", "fd00::/120"},
+	}
+	for _, tt := range tests {
+		if got := coveringPrefix(netip.MustParseAddr(tt.start), netip.MustParseAddr(tt.end)); got.String() != tt.want {
+			t.Errorf("%s-%s: covering prefix = %s, want %s", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestIPBlockPeersCoveringCIDR(t *testing.T) {
+	tests := []struct {
+		address string
+		want    []string
+		warning string
+	}{
+		// 8 CIDRs is still split exactly
+		{"10.0.0.1-10.0.0.30", []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/30", "10.0.0.8/29", "10.0.0.16/29", "10.0.0.24/30", "10.0.0.28/31", "10.0.0.30/32"}, ""},
+		{"10.0.1.1-10.0.1.254", []string{"10.0.1.0/24"}, `range "10.0.1.1-10.0.1.254" in group "dns-servers" spans 14 CIDRs, using covering CIDR 10.0.1.0/24`},
+	}
+	for _, tt := range tests {
+		diag := newDiagnostics(verbosityQuiet)
+		peers := ipBlockPeers(diag, "dns-servers", []string{tt.address}, familyDual)
+		if got := peerCIDRs(peers); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: peers = %v, want %v", tt.address, got, tt.want)
+		}
+		want := []Warning{}
+		if tt.warning != "" {
+			want = []Warning{{Service: "dns-servers", Type: warnCoveringCIDR, Message: tt.warning}}
+		}
+		if !reflect.DeepEqual(diag.warnings, want) {
+			t.Errorf("%s: warnings = %+v, want %+v", tt.address, diag.warnings, want)
+		}
+	}
+}
+
+func TestRuleDirections(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	policies := rulePolicies(diag, readExport(t, "rule-directions.json"), testOptions())
+	tests := []struct {
+		direction string
+		policy    string
+		selected  string
+		ingress   bool
+		peer      NetworkPolicyPeer
+	}{
+		{"IN_OUT", "app-to-db", "db", true, NetworkPolicyPeer{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "app"}}}},
+		{"IN_OUT", "app-to-db-egress", "app", false, NetworkPolicyPeer{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "db"}}}},
+		{"IN", "reporting-to-db", "db", true, NetworkPolicyPeer{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "reporting"}}}},
+		{"OUT", "app-to-managed-db-egress", "app", false, NetworkPolicyPeer{IPBlock: &IPBlock{CIDR: "10.20.0.0/24"}}},
+	}
+	if len(policies) != len(tests) {
+		t.Fatalf("got %d policies, want %d: %+v", len(policies), len(tests), policies)
+	}
+	wantPorts := testPorts(t, "TCP", "5432")
+	for _, tt := range tests {
+		policy := findPolicy(t, policies, tt.policy)
+		if got := policy.Spec.PodSelector.MatchLabels["app"]; got != tt.selected {
+			t.Errorf("%s: %s selects app %q, want %q", tt.direction, tt.policy, got, tt.selected)
+		}
+		rules, other, policyType := policy.Spec.Egress, policy.Spec.Ingress, "Egress"
+		if tt.ingress {
+			rules, other, policyType = policy.Spec.Ingress, policy.Spec.Egress, "Ingress"
+		}
+		if !reflect.DeepEqual(policy.Spec.PolicyTypes, []string{policyType}) || len(rules) != 1 || len(other) != 0 {
+			t.Errorf("%s: %s = %+v, want a single %s rule", tt.direction, tt.policy, policy.Spec, policyType)
+			continue
+		}
+		peers := rules[0].From
+		if !tt.ingress {
+			peers = rules[0].To
+		}
+		if !reflect.DeepEqual(peers, []NetworkPolicyPeer{tt.peer}) || !reflect.DeepEqual(rules[0].Ports, wantPorts) {
+			t.Errorf("%s: %s rule = %+v, want peer %+v on port 5432", tt.direction, tt.policy, rules[0], tt.peer)
+		}
+	}
+}
+
+func TestExternalEgress(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	policies := rulePolicies(diag, readExport(t, "external-egress.json"), testOptions())
+	policy := findPolicy(t, policies, "payments-to-card-processor-egress")
+	egress := policy.Spec.Egress
+	if len(egress) != 1 || len(policy.Spec.Ingress) != 0 {
+		t.Fatalf("rules = %+v, want a single egress rule", policy.Spec)
+	}
+	if got, want := peerCIDRs(egress[0].To), []string{"203.0.113.0/24", "198.51.100.7/32"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("to = %v, want %v", got, want)
+	}
+	if got, want := egress[0].Ports, testPorts(t, "TCP", "443"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ports = %+v, want %+v", got, want)
+	}
+
+	// The peers and the ports render in the same rule, so only port 443 is
+	// allowed to the external CIDRs
+	data, err := marshalYAML(renderPolicy(policy, kindNetworkPolicy, defaultRender), defaultYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `  egress:
+  - to:
+    - ipBlock:
+        cidr: 203.0.113.0/24
+    - ipBlock:
+        cidr: 198.51.100.7/32
+    ports:
+    - port: 443
+      protocol: TCP
+`
+	if !strings.HasSuffix(string(data), want) {
+		t.Errorf("rendered policy does not end with the egress rule\n%s\nwant suffix\n%s", data, want)
+	}
+}
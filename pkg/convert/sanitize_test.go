@@ -0,0 +1,49 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	tests := []struct {
+		name string
+		in   string
+		opts NameOptions
+		want string
+	}{
+		{"spaces", "Web Service", NameOptions{Replacement: "-"}, "web-service"},
+		{"run of invalid characters", "IMAP  (SSL)", NameOptions{Replacement: "-"}, "imap-ssl"},
+		{"dashes kept without collapse", "a - b", NameOptions{Replacement: "-"}, "a---b"},
+		{"dashes collapsed", "a - b", NameOptions{Replacement: "-", Collapse: true}, "a-b"},
+		{"longer replacement collapsed", "a_x-_b", NameOptions{Replacement: "x-", Collapse: true}, "ax-b"},
+		{"longer replacement kept without collapse", "a_x-_b", NameOptions{Replacement: "x-"}, "ax-x-x-b"},
+		{"repeated replacement collapsed", "a__b", NameOptions{Replacement: "x", Collapse: true}, "axb"},
+		{"replacement runs collapsed", "a_b", NameOptions{Replacement: "0", Collapse: true}, "a0b"},
+		{"empty replacement", "Web Service", NameOptions{Collapse: true}, "webservice"},
+		{"leading and trailing dashes", "--web--", NameOptions{Replacement: "-"}, "web"},
+		{"only invalid characters", "__", NameOptions{Replacement: "-"}, ""},
+		{"transliterated", "Café Svc", NameOptions{Replacement: "-", Transliterate: true}, "cafe-svc"},
+		{"truncated", long, NameOptions{Replacement: "-"}, long[:maxNameLength]},
+		{"exactly the limit", long[:maxNameLength], NameOptions{Replacement: "-"}, long[:maxNameLength]},
+		{"trailing dash after truncation", long[:62] + " b", NameOptions{Replacement: "-"}, long[:62]},
+		{"collapsed before truncation", long[:60] + " - b", NameOptions{Replacement: "-", Collapse: true}, long[:60] + "-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeName(tt.in, tt.opts); got != tt.want {
+				t.Errorf("sanitizeName(%q, %+v) = %q, want %q", tt.in, tt.opts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepeatedReplacementIsCached(t *testing.T) {
+	if repeatedReplacement("-") != repeatedDashes {
+		t.Error("the default replacement does not use the precompiled regexp")
+	}
+	if repeatedReplacement("x-") != repeatedReplacement("x-") {
+		t.Error("the regexp of a replacement is compiled again")
+	}
+}
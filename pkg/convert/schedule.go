@@ -0,0 +1,57 @@
+package convert
+
+import "strings"
+
+// scheduleAnnotation records the NSX schedule of a time-based rule on the
+// policies generated from it
+const scheduleAnnotation = "nsx.vmware.com/schedule"
+
+// RuleSchedule is the time window of an NSX time-based firewall rule
+type RuleSchedule struct {
+	DisplayName string   `json:"display_name"`
+	StartDate   string   `json:"start_date"`
+	EndDate     string   `json:"end_date"`
+	StartTime   string   `json:"start_time"`
+	EndTime     string   `json:"end_time"`
+	TimeZone    string   `json:"time_zone"`
+	Recurring   bool     `json:"recurring"`
+	Days        []string `json:"days"`
+}
+
+// String describes the schedule window, e.g.
+// "MONDAY,FRIDAY 08:00-18:00 UTC from 2024-01-01 until 2024-12-31"
+func (s RuleSchedule) String() string {
+	var parts []string
+	if s.Recurring && len(s.Days) > 0 {
+		parts = append(parts, strings.Join(s.Days, ","))
+	}
+	if s.StartTime != "" || s.EndTime != "" {
+		parts = append(parts, s.StartTime+"-"+s.EndTime)
+	}
+	if s.TimeZone != "" {
+		parts = append(parts, s.TimeZone)
+	}
+	if s.StartDate != "" {
+		parts = append(parts, "from "+s.StartDate)
+	}
+	if s.EndDate != "" {
+		parts = append(parts, "until "+s.EndDate)
+	}
+	return strings.Join(parts, " ")
+}
+
+// annotateSchedule documents the schedule of a time-based rule on its policies
+// and warns that Kubernetes enforces them at all times
+func annotateSchedule(diag *diagnostics, rule SecurityRule, policies []NetworkPolicy) {
+	if rule.Schedule == nil || len(policies) == 0 {
+		return
+	}
+	window := rule.Schedule.String()
+	diag.warn(rule.DisplayName, warnScheduleIgnored, "rule %q only applies during %q, but NetworkPolicies cannot be scheduled and apply at all times", rule.DisplayName, window)
+	for i := range policies {
+		if policies[i].Metadata.Annotations == nil {
+			policies[i].Metadata.Annotations = map[string]string{}
+		}
+		policies[i].Metadata.Annotations[scheduleAnnotation] = window
+	}
+}
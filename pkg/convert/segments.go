@@ -0,0 +1,45 @@
+package convert
+
+// Segment is an NSX overlay or VLAN segment. Rules may reference a segment
+// by path as a source or destination, which stands for its subnets.
+type Segment struct {
+	DisplayName string          `json:"display_name"`
+	Path        string          `json:"path"`
+	Subnets     []SegmentSubnet `json:"subnets"`
+}
+
+// SegmentSubnet is a subnet of a segment. The gateway address is given in
+// CIDR notation, e.g. 10.0.1.1/24, and network only by some NSX versions.
+type SegmentSubnet struct {
+	GatewayAddress string `json:"gateway_address"`
+	Network        string `json:"network"`
+}
+
+// cidrs returns the CIDR of each subnet of the segment
+func (s Segment) cidrs() []string {
+	var cidrs []string
+	for _, subnet := range s.Subnets {
+		switch {
+		case subnet.Network != "":
+			cidrs = append(cidrs, subnet.Network)
+		case subnet.GatewayAddress != "":
+			cidrs = append(cidrs, subnet.GatewayAddress)
+		}
+	}
+	return cidrs
+}
+
+// segmentGroups returns a group for each segment of the export, holding its
+// subnets as IP addresses, so rules can reference segments like IP sets
+func segmentGroups(root Root) []Group {
+	var groups []Group
+	for _, segment := range root.Segments {
+		groups = append(groups, Group{
+			DisplayName: segment.DisplayName,
+			Path:        segment.Path,
+			IPAddresses: segment.cidrs(),
+			segment:     true,
+		})
+	}
+	return groups
+}
@@ -0,0 +1,313 @@
+package convert
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	labelNamePattern   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelValuePattern  = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+	labelPrefixPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// validateLabel checks a label key and value against the Kubernetes label syntax
+func validateLabel(key, value string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if len(prefix) > 253 || !labelPrefixPattern.MatchString(prefix) {
+			return fmt.Errorf("label key %q has an invalid prefix", key)
+		}
+		name = rest
+	}
+	if !labelNamePattern.MatchString(name) {
+		return fmt.Errorf("label key %q is invalid", key)
+	}
+	if !labelValuePattern.MatchString(value) {
+		return fmt.Errorf("label value %q for key %q is invalid", value, key)
+	}
+	return nil
+}
+
+// parseLabels parses key=value pairs into a label map
+func parseLabels(values []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, value := range values {
+		key, labelValue, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not in key=value form", value)
+		}
+		if err := validateLabel(key, labelValue); err != nil {
+			return nil, err
+		}
+		labels[key] = labelValue
+	}
+	return labels, nil
+}
+
+// applyGlobalSelector replaces the podSelector of every policy with the given labels
+func applyGlobalSelector(policies []NetworkPolicy, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	for i := range policies {
+		policies[i].Spec.PodSelector.MatchLabels = copyLabels(labels)
+		policies[i].Spec.PodSelector.MatchExpressions = nil
+	}
+}
+
+// loadServiceLabels reads a YAML map from service display name to the extra
+// labels added to the podSelector of that service's policy
+func loadServiceLabels(path string) (map[string]map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var labels map[string]map[string]string
+	if err := yaml.UnmarshalStrict(data, &labels); err != nil {
+		return nil, err
+	}
+	for service, serviceLabels := range labels {
+		for key, value := range serviceLabels {
+			if err := validateLabel(key, value); err != nil {
+				return nil, fmt.Errorf("service %q: %v", service, err)
+			}
+		}
+	}
+	return labels, nil
+}
+
+// checkServiceNames warns about the entries of a per-service file, given by
+// flag, whose display name matches no service of the export
+func checkServiceNames(diag *diagnostics, root Root, flag string, names []string) {
+	known := map[string]bool{}
+	for _, service := range root.Services {
+		known[service.DisplayName] = true
+	}
+	var unknown []string
+	for _, service := range names {
+		if !known[service] {
+			unknown = append(unknown, service)
+		}
+	}
+	sort.Strings(unknown)
+	for _, service := range unknown {
+		diag.warn(service, warnUnknownService, "%s lists service %q which is not in the export", flag, service)
+	}
+}
+
+// parseTagLabels parses scope or scope=key mappings from NSX tag scopes to
+// label keys. A bare scope is used as the label key.
+func parseTagLabels(values []string) (map[string]string, error) {
+	scopes := map[string]string{}
+	for _, value := range values {
+		scope, key, ok := strings.Cut(value, "=")
+		if !ok {
+			key = scope
+		}
+		if scope == "" {
+			return nil, fmt.Errorf("%q has an empty scope", value)
+		}
+		if err := validateLabel(key, ""); err != nil {
+			return nil, err
+		}
+		scopes[scope] = key
+	}
+	return scopes, nil
+}
+
+// tagLabels returns the labels for the tags of a service whose scope is
+// mapped. The first tag of a scope wins, and invalid values are skipped.
+func tagLabels(diag *diagnostics, service Service, scopes map[string]string) map[string]string {
+	var labels map[string]string
+	for _, tag := range service.Tags {
+		key, ok := scopes[tag.Scope]
+		if !ok {
+			continue
+		}
+		if existing, found := labels[key]; found {
+			if existing != tag.Tag {
+				diag.warn(service.DisplayName, warnInvalidLabel, "service %q has several tags with scope %q, keeping %q", service.DisplayName, tag.Scope, existing)
+			}
+			continue
+		}
+		if err := validateLabel(key, tag.Tag); err != nil {
+			diag.warn(service.DisplayName, warnInvalidLabel, "service %q tag %q: %v, skipping it", service.DisplayName, tag.Scope, err)
+			continue
+		}
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = tag.Tag
+	}
+	return labels
+}
+
+// parseLabelPrefix validates a -label-prefix and returns it with its
+// trailing slash
+func parseLabelPrefix(prefix string) (string, error) {
+	if prefix == "" {
+		return "", nil
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	if err := validateLabel(prefix+"app", ""); err != nil {
+		return "", fmt.Errorf("%q is not a valid label prefix", strings.TrimSuffix(prefix, "/"))
+	}
+	return prefix, nil
+}
+
+// prefixLabels moves the selector and metadata label keys of every policy
+// under the prefix. Namespace selectors are left alone, since they match
+// labels of namespaces, and keys that already have a prefix are kept. So are
+// the selectors mapped by -vm-labels or -group-labels, which hold the labels
+// of existing pods.
+func prefixLabels(policies []NetworkPolicy, prefix string, opts Options) {
+	if prefix == "" {
+		return
+	}
+	mapped := mappedLabels(opts)
+	for i := range policies {
+		policy := &policies[i]
+		policy.Metadata.Labels = prefixKeys(policy.Metadata.Labels, prefix)
+		if !isMappedSelector(policy.Spec.PodSelector.MatchLabels, policy.Spec.PodSelector.MatchExpressions, mapped) {
+			policy.Spec.PodSelector.MatchLabels = prefixKeys(policy.Spec.PodSelector.MatchLabels, prefix)
+			policy.Spec.PodSelector.MatchExpressions = prefixExpressions(policy.Spec.PodSelector.MatchExpressions, prefix)
+		}
+		for j := range policy.Spec.Ingress {
+			policy.Spec.Ingress[j].From = prefixPeers(policy.Spec.Ingress[j].From, prefix, mapped)
+		}
+		for j := range policy.Spec.Egress {
+			policy.Spec.Egress[j].To = prefixPeers(policy.Spec.Egress[j].To, prefix, mapped)
+		}
+	}
+}
+
+// mappedLabels returns the pod labels of the -vm-labels and -group-labels
+// mappings
+func mappedLabels(opts Options) []map[string]string {
+	var mapped []map[string]string
+	for _, labels := range opts.VMLabels {
+		mapped = append(mapped, labels)
+	}
+	for _, labels := range opts.GroupLabels {
+		mapped = append(mapped, labels.Pod)
+	}
+	return mapped
+}
+
+// isMappedSelector reports whether a selector matches exactly the labels of
+// a mapping, as the selectors built from the mappings do
+func isMappedSelector(labels map[string]string, exprs []LabelSelectorRequirement, mapped []map[string]string) bool {
+	if len(labels) == 0 || len(exprs) > 0 {
+		return false
+	}
+	for _, m := range mapped {
+		if reflect.DeepEqual(labels, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixPeers returns a copy of peers with their pod selector keys prefixed,
+// except for the mapped ones
+func prefixPeers(peers []NetworkPolicyPeer, prefix string, mapped []map[string]string) []NetworkPolicyPeer {
+	if len(peers) == 0 {
+		return peers
+	}
+	prefixed := make([]NetworkPolicyPeer, 0, len(peers))
+	for _, peer := range peers {
+		if peer.PodSelector != nil && !isMappedSelector(peer.PodSelector.MatchLabels, peer.PodSelector.MatchExpressions, mapped) {
+			peer.PodSelector = &LabelSelector{
+				MatchLabels:      prefixKeys(peer.PodSelector.MatchLabels, prefix),
+				MatchExpressions: prefixExpressions(peer.PodSelector.MatchExpressions, prefix),
+			}
+		}
+		prefixed = append(prefixed, peer)
+	}
+	return prefixed
+}
+
+// prefixKeys returns a copy of labels with each unprefixed key prefixed
+func prefixKeys(labels map[string]string, prefix string) map[string]string {
+	if labels == nil {
+		return nil
+	}
+	prefixed := make(map[string]string, len(labels))
+	for key, value := range labels {
+		prefixed[prefixKey(key, prefix)] = value
+	}
+	return prefixed
+}
+
+// prefixExpressions returns a copy of exprs with each unprefixed key prefixed
+func prefixExpressions(exprs []LabelSelectorRequirement, prefix string) []LabelSelectorRequirement {
+	if exprs == nil {
+		return nil
+	}
+	prefixed := make([]LabelSelectorRequirement, 0, len(exprs))
+	for _, expr := range exprs {
+		expr.Key = prefixKey(expr.Key, prefix)
+		prefixed = append(prefixed, expr)
+	}
+	return prefixed
+}
+
+// prefixKey prefixes a label key unless it already has a prefix
+func prefixKey(key, prefix string) string {
+	if strings.Contains(key, "/") {
+		return key
+	}
+	return prefix + key
+}
+
+// applyNamespaceSelector restricts every rule to peers in namespaces with the
+// given labels. A rule without peers gets a namespace-only peer, and pod peers
+// get a namespaceSelector. A peer that already has a namespaceSelector keeps
+// its labels, and the global labels are added unless their key is set.
+// ipBlock peers cannot be combined with selectors and are left unchanged.
+func applyNamespaceSelector(policies []NetworkPolicy, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	for i := range policies {
+		for j := range policies[i].Spec.Ingress {
+			policies[i].Spec.Ingress[j].From = scopePeers(policies[i].Spec.Ingress[j].From, labels)
+		}
+		for j := range policies[i].Spec.Egress {
+			policies[i].Spec.Egress[j].To = scopePeers(policies[i].Spec.Egress[j].To, labels)
+		}
+	}
+}
+
+// scopePeers returns a copy of peers with the namespace labels applied
+func scopePeers(peers []NetworkPolicyPeer, labels map[string]string) []NetworkPolicyPeer {
+	if len(peers) == 0 {
+		return []NetworkPolicyPeer{{NamespaceSelector: &LabelSelector{MatchLabels: copyLabels(labels)}}}
+	}
+	scoped := make([]NetworkPolicyPeer, 0, len(peers))
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			scoped = append(scoped, peer)
+			continue
+		}
+		namespaces := &LabelSelector{MatchLabels: copyLabels(labels)}
+		if peer.NamespaceSelector != nil {
+			for key, value := range peer.NamespaceSelector.MatchLabels {
+				namespaces.MatchLabels[key] = value
+			}
+			namespaces.MatchExpressions = peer.NamespaceSelector.MatchExpressions
+		}
+		peer.NamespaceSelector = namespaces
+		scoped = append(scoped, peer)
+	}
+	return scoped
+}
@@ -0,0 +1,180 @@
+package convert
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := parseLabels([]string{"tier=web", "app.kubernetes.io/part-of=shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"tier": "web", "app.kubernetes.io/part-of": "shop"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("parseLabels = %v, want %v", labels, want)
+	}
+	for _, value := range []string{"tier", "tier=not valid", "-bad=web"} {
+		if _, err := parseLabels([]string{value}); err == nil {
+			t.Errorf("parseLabels accepted %q", value)
+		}
+	}
+}
+
+func TestGlobalSelector(t *testing.T) {
+	selector := map[string]string{"tier": "web"}
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	applyGlobalSelector(policies, selector)
+	for _, policy := range policies {
+		if !reflect.DeepEqual(policy.Spec.PodSelector.MatchLabels, selector) || policy.Spec.PodSelector.MatchExpressions != nil {
+			t.Errorf("policy %s selects %+v, want %v", policy.Metadata.Name, policy.Spec.PodSelector, selector)
+		}
+	}
+	policies[0].Spec.PodSelector.MatchLabels["tier"] = "changed"
+	if policies[1].Spec.PodSelector.MatchLabels["tier"] != "web" {
+		t.Error("the policies share their selector labels")
+	}
+}
+
+func TestNamespaceSelector(t *testing.T) {
+	selector := map[string]string{"team": "shop"}
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	applyNamespaceSelector(policies, selector)
+	want := []NetworkPolicyPeer{{NamespaceSelector: &LabelSelector{MatchLabels: selector}}}
+	for _, policy := range policies {
+		for _, rule := range policy.Spec.Ingress {
+			if !reflect.DeepEqual(rule.From, want) {
+				t.Errorf("ingress of %s is from %+v, want %+v", policy.Metadata.Name, rule.From, want)
+			}
+		}
+		for _, rule := range policy.Spec.Egress {
+			if !reflect.DeepEqual(rule.To, want) {
+				t.Errorf("egress of %s is to %+v, want %+v", policy.Metadata.Name, rule.To, want)
+			}
+		}
+	}
+}
+
+func TestScopePeers(t *testing.T) {
+	labels := map[string]string{"team": "shop"}
+	pods := &LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	block := &IPBlock{CIDR: "10.0.0.0/8"}
+	peers := scopePeers([]NetworkPolicyPeer{
+		{PodSelector: pods},
+		{PodSelector: pods, NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{"zone": "dmz"}}},
+		{IPBlock: block},
+	}, labels)
+	want := []NetworkPolicyPeer{
+		{PodSelector: pods, NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{"team": "shop"}}},
+		{PodSelector: pods, NamespaceSelector: &LabelSelector{MatchLabels: map[string]string{"team": "shop", "zone": "dmz"}}},
+		{IPBlock: block},
+	}
+	if !reflect.DeepEqual(peers, want) {
+		t.Errorf("scopePeers = %+v, want %+v", peers, want)
+	}
+}
+
+func TestServiceLabels(t *testing.T) {
+	labels, err := loadServiceLabels(filepath.Join(repoRoot, "json/service-labels.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions()
+	opts.ServiceLabels = map[string]map[string]string{"db": labels["Microsoft SQL Server"], "missing": {"tier": "cache"}}
+	policies, diag := convertExport(t, directionsExport, Pipeline{Options: opts})
+	want := map[string]string{"app": "db", "tier": "database", "instance": "primary"}
+	if got := findPolicy(t, policies, "db").Spec.PodSelector.MatchLabels; !reflect.DeepEqual(got, want) {
+		t.Errorf("selector of db = %v, want %v", got, want)
+	}
+	if got := findPolicy(t, policies, "web").Spec.PodSelector.MatchLabels; !reflect.DeepEqual(got, map[string]string{"app": "web"}) {
+		t.Errorf("selector of web = %v, want only its app label", got)
+	}
+	if len(diag.warnings) != 1 || diag.warnings[0].Type != warnUnknownService || diag.warnings[0].Service != "missing" {
+		t.Errorf("warnings = %+v, want unknown-service for missing", diag.warnings)
+	}
+}
+
+func TestLoadServiceLabelsRejectsInvalidLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.yaml")
+	if err := ioutil.WriteFile(path, []byte("db:\n  tier: not valid\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadServiceLabels(path); err == nil {
+		t.Error("loadServiceLabels accepted an invalid label value")
+	}
+}
+
+func TestParseLabelPrefix(t *testing.T) {
+	for _, value := range []string{"nsx.example.com", "nsx.example.com/"} {
+		if prefix, err := parseLabelPrefix(value); err != nil || prefix != "nsx.example.com/" {
+			t.Errorf("parseLabelPrefix(%q) = %q, %v, want nsx.example.com/", value, prefix, err)
+		}
+	}
+	for _, value := range []string{"NSX.example.com", "nsx_example", "-nsx.example.com"} {
+		if _, err := parseLabelPrefix(value); err == nil {
+			t.Errorf("parseLabelPrefix accepted %q", value)
+		}
+	}
+}
+
+func TestPrefixLabels(t *testing.T) {
+	const prefix = "nsx.example.com/"
+	namespaces := &LabelSelector{MatchLabels: map[string]string{"team": "shop"}}
+	policy := newPolicy("web", "default")
+	policy.Metadata.Labels = map[string]string{"env": "prod", "app.kubernetes.io/part-of": "shop"}
+	policy.Spec.PodSelector = PodSelector{MatchLabels: map[string]string{"app": "web"}, MatchExpressions: []LabelSelectorRequirement{{Key: "tier", Operator: "Exists"}}}
+	policy.Spec.Ingress = []Rule{{From: []NetworkPolicyPeer{{PodSelector: &LabelSelector{MatchLabels: map[string]string{"app": "client"}}, NamespaceSelector: namespaces}}}}
+	policy.Spec.Egress = []Rule{{To: []NetworkPolicyPeer{{PodSelector: &LabelSelector{MatchExpressions: []LabelSelectorRequirement{{Key: "app", Operator: "In", Values: []string{"db"}}}}}}}}
+	original := policy.Spec.Ingress[0].From[0].PodSelector
+	policies := []NetworkPolicy{policy}
+	prefixLabels(policies, prefix, Options{})
+
+	got := policies[0]
+	if want := map[string]string{prefix + "env": "prod", "app.kubernetes.io/part-of": "shop"}; !reflect.DeepEqual(got.Metadata.Labels, want) {
+		t.Errorf("metadata labels = %v, want %v", got.Metadata.Labels, want)
+	}
+	wantSelector := PodSelector{MatchLabels: map[string]string{prefix + "app": "web"}, MatchExpressions: []LabelSelectorRequirement{{Key: prefix + "tier", Operator: "Exists"}}}
+	if !reflect.DeepEqual(got.Spec.PodSelector, wantSelector) {
+		t.Errorf("podSelector = %+v, want %+v", got.Spec.PodSelector, wantSelector)
+	}
+	from := got.Spec.Ingress[0].From[0]
+	if !reflect.DeepEqual(from.PodSelector.MatchLabels, map[string]string{prefix + "app": "client"}) || from.NamespaceSelector != namespaces {
+		t.Errorf("ingress peer = %+v, want a prefixed podSelector and the namespaceSelector unchanged", from)
+	}
+	if key := got.Spec.Egress[0].To[0].PodSelector.MatchExpressions[0].Key; key != prefix+"app" {
+		t.Errorf("egress peer expression key = %q, want %q", key, prefix+"app")
+	}
+	if original.MatchLabels["app"] != "client" {
+		t.Error("prefixLabels changed a shared peer selector in place")
+	}
+}
+
+func TestPrefixLabelsKeepsVMLabels(t *testing.T) {
+	vms, err := loadVMLabels(filepath.Join(repoRoot, "json", "vm-labels.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := testOptions()
+	opts.VMLabels = vms
+	policies, _, err := Pipeline{Options: opts, Rules: true, LabelPrefix: "nsx.example.com/"}.convert(readExport(t, "vm-applied.json"), newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped := 0
+	for _, policy := range policies {
+		if reflect.DeepEqual(policy.Spec.PodSelector.MatchLabels, vms["vm-db-primary"]) {
+			mapped++
+			continue
+		}
+		for key := range policy.Spec.PodSelector.MatchLabels {
+			if !strings.HasPrefix(key, "nsx.example.com/") {
+				t.Errorf("policy %s podSelector key %q, want it prefixed", policy.Metadata.Name, key)
+			}
+		}
+	}
+	if mapped == 0 {
+		t.Errorf("no policy selects the -vm-labels labels %v as written", vms["vm-db-primary"])
+	}
+}
@@ -0,0 +1,26 @@
+package convert
+
+// checkSelectors warns about policies with rules whose podSelector selects
+// more pods than intended: an empty podSelector the conversion did not mean
+// to select all pods with, or a label whose value is empty, as happens for
+// names that sanitize to nothing. Default-deny policies, which have no
+// rules, are expected to select all pods.
+func checkSelectors(diag *diagnostics, policies []NetworkPolicy) {
+	for _, policy := range policies {
+		if len(policy.Spec.Ingress) == 0 && len(policy.Spec.Egress) == 0 {
+			continue
+		}
+		selector := policy.Spec.PodSelector
+		if len(selector.MatchLabels) == 0 && len(selector.MatchExpressions) == 0 {
+			if !policy.allPods {
+				diag.warn(policy.source, warnEmptySelector, "policy %q from %q has an empty podSelector, which selects every pod in namespace %s", policy.Metadata.Name, policy.source, policy.Metadata.Namespace)
+			}
+			continue
+		}
+		for key, value := range selector.MatchLabels {
+			if value == "" {
+				diag.warn(policy.source, warnEmptySelector, "policy %q from %q selects pods by an empty %s label, likely from a name with no valid label characters", policy.Metadata.Name, policy.source, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package convert
+
+import (
+	"testing"
+)
+
+func TestCheckSelectors(t *testing.T) {
+	rules := []Rule{{Ports: testPorts(t, "TCP", "80")}}
+	tests := []struct {
+		name   string
+		policy NetworkPolicy
+		warns  bool
+	}{
+		{"accidental empty selector", NetworkPolicy{Spec: PolicySpec{Ingress: rules}}, true},
+		{"empty label value", NetworkPolicy{Spec: PolicySpec{PodSelector: PodSelector{MatchLabels: map[string]string{"app": ""}}, Ingress: rules}}, true},
+		{"intentional all pods", NetworkPolicy{Spec: PolicySpec{Ingress: rules}, allPods: true}, false},
+		{"default deny", NetworkPolicy{Spec: PolicySpec{PolicyTypes: []string{"Ingress"}}}, false},
+		{"app label", NetworkPolicy{Spec: PolicySpec{PodSelector: PodSelector{MatchLabels: map[string]string{"app": "web"}}, Egress: rules}}, false},
+	}
+	for _, tt := range tests {
+		diag := newDiagnostics(verbosityQuiet)
+		tt.policy.Metadata.Name, tt.policy.source = "web", "Web"
+		checkSelectors(diag, []NetworkPolicy{tt.policy})
+		if warned := len(diag.warnings) > 0; warned != tt.warns {
+			t.Errorf("%s: warnings = %+v, want warning %v", tt.name, diag.warnings, tt.warns)
+		}
+		if problems := diag.strictProblems(); len(problems) != len(diag.warnings) {
+			t.Errorf("%s: strict problems = %+v, want every empty-selector warning to fail -strict", tt.name, problems)
+		}
+		for _, w := range diag.warnings {
+			if w.Type != warnEmptySelector {
+				t.Errorf("%s: warning %+v is not an empty-selector warning", tt.name, w)
+			}
+		}
+	}
+}
+
+func TestAllPodsIsIntentional(t *testing.T) {
+	opts := testOptions()
+	opts.AllPods = true
+	_, diag := convertExport(t, directionsExport, Pipeline{Options: opts})
+	if len(diag.warnings) != 0 {
+		t.Errorf("-all-pods warnings = %+v, want none", diag.warnings)
+	}
+}
@@ -0,0 +1,158 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PolicyChanges is the difference between the policies of two exports
+type PolicyChanges struct {
+	Added     []NetworkPolicy
+	Changed   []NetworkPolicy
+	Unchanged int
+	Deleted   []NetworkPolicy
+}
+
+// previousPolicies converts an earlier export with the same pipeline. Its
+// diagnostics are dropped, since they describe the old export.
+func previousPolicies(path string, paths *ExportPaths, pipeline Pipeline) ([]NetworkPolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseExport(data, paths)
+	if err != nil {
+		return nil, err
+	}
+	policies, _, err := pipeline.convert(root, newDiagnostics(verbosityQuiet))
+	return policies, err
+}
+
+// diffPolicies compares the policies of the previous and the current export
+// by namespace and name. A policy changed when its rendering differs in any
+// of the output kinds.
+func diffPolicies(previous, current []NetworkPolicy, kinds []string, render RenderOptions) (PolicyChanges, error) {
+	old := map[string][]byte{}
+	for _, policy := range previous {
+		rendered, err := renderAll(policy, kinds, render)
+		if err != nil {
+			return PolicyChanges{}, err
+		}
+		old[policyKey(policy)] = rendered
+	}
+
+	var changes PolicyChanges
+	seen := map[string]bool{}
+	for _, policy := range current {
+		key := policyKey(policy)
+		seen[key] = true
+		rendered, err := renderAll(policy, kinds, render)
+		if err != nil {
+			return PolicyChanges{}, err
+		}
+		before, found := old[key]
+		switch {
+		case !found:
+			changes.Added = append(changes.Added, policy)
+		case !bytes.Equal(before, rendered):
+			changes.Changed = append(changes.Changed, policy)
+		default:
+			changes.Unchanged++
+		}
+	}
+	for _, policy := range previous {
+		if !seen[policyKey(policy)] {
+			changes.Deleted = append(changes.Deleted, policy)
+		}
+	}
+	return changes, nil
+}
+
+// emitted returns the added and changed policies in their original order
+func (c PolicyChanges) emitted(current []NetworkPolicy) []NetworkPolicy {
+	keep := map[string]bool{}
+	for _, policy := range append(append([]NetworkPolicy{}, c.Added...), c.Changed...) {
+		keep[policyKey(policy)] = true
+	}
+	var policies []NetworkPolicy
+	for _, policy := range current {
+		if keep[policyKey(policy)] {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+// logChanges logs a summary of the changes and every deleted policy
+func logChanges(since string, changes PolicyChanges) {
+	for _, policy := range changes.Deleted {
+		log.Printf("Deleted: policy %s is not generated from the current export", policyKey(policy))
+	}
+	log.Printf("Since %s: %d added, %d changed, %d unchanged, %d deleted policies", since, len(changes.Added), len(changes.Changed), changes.Unchanged, len(changes.Deleted))
+}
+
+// Formats of the -deletions-file selectable with -deletions-format
+const (
+	deletionsDelete = "delete"
+	deletionsPatch  = "patch"
+)
+
+// PolicyReference names a policy without its spec. Patch is set to "delete"
+// to make it a strategic merge patch that removes the policy.
+type PolicyReference struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata"`
+	Patch      string     `yaml:"$patch,omitempty"`
+}
+
+// writeDeletions writes a manifest naming the deleted policies in every
+// output kind. The delete format is for kubectl delete -f, and the patch
+// format holds $patch: delete patches for kustomize.
+func writeDeletions(path string, deleted []NetworkPolicy, kinds []string, render RenderOptions, format string) error {
+	var buf bytes.Buffer
+	for _, kind := range kinds {
+		for _, policy := range deleted {
+			ref := PolicyReference{
+				APIVersion: resourceAPIVersion(kind),
+				Kind:       resourceKind(kind),
+				Metadata:   ObjectMeta{Name: resourceName(policy, kind, render), Namespace: policy.Metadata.Namespace},
+			}
+			if format == deletionsPatch {
+				ref.Patch = "delete"
+			}
+			data, err := yaml.Marshal(ref)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&buf, "---\n%s\n", data)
+		}
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// renderAll marshals the policy in every output kind
+func renderAll(policy NetworkPolicy, kinds []string, render RenderOptions) ([]byte, error) {
+	var parts []string
+	for _, kind := range kinds {
+		data, err := marshalYAML(renderPolicy(policy, kind, render), defaultYAML)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, string(data))
+	}
+	return []byte(strings.Join(parts, "---\n")), nil
+}
+
+// policyKey identifies a policy across exports
+func policyKey(policy NetworkPolicy) string {
+	if policy.Metadata.Namespace == "" {
+		return policy.Metadata.Name
+	}
+	return policy.Metadata.Namespace + "/" + policy.Metadata.Name
+}
@@ -0,0 +1,123 @@
+package convert
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// policyNames returns the names of policies in order
+func policyNames(policies []NetworkPolicy) []string {
+	names := []string{}
+	for _, policy := range policies {
+		names = append(names, policy.Metadata.Name)
+	}
+	return names
+}
+
+func TestDiffPolicies(t *testing.T) {
+	pipeline := Pipeline{Options: testOptions()}
+	previous, err := previousPolicies(filepath.Join(repoRoot, "json/since-before.json"), nil, pipeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _, err := pipeline.convert(readExport(t, "since-after.json"), newDiagnostics(verbosityQuiet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := []string{kindNetworkPolicy}
+	changes, err := diffPolicies(previous, current, kinds, defaultRender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := policyNames(changes.Added); !reflect.DeepEqual(got, []string{"dns"}) {
+		t.Errorf("added = %v, want [dns]", got)
+	}
+	if got := policyNames(changes.Changed); !reflect.DeepEqual(got, []string{"http"}) {
+		t.Errorf("changed = %v, want [http]", got)
+	}
+	if changes.Unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", changes.Unchanged)
+	}
+	if got := policyNames(changes.Deleted); !reflect.DeepEqual(got, []string{"telnet"}) {
+		t.Errorf("deleted = %v, want [telnet]", got)
+	}
+	if got := policyNames(changes.emitted(current)); !reflect.DeepEqual(got, []string{"http", "dns"}) {
+		t.Errorf("emitted = %v, want the added and changed policies in export order", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "deleted.yaml")
+	if err := writeDeletions(path, changes.Deleted, kinds, defaultRender, deletionsPatch); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: telnet
+  namespace: default
+$patch: delete
+
+`
+	if string(data) != want {
+		t.Errorf("deletions file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestDeletionsOfShrinkingExport(t *testing.T) {
+	previous, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	current, _ := convertExport(t, `{"services": [
+		{"display_name": "web", "service_entries": [{"display_name": "http", "resource_type": "L4PortSetServiceEntry", "l4_protocol": "TCP", "destination_ports": ["80"]}]}
+	]}`, Pipeline{Options: testOptions()})
+	kinds := []string{kindNetworkPolicy, kindCilium}
+	changes, err := diffPolicies(previous, current, kinds, defaultRender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := policyNames(changes.Deleted); !reflect.DeepEqual(got, []string{"client", "db"}) {
+		t.Errorf("deleted = %v, want [client db]", got)
+	}
+	if len(changes.Added) != 0 || len(changes.Changed) != 0 || changes.Unchanged != 1 {
+		t.Errorf("changes = %+v, want only web unchanged", changes)
+	}
+
+	path := filepath.Join(t.TempDir(), "deleted.yaml")
+	if err := writeDeletions(path, changes.Deleted, kinds, defaultRender, deletionsDelete); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want string
+	for _, kind := range []string{"networking.k8s.io/v1\nkind: NetworkPolicy", "cilium.io/v2\nkind: CiliumNetworkPolicy"} {
+		for _, name := range []string{"client", "db"} {
+			want += "---\napiVersion: " + kind + "\nmetadata:\n  name: " + name + "\n  namespace: default\n\n"
+		}
+	}
+	if string(data) != want {
+		t.Errorf("deletions file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestPreviousPoliciesQuiet(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	// The previous export skips a wide-open policy, which is not logged
+	if _, err := previousPolicies(filepath.Join(repoRoot, "json/wide-open.json"), nil, Pipeline{Options: testOptions(), Rules: true}); err != nil {
+		t.Fatal(err)
+	}
+	if logged.Len() > 0 {
+		t.Errorf("previousPolicies logged:\n%s", logged.String())
+	}
+}
@@ -0,0 +1,71 @@
+package convert
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// tarModTime is the timestamp of every archive entry, so the same policies
+// always produce the same archive
+var tarModTime = time.Unix(0, 0)
+
+// writePolicyTar writes the policy files, and the index when indexFile is
+// set, to a tar archive at path, or to stdout for "-". Names ending in .gz
+// or .tgz are compressed with gzip.
+func writePolicyTar(path string, files []PolicyFile, entries []IndexEntry, indexFile string) error {
+	if indexFile != "" {
+		data, err := marshalReport(indexFile, entries)
+		if err != nil {
+			return err
+		}
+		files = append(files, PolicyFile{Path: indexFile, Data: data})
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		file, out = f, f
+	}
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz") {
+		gz = gzip.NewWriter(out)
+		out = gz
+	}
+
+	tw := tar.NewWriter(out)
+	for _, pf := range files {
+		header := &tar.Header{
+			Name:    pf.Path,
+			Mode:    0644,
+			Size:    int64(len(pf.Data)),
+			ModTime: tarModTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(pf.Data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
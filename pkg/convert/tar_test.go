@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readTar returns the names and contents of the entries of a tar archive,
+// decompressing it first when gzipped is set
+func readTar(t *testing.T, path string, gzipped bool) ([]string, map[string][]byte) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var in io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in = gz
+	}
+	var names []string
+	contents := map[string][]byte{}
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !header.ModTime.Equal(tarModTime) {
+			t.Errorf("%s has modification time %v, want %v", header.Name, header.ModTime, tarModTime)
+		}
+		names = append(names, header.Name)
+		contents[header.Name] = data
+	}
+	return names, contents
+}
+
+func TestWritePolicyTar(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	files, entries, err := policyFiles(policies, []string{kindNetworkPolicy}, defaultRender, defaultYAML, layoutFlat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"policies.tar", "policies.tar.gz"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := writePolicyTar(path, files, entries, "index.json"); err != nil {
+			t.Fatal(err)
+		}
+		names, contents := readTar(t, path, filepath.Ext(name) == ".gz")
+		if want := []string{"web.yaml", "client.yaml", "db.yaml", "index.json"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("%s has entries %v, want %v", name, names, want)
+		}
+		for _, file := range files {
+			if !bytes.Equal(contents[file.Path], file.Data) {
+				t.Errorf("%s entry %s = %q, want %q", name, file.Path, contents[file.Path], file.Data)
+			}
+		}
+		index, err := marshalReport("index.json", entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(contents["index.json"], index) {
+			t.Errorf("%s index = %s, want %s", name, contents["index.json"], index)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// loadTemplate parses a -template file, making the helper functions
+// available to it
+func loadTemplate(path string, opts Options) (*template.Template, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	funcs := template.FuncMap{
+		"sanitize": func(name string) string {
+			return sanitizeName(name, opts.Naming)
+		},
+		"join": strings.Join,
+	}
+	return template.New(filepath.Base(path)).Funcs(funcs).Parse(string(data))
+}
+
+// loadTemplateDir parses every file of a -template-dir, in name order.
+// Hidden files and subdirectories are skipped.
+func loadTemplateDir(dir string, opts Options) ([]*template.Template, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var templates []*template.Template
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		tmpl, err := loadTemplate(filepath.Join(dir, entry.Name()), opts)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("no templates in %s", dir)
+	}
+	return templates, nil
+}
+
+// templateFiles executes each template once per policy. The output of
+// template doc.md.tmpl for policy web is written to web.doc.md.
+func templateFiles(templates []*template.Template, policies []NetworkPolicy) ([]PolicyFile, error) {
+	var files []PolicyFile
+	written := map[string]string{}
+	for _, policy := range policies {
+		for _, tmpl := range templates {
+			file := policyName(policy) + "." + strings.TrimSuffix(tmpl.Name(), ".tmpl")
+			if source, ok := written[file]; ok {
+				return nil, fmt.Errorf("policies from %q and %q would both be written to %s", source, policy.source, file)
+			}
+			written[file] = policy.source
+
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, policy); err != nil {
+				return nil, fmt.Errorf("executing %s for %s: %v", tmpl.Name(), policy.Metadata.Name, err)
+			}
+			files = append(files, PolicyFile{Path: file, Data: buf.Bytes()})
+		}
+	}
+	return files, nil
+}
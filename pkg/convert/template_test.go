@@ -0,0 +1,60 @@
+package convert
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestTemplateFiles(t *testing.T) {
+	templates, err := loadTemplateDir(filepath.Join(repoRoot, "json", "templates"), testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	files, err := templateFiles(templates, policies[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if want := []string{"web.doc.md", "web.networkpolicy.yaml"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("files = %v, want %v", paths, want)
+	}
+
+	doc := "# web\n\nNetworkPolicy `web` in namespace `default` applies to pods labeled `app=web`.\n\nAllowed ingress ports:\n- TCP/80\n"
+	if string(files[0].Data) != doc {
+		t.Errorf("web.doc.md =\n%s\nwant\n%s", files[0].Data, doc)
+	}
+	var policy NetworkPolicy
+	if err := yaml.UnmarshalStrict(files[1].Data, &policy); err != nil {
+		t.Fatalf("web.networkpolicy.yaml is not a NetworkPolicy: %v\n%s", err, files[1].Data)
+	}
+	if policy.Kind != "NetworkPolicy" || policy.Metadata.Name != "web" || !reflect.DeepEqual(policy.Spec.PolicyTypes, []string{"Ingress"}) {
+		t.Errorf("web.networkpolicy.yaml = %+v", policy)
+	}
+}
+
+func TestLoadTemplateDirSkipsHiddenFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, text := range map[string]string{"name.txt.tmpl": "{{ sanitize .Metadata.Name }}\n", ".swap.tmpl": "{{"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := loadTemplateDir(dir, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 || templates[0].Name() != "name.txt.tmpl" {
+		t.Errorf("templates = %v, want only name.txt.tmpl", templates)
+	}
+	if _, err := loadTemplateDir(t.TempDir(), testOptions()); err == nil {
+		t.Error("loadTemplateDir accepted a directory without templates")
+	}
+}
@@ -0,0 +1,72 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+)
+
+// testScriptHeader defines the probe function of the generated test script.
+// It probes the first pod selected by a policy from the test pod.
+const testScriptHeader = `#!/bin/sh
+# Connectivity checks for the policies generated by vmware-analyzer-to-netpol.
+# Each allowed ingress port is probed with nc from the test pod, which needs
+# kubectl exec access and an nc that supports -z, -u and -w.
+PROBE_POD=${PROBE_POD:-%s}
+PROBE_NAMESPACE=${PROBE_NAMESPACE:-%s}
+failed=0
+
+# probe <policy> <namespace> <selector> <protocol> <port>
+probe() {
+  ip=$(kubectl get pods ${2:+-n "$2"} -l "$3" -o jsonpath='{.items[0].status.podIP}' 2>/dev/null)
+  if [ -z "$ip" ]; then
+    echo "SKIP $1 $4/$5: no pod matches $3"
+    return
+  fi
+  udp=""
+  if [ "$4" = "UDP" ]; then
+    udp="-u"
+  fi
+  if kubectl exec -n "$PROBE_NAMESPACE" "$PROBE_POD" -- nc -z $udp -w 2 "$ip" "$5" >/dev/null 2>&1; then
+    echo "OK   $1 $4/$5"
+  else
+    echo "FAIL $1 $4/$5"
+    failed=1
+  fi
+}
+
+`
+
+// writeTestScript writes a shell script that probes every port the policies
+// allow ingress on. Ports that nc cannot probe are listed as comments.
+func writeTestScript(path string, policies []NetworkPolicy, pod, podNamespace string) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, testScriptHeader, pod, podNamespace)
+	for _, policy := range policies {
+		name := policy.Metadata.Name
+		selector := selectorString(policy.Spec.PodSelector)
+		for _, rule := range policy.Spec.Ingress {
+			if len(rule.From) > 0 {
+				fmt.Fprintf(&buf, "# %s: only reachable if the test pod matches one of the rule's from peers\n", name)
+			}
+			if len(rule.Ports) == 0 {
+				fmt.Fprintf(&buf, "# %s: all ports are allowed, nothing to probe\n", name)
+			}
+			for _, port := range rule.Ports {
+				switch {
+				case port.Port == nil:
+					fmt.Fprintf(&buf, "# %s: all %s ports are allowed, nothing to probe\n", name, port.Protocol)
+				case port.Port.StrVal != "":
+					fmt.Fprintf(&buf, "# %s: named port %s/%s cannot be probed\n", name, port.Protocol, port.Port.StrVal)
+				case port.Protocol != "TCP" && port.Protocol != "UDP":
+					fmt.Fprintf(&buf, "# %s: %s/%d cannot be probed with nc\n", name, port.Protocol, port.Port.IntVal)
+				default:
+					// A range is probed on its first port
+					fmt.Fprintf(&buf, "probe '%s' '%s' '%s' %s %d\n", name, policy.Metadata.Namespace, selector, port.Protocol, port.Port.IntVal)
+				}
+			}
+		}
+	}
+	buf.WriteString("\nexit $failed\n")
+	return ioutil.WriteFile(path, buf.Bytes(), 0755)
+}
@@ -0,0 +1,29 @@
+package convert
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// latinLetters spells out Latin letters that do not decompose into a base
+// letter and accents
+var latinLetters = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+	"ø", "o", "Ø", "O", "đ", "d", "Đ", "D", "ł", "l", "Ł", "L",
+	"þ", "th", "Þ", "TH", "ð", "d", "Ð", "D", "ı", "i",
+)
+
+// transliterate romanizes accented Latin characters, e.g. "café" becomes
+// "cafe". Characters of other scripts are left for sanitizeName to replace.
+func transliterate(name string) string {
+	stripMarks := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(stripMarks, name)
+	if err != nil {
+		return latinLetters.Replace(name)
+	}
+	return latinLetters.Replace(result)
+}
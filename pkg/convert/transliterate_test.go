@@ -0,0 +1,42 @@
+package convert
+
+import "testing"
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"café-svc", "cafe-svc"},
+		{"Señor Müller", "Senor Muller"},
+		{"Straße", "Strasse"},
+		{"Øresund Łódź", "Oresund Lodz"},
+		{"ﬁle", "file"},
+		{"日本 api", "日本 api"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := transliterate(tt.in); got != tt.want {
+			t.Errorf("transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeUnicodeNames(t *testing.T) {
+	tests := []struct {
+		in            string
+		transliterate bool
+		want          string
+	}{
+		{"café-svc", false, "caf--svc"},
+		{"café-svc", true, "cafe-svc"},
+		{"Zürich Büro", true, "zurich-buro"},
+		{"日本 api", true, "api"},
+		{"日本", true, ""},
+	}
+	for _, tt := range tests {
+		opts := NameOptions{Replacement: "-", Transliterate: tt.transliterate}
+		if got := sanitizeName(tt.in, opts); got != tt.want {
+			t.Errorf("sanitizeName(%q, transliterate %v) = %q, want %q", tt.in, tt.transliterate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+//go:build k8sclient
+
+package convert
+
+import (
+	"context"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// k8sClient reports whether -list-unmanaged is available in this build
+const k8sClient = true
+
+// unmanagedPolicies connects to the cluster of the kubeconfig, or of the
+// default kubeconfig when it is empty, and lists the unmanaged policies of
+// the namespace. An empty namespace is the one of the current context,
+// which is returned along with the names.
+func unmanagedPolicies(kubeconfig, namespace string) (string, []string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+	if namespace == "" {
+		var err error
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return "", nil, err
+		}
+	}
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", nil, err
+	}
+	names, err := listUnmanaged(context.Background(), client, namespace)
+	return namespace, names, err
+}
+
+// listUnmanaged returns the sorted names of the NetworkPolicies of a
+// namespace that lack the label of -managed-by-label
+func listUnmanaged(ctx context.Context, client kubernetes.Interface, namespace string) ([]string, error) {
+	list, err := client.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, policy := range list.Items {
+		if !managedBy(policy.Labels) {
+			names = append(names, policy.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
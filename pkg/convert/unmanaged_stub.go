@@ -0,0 +1,13 @@
+//go:build !k8sclient
+
+package convert
+
+import "errors"
+
+// k8sClient reports whether -list-unmanaged is available in this build
+const k8sClient = false
+
+// unmanagedPolicies is only available when built with -tags k8sclient
+func unmanagedPolicies(kubeconfig, namespace string) (string, []string, error) {
+	return "", nil, errors.New("built without the k8sclient tag")
+}
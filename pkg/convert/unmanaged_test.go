@@ -0,0 +1,32 @@
+//go:build k8sclient
+
+package convert
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListUnmanaged(t *testing.T) {
+	policy := func(namespace, name string, labels map[string]string) *networkingv1.NetworkPolicy {
+		return &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels}}
+	}
+	client := fake.NewSimpleClientset(
+		policy("payments", "https", map[string]string{managedByLabel: managedByValue}),
+		policy("payments", "default-deny", nil),
+		policy("payments", "allow-monitoring", map[string]string{managedByLabel: "helm"}),
+		policy("other", "legacy", nil),
+	)
+	names, err := listUnmanaged(context.Background(), client, "payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"allow-monitoring", "default-deny"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("listUnmanaged = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,45 @@
+package convert
+
+import "fmt"
+
+// unsupportedWarnings are the warning types about NSX constructs that the
+// output cannot fully represent, which fail the run under
+// -fail-on-unsupported
+var unsupportedWarnings = map[string]bool{
+	warnUnsupportedRule:       true,
+	warnUnsupportedAction:     true,
+	warnNoPorts:               true,
+	warnScheduleIgnored:       true,
+	warnProfileIgnored:        true,
+	warnUnsupportedICMP:       true,
+	warnUnsupportedProtocol:   true,
+	warnUnexpandableRange:     true,
+	warnUnsupportedExpression: true,
+	warnExceptOutsideCIDR:     true,
+}
+
+// warnUnlessCilium logs a warning about a construct that only Cilium output
+// represents
+func (d *diagnostics) warnUnlessCilium(service, warningType, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	d.warn(service, warningType, "%s", message)
+	d.ciliumEnforced[Warning{Service: service, Type: warningType, Message: message}] = true
+}
+
+// unsupportedProblems returns the collected warnings about constructs that
+// the output kinds cannot fully represent
+func (d *diagnostics) unsupportedProblems(kinds []string) []Warning {
+	ciliumOnly := true
+	for _, kind := range kinds {
+		if kind != kindCilium {
+			ciliumOnly = false
+		}
+	}
+	var problems []Warning
+	for _, w := range d.warnings {
+		if unsupportedWarnings[w.Type] && !(ciliumOnly && d.ciliumEnforced[w]) {
+			problems = append(problems, w)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,61 @@
+package convert
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// problemTypes returns the distinct types of problems, sorted
+func problemTypes(problems []Warning) []string {
+	seen := map[string]bool{}
+	types := []string{}
+	for _, problem := range problems {
+		if !seen[problem.Type] {
+			seen[problem.Type] = true
+			types = append(types, problem.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+func TestUnsupportedProblems(t *testing.T) {
+	tests := []struct {
+		export  string
+		network []string
+		cilium  []string
+	}{
+		{"gre-tunnel.json", []string{warnUnsupportedProtocol}, []string{warnUnsupportedProtocol}},
+		{"context-profile.json", []string{warnProfileIgnored}, []string{warnProfileIgnored}},
+		{"scheduled-rule.json", []string{warnScheduleIgnored}, []string{warnScheduleIgnored}},
+		{"icmpv6-nd.json", []string{warnUnsupportedICMP}, []string{}},
+		{"multi-protocol.json", []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		diag := newDiagnostics(verbosityQuiet)
+		if _, _, err := (Pipeline{Options: testOptions(), Rules: true}).convert(readExport(t, tt.export), diag); err != nil {
+			t.Fatal(err)
+		}
+		if got := problemTypes(diag.unsupportedProblems([]string{kindNetworkPolicy})); !reflect.DeepEqual(got, tt.network) {
+			t.Errorf("%s: networkpolicy problems = %v, want %v", tt.export, got, tt.network)
+		}
+		if got := problemTypes(diag.unsupportedProblems([]string{kindCilium})); !reflect.DeepEqual(got, tt.cilium) {
+			t.Errorf("%s: cilium problems = %v, want %v", tt.export, got, tt.cilium)
+		}
+		if got := problemTypes(diag.unsupportedProblems([]string{kindNetworkPolicy, kindCilium})); !reflect.DeepEqual(got, tt.network) {
+			t.Errorf("%s: networkpolicy and cilium problems = %v, want the networkpolicy ones %v", tt.export, got, tt.network)
+		}
+	}
+}
+
+func TestUnsupportedDenyRule(t *testing.T) {
+	export := `{"services": [], "domains": [{"display_name": "default", "resources": {"security_policies": [{"display_name": "deny", "rules": [
+		{"display_name": "block-telnet", "action": "DROP", "direction": "IN", "source_groups": ["ANY"], "destination_groups": ["ANY"], "services": ["ANY"]}
+	]}]}}]}`
+	_, diag := convertExport(t, export, Pipeline{Options: testOptions(), Rules: true})
+	want := []Warning{{Service: "block-telnet", Type: warnUnsupportedAction, Message: `rule "block-telnet" has action DROP, which NetworkPolicies cannot express as they only allow traffic, skipping it`}}
+	if got := diag.unsupportedProblems([]string{kindNetworkPolicy}); !reflect.DeepEqual(got, want) {
+		t.Errorf("problems = %+v, want %+v", got, want)
+	}
+}
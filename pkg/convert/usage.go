@@ -0,0 +1,115 @@
+package convert
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formats of the -port-usage report
+const (
+	usageTable = "table"
+	usageCSV   = "csv"
+)
+
+// PortUsage counts the services that allow a port, range or named port of
+// a protocol
+type PortUsage struct {
+	Protocol string
+	Port     string
+	Services []string
+	// start and end order the numeric ports, with named ports last
+	start, end int
+}
+
+// portUsage aggregates the ports of the service policies across the export.
+// policies must be the output of servicePolicies for the same services. A
+// service is counted once per port, whichever direction allows it.
+func portUsage(services []Service, policies []NetworkPolicy) []PortUsage {
+	usage := map[string]*PortUsage{}
+	for i, service := range services {
+		policy := policies[i]
+		counted := map[string]bool{}
+		for _, rule := range append(append([]Rule{}, policy.Spec.Ingress...), policy.Spec.Egress...) {
+			for _, port := range rule.Ports {
+				entry := usagePort(port)
+				key := entry.Protocol + "/" + entry.Port
+				if counted[key] {
+					continue
+				}
+				counted[key] = true
+				if existing, ok := usage[key]; ok {
+					existing.Services = append(existing.Services, service.DisplayName)
+					continue
+				}
+				entry.Services = []string{service.DisplayName}
+				usage[key] = &entry
+			}
+		}
+	}
+	entries := make([]PortUsage, 0, len(usage))
+	for _, entry := range usage {
+		sort.Strings(entry.Services)
+		entries = append(entries, *entry)
+	}
+	// Most used first, then by protocol and port
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if len(a.Services) != len(b.Services) {
+			return len(a.Services) > len(b.Services)
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		if a.start != b.start {
+			return a.start < b.start
+		}
+		if a.end != b.end {
+			return a.end < b.end
+		}
+		return a.Port < b.Port
+	})
+	return entries
+}
+
+// usagePort describes a port of a rule as its protocol and "443",
+// "8000-8100", a port name, or "all" for every port
+func usagePort(port PortRule) PortUsage {
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = "TCP"
+	}
+	switch {
+	case port.Port == nil:
+		return PortUsage{Protocol: protocol, Port: "all", start: 0, end: maxPort}
+	case port.Port.StrVal != "":
+		return PortUsage{Protocol: protocol, Port: port.Port.StrVal, start: maxPort + 1, end: maxPort + 1}
+	case port.EndPort != nil:
+		return PortUsage{Protocol: protocol, Port: fmt.Sprintf("%d-%d", port.Port.IntVal, *port.EndPort), start: port.Port.IntVal, end: *port.EndPort}
+	}
+	return PortUsage{Protocol: protocol, Port: strconv.Itoa(port.Port.IntVal), start: port.Port.IntVal, end: port.Port.IntVal}
+}
+
+// writePortUsage writes the port usage as an aligned table, or as CSV with
+// the service names separated by semicolons
+func writePortUsage(w io.Writer, entries []PortUsage, format string) error {
+	if format == usageCSV {
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"protocol", "port", "services", "names"})
+		for _, entry := range entries {
+			cw.Write([]string{entry.Protocol, entry.Port, strconv.Itoa(len(entry.Services)), strings.Join(entry.Services, ";")})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROTOCOL\tPORT\tSERVICES\tNAMES")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", entry.Protocol, entry.Port, len(entry.Services), strings.Join(entry.Services, ", "))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,63 @@
+package convert
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPortUsage(t *testing.T) {
+	root := readExport(t, "port-usage.json")
+	entries := portUsage(root.Services, servicePolicies(newDiagnostics(verbosityQuiet), root, testOptions()))
+	want := []struct {
+		port     string
+		services []string
+	}{
+		{"TCP/443", []string{"API", "Admin Console", "Web"}},
+		{"UDP/53", []string{"DNS", "Resolver"}},
+		{"TCP/53", []string{"DNS"}},
+		{"TCP/80", []string{"Web"}},
+		{"TCP/9090-9100", []string{"API"}},
+		{"UDP/514", []string{"Syslog Client"}},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("port usage = %+v, want %d entries", entries, len(want))
+	}
+	for i, w := range want {
+		if got := entries[i].Protocol + "/" + entries[i].Port; got != w.port || !reflect.DeepEqual(entries[i].Services, w.services) {
+			t.Errorf("entry %d = %s used by %v, want %s used by %v", i, got, entries[i].Services, w.port, w.services)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := writePortUsage(&out, entries[:2], usageTable); err != nil {
+		t.Fatal(err)
+	}
+	table := "PROTOCOL  PORT  SERVICES  NAMES\n" +
+		"TCP       443   3         API, Admin Console, Web\n" +
+		"UDP       53    2         DNS, Resolver\n"
+	if out.String() != table {
+		t.Errorf("table =\n%s\nwant\n%s", out.String(), table)
+	}
+}
+
+func TestUsagePortOrder(t *testing.T) {
+	named := IntOrString{StrVal: "http"}
+	low, high := IntOrString{IntVal: 22}, IntOrString{IntVal: 8000}
+	end := 8100
+	services := []Service{{DisplayName: "a"}, {DisplayName: "b"}}
+	policies := []NetworkPolicy{
+		{Spec: PolicySpec{Ingress: []Rule{{Ports: []PortRule{{Port: &named, Protocol: "TCP"}, {Port: &high, EndPort: &end, Protocol: "TCP"}}}}}},
+		{Spec: PolicySpec{Ingress: []Rule{{Ports: []PortRule{{Protocol: "TCP"}, {Port: &low}}}}, Egress: []Rule{{Ports: []PortRule{{Port: &low, Protocol: "TCP"}}}}}},
+	}
+	var ports []string
+	for _, entry := range portUsage(services, policies) {
+		ports = append(ports, entry.Protocol+"/"+entry.Port)
+		if len(entry.Services) != 1 {
+			t.Errorf("%s/%s is counted for %v, want one service", entry.Protocol, entry.Port, entry.Services)
+		}
+	}
+	if want := []string{"TCP/all", "TCP/22", "TCP/8000-8100", "TCP/http"}; !reflect.DeepEqual(ports, want) {
+		t.Errorf("ports = %v, want %v", ports, want)
+	}
+}
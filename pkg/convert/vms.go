@@ -0,0 +1,82 @@
+package convert
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// vmPathSegment marks the scope entries of a rule that reference a virtual
+// machine rather than a group
+const vmPathSegment = "/virtual-machines/"
+
+// loadVMLabels reads a YAML map from VM name or ID to the labels of the
+// pods the VM was migrated to
+func loadVMLabels(path string) (map[string]map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var labels map[string]map[string]string
+	if err := yaml.UnmarshalStrict(data, &labels); err != nil {
+		return nil, err
+	}
+	for vm, vmLabels := range labels {
+		if len(vmLabels) == 0 {
+			return nil, fmt.Errorf("VM %q has no labels", vm)
+		}
+		for key, value := range vmLabels {
+			if err := validateLabel(key, value); err != nil {
+				return nil, fmt.Errorf("VM %q: %v", vm, err)
+			}
+		}
+	}
+	return labels, nil
+}
+
+// vmLabels returns the mapped labels of a scope entry, given as the VM
+// path, ID or name
+func vmLabels(scope string, vms map[string]map[string]string) (map[string]string, bool) {
+	if labels, ok := vms[scope]; ok {
+		return labels, true
+	}
+	labels, ok := vms[lastPathElement(scope)]
+	return labels, ok
+}
+
+// isVMScope reports whether a scope entry of a rule is a virtual machine:
+// a VM path, or a VM that the -vm-labels mapping lists
+func isVMScope(scope string, vms map[string]map[string]string) bool {
+	if strings.Contains(scope, vmPathSegment) {
+		return true
+	}
+	_, ok := vmLabels(scope, vms)
+	return ok
+}
+
+// vmSelectors returns one pod selector per VM a rule applies to, from the
+// -vm-labels mapping. It returns false when the rule applies to no VMs, or
+// to a VM without mapping, in which case the rule's groups are selected as
+// without VMs.
+func vmSelectors(diag *diagnostics, rule SecurityRule, opts Options) ([]PodSelector, bool) {
+	var selectors []PodSelector
+	unmapped := false
+	for _, scope := range rule.Scope {
+		if !isVMScope(scope, opts.VMLabels) {
+			continue
+		}
+		labels, ok := vmLabels(scope, opts.VMLabels)
+		if !ok {
+			diag.warn(rule.DisplayName, warnUnmappedVM, "rule %q applies to VM %q which has no -vm-labels mapping, selecting the pods of its groups instead", rule.DisplayName, scope)
+			unmapped = true
+			continue
+		}
+		selectors = append(selectors, PodSelector{MatchLabels: copyLabels(labels)})
+	}
+	if unmapped || len(selectors) == 0 {
+		return nil, false
+	}
+	return selectors, true
+}
@@ -0,0 +1,92 @@
+package convert
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+)
+
+// Warning types reported in the -warnings-file
+const (
+	warnInvalidPort           = "invalid-port"
+	warnUnknownService        = "unknown-service"
+	warnUnknownGroup          = "unknown-group"
+	warnNoPorts               = "no-ports"
+	warnNoSources             = "no-sources"
+	warnUnsupportedRule       = "unsupported-rule"
+	warnInvalidIPAddress      = "invalid-ip-address"
+	warnCoveringCIDR          = "covering-cidr"
+	warnUnmappedScope         = "unmapped-scope"
+	warnServiceCycle          = "service-cycle"
+	warnInvalidLabel          = "invalid-label"
+	warnScheduleIgnored       = "schedule-ignored"
+	warnWideOpen              = "wide-open"
+	warnBaseConflict          = "base-conflict"
+	warnUnknownProfile        = "unknown-profile"
+	warnProfileIgnored        = "profile-ignored"
+	warnPortConflict          = "port-conflict"
+	warnNoCIDR                = "no-cidr"
+	warnNoPeers               = "no-peers"
+	warnOrderingLost          = "ordering-lost"
+	warnUnsupportedICMP       = "unsupported-icmp"
+	warnExceptOutsideCIDR     = "except-outside-cidr"
+	warnUnsupportedExpression = "unsupported-expression"
+	warnUnresolvedNamespace   = "unresolved-namespace"
+	warnEmptySelector         = "empty-selector"
+	warnUnexpandableRange     = "unexpandable-range"
+	warnUnsupportedProtocol   = "unsupported-protocol"
+	warnUnmappedVM            = "unmapped-vm"
+	warnUnsupportedAction     = "unsupported-action"
+	warnMissingID             = "missing-id"
+)
+
+// strictWarnings are the warning types that fail the run under -strict
+var strictWarnings = map[string]bool{
+	warnInvalidPort:         true,
+	warnPortConflict:        true,
+	warnEmptySelector:       true,
+	warnUnsupportedProtocol: true,
+}
+
+// Warning is a structured conversion warning
+type Warning struct {
+	Service string `json:"service"`
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// warn logs a warning about an NSX service or rule and records it for the
+// warnings file. Repeats of an identical warning are dropped.
+func (d *diagnostics) warn(service, warningType, format string, args ...interface{}) {
+	w := Warning{Service: service, Type: warningType, Message: fmt.Sprintf(format, args...)}
+	for _, existing := range d.warnings {
+		if existing == w {
+			return
+		}
+	}
+	if d.verbosity >= verbosityWarnings {
+		log.Printf("Warning: %s", w.Message)
+	}
+	d.warnings = append(d.warnings, w)
+}
+
+// strictProblems returns the collected warnings that are errors under -strict
+func (d *diagnostics) strictProblems() []Warning {
+	var problems []Warning
+	for _, w := range d.warnings {
+		if strictWarnings[w.Type] {
+			problems = append(problems, w)
+		}
+	}
+	return problems
+}
+
+// writeWarnings writes the collected warnings as a JSON array
+func (d *diagnostics) writeWarnings(path string) error {
+	data, err := json.MarshalIndent(d.warnings, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,54 @@
+package convert
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readWarningsFile returns the objects of a warnings file, keeping their
+// fields as generic JSON values so the test checks the schema too
+func readWarningsFile(t *testing.T, path string) []map[string]interface{} {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings []map[string]interface{}
+	if err := json.Unmarshal(data, &warnings); err != nil {
+		t.Fatalf("warnings file is not a JSON array of objects: %v\n%s", err, data)
+	}
+	if warnings == nil {
+		t.Fatalf("warnings file is %s, want an array", data)
+	}
+	return warnings
+}
+
+func TestWriteWarnings(t *testing.T) {
+	diag := newDiagnostics(verbosityQuiet)
+	diag.warn("web", warnInvalidPort, "service %q has invalid port %q", "web", "http")
+	diag.warn("web", warnInvalidPort, "service %q has invalid port %q", "web", "http")
+	diag.info("web", infoAllPorts, "infos are not written")
+	path := filepath.Join(t.TempDir(), "warnings.json")
+	if err := diag.writeWarnings(path); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"service": "web", "type": "invalid-port", "message": `service "web" has invalid port "http"`},
+	}
+	if got := readWarningsFile(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings file = %v, want %v", got, want)
+	}
+}
+
+func TestWriteWarningsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warnings.json")
+	if err := newDiagnostics(verbosityQuiet).writeWarnings(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := readWarningsFile(t, path); len(got) != 0 {
+		t.Errorf("warnings file = %v, want an empty array", got)
+	}
+}
@@ -0,0 +1,132 @@
+package convert
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the input must stay unchanged after a change
+// before -watch converts it again, so a file saved in several writes is
+// converted once
+const watchDebounce = 200 * time.Millisecond
+
+// inputWatcher reports changes to the input files of the run: the -f file,
+// or the export files of -dir. It watches the directory holding them rather
+// than the files, so a file an editor replaces by renaming a new one over
+// it, and export files added to or removed from -dir, count as changes.
+type inputWatcher struct {
+	watcher   *fsnotify.Watcher
+	jsonFile  string
+	exportDir string
+	// changes receives a value once the input has been unchanged for the
+	// debounce interval after a change. Changes made before the value is
+	// received are coalesced into it.
+	changes chan struct{}
+}
+
+// newInputWatcher starts watching the input files
+func newInputWatcher(jsonFile, exportDir string, debounce time.Duration) (*inputWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dir := exportDir
+	if dir == "" {
+		dir = filepath.Dir(jsonFile)
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	w := &inputWatcher{watcher: watcher, jsonFile: jsonFile, exportDir: exportDir, changes: make(chan struct{}, 1)}
+	go w.run(debounce)
+	return w, nil
+}
+
+// isInput reports whether a file of the watched directory is an input file
+func (w *inputWatcher) isInput(name string) bool {
+	if w.exportDir != "" {
+		return strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.gz")
+	}
+	return filepath.Base(name) == filepath.Base(w.jsonFile)
+}
+
+// run turns the events of the input files into debounced changes until the
+// watcher is closed. Permission changes do not change the input and are
+// ignored.
+func (w *inputWatcher) run(debounce time.Duration) {
+	var settled <-chan time.Time
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || !w.isInput(event.Name) {
+				continue
+			}
+			settled = time.After(debounce)
+		case <-settled:
+			settled = nil
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Error watching the input: %v", err)
+		}
+	}
+}
+
+// Close stops watching the input
+func (w *inputWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// watch converts the input, then converts it again each time it changes,
+// until SIGINT or SIGTERM. Each conversion runs the program again without
+// -watch, so an export that fails to parse is logged and the next change
+// is still picked up.
+func watch(jsonFile, exportDir string) {
+	executable, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Error finding executable: %v", err)
+	}
+	args := append(append([]string{}, os.Args[1:]...), "-watch=false")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	input, err := newInputWatcher(jsonFile, exportDir, watchDebounce)
+	if err != nil {
+		log.Fatalf("Error watching the input: %v", err)
+	}
+	defer input.Close()
+
+	for {
+		cmd := exec.Command(executable, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Printf("Conversion failed: %v", err)
+		}
+		log.Print("Watching for changes, press Ctrl-C to stop")
+
+		select {
+		case <-signals:
+			log.Print("Stopped watching")
+			return
+		case <-input.changes:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package convert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForChange reports whether the watcher reports a change within timeout
+func waitForChange(w *inputWatcher, timeout time.Duration) bool {
+	select {
+	case <-w.changes:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestInputWatcher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "export.json")
+	write := func(name, data string) {
+		t.Helper()
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("export.json", directionsExport)
+
+	debounce := 50 * time.Millisecond
+	w, err := newInputWatcher(path, "", debounce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if waitForChange(w, 4*debounce) {
+		t.Fatal("watcher reported a change before the input changed")
+	}
+
+	// Writes in quick succession are a single change
+	for i := 0; i < 5; i++ {
+		write("export.json", directionsExport+"\n")
+		time.Sleep(debounce / 5)
+	}
+	if !waitForChange(w, time.Second) {
+		t.Fatal("watcher did not report the changed input")
+	}
+	if waitForChange(w, 4*debounce) {
+		t.Error("watcher reported the burst of writes more than once")
+	}
+
+	// Editors save by renaming a new file over the old one
+	write("export.json.tmp", directionsExport)
+	if err := os.Rename(filepath.Join(dir, "export.json.tmp"), path); err != nil {
+		t.Fatal(err)
+	}
+	if !waitForChange(w, time.Second) {
+		t.Fatal("watcher did not report the input replaced by a rename")
+	}
+
+	write("other.json", "{}")
+	if waitForChange(w, 4*debounce) {
+		t.Error("watcher reported a change to another file of the directory")
+	}
+}
+
+func TestInputWatcherDir(t *testing.T) {
+	dir := t.TempDir()
+	debounce := 50 * time.Millisecond
+	w, err := newInputWatcher("", dir, debounce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an export"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if waitForChange(w, 4*debounce) {
+		t.Error("watcher reported a file that is not an export file")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "services.json"), []byte(directionsExport), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !waitForChange(w, time.Second) {
+		t.Error("watcher did not report an export file added to the directory")
+	}
+}
@@ -0,0 +1,60 @@
+package convert
+
+import (
+	"log"
+	"strings"
+)
+
+// wideOpenAnnotation marks a policy that allows all traffic in a direction
+const wideOpenAnnotation = "nsx.vmware.com/wide-open"
+
+// wideOpenDirections returns the directions in which a policy has a rule
+// without peers and without ports, which allows any protocol on any port from
+// or to anywhere
+func wideOpenDirections(policy NetworkPolicy) []string {
+	var directions []string
+	for _, rule := range policy.Spec.Ingress {
+		if len(rule.From) == 0 && len(rule.Ports) == 0 {
+			directions = append(directions, "ingress")
+			break
+		}
+	}
+	for _, rule := range policy.Spec.Egress {
+		if len(rule.To) == 0 && len(rule.Ports) == 0 {
+			directions = append(directions, "egress")
+			break
+		}
+	}
+	return directions
+}
+
+// guardWideOpen drops every wide-open policy unless allow is set, in which
+// case the policy is kept with a warning annotation. Both are logged, and the
+// dropped policies are listed as an error.
+func guardWideOpen(diag *diagnostics, policies []NetworkPolicy, allow bool) []NetworkPolicy {
+	kept := policies[:0]
+	var skipped []string
+	for _, policy := range policies {
+		directions := wideOpenDirections(policy)
+		if len(directions) == 0 {
+			kept = append(kept, policy)
+			continue
+		}
+		traffic := strings.Join(directions, " and ")
+		if !allow {
+			diag.warn(policy.source, warnWideOpen, "policy %s from %q allows all %s traffic on any port, skipping it", policy.Metadata.Name, policy.source, traffic)
+			skipped = append(skipped, policy.Metadata.Name)
+			continue
+		}
+		diag.warn(policy.source, warnWideOpen, "policy %s from %q allows all %s traffic on any port", policy.Metadata.Name, policy.source, traffic)
+		if policy.Metadata.Annotations == nil {
+			policy.Metadata.Annotations = map[string]string{}
+		}
+		policy.Metadata.Annotations[wideOpenAnnotation] = "WARNING: allows all " + traffic + " traffic on any port"
+		kept = append(kept, policy)
+	}
+	if len(skipped) > 0 && diag.verbosity >= verbosityWarnings {
+		log.Printf("Error: skipped %d wide-open policies: %s (use -allow-wide-open to emit them)", len(skipped), strings.Join(skipped, ", "))
+	}
+	return kept
+}
@@ -0,0 +1,62 @@
+package convert
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// readExport parses an example export of the repository
+func readExport(t *testing.T, name string) Root {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join(repoRoot, "json", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := parseRoot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestGuardWideOpen(t *testing.T) {
+	root := readExport(t, "wide-open.json")
+	for _, allow := range []bool{false, true} {
+		diag := newDiagnostics(verbosityQuiet)
+		policies, _, err := Pipeline{Options: testOptions(), Rules: true, AllowWideOpen: allow}.convert(root, diag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diag.warnings) != 1 || diag.warnings[0].Type != warnWideOpen {
+			t.Errorf("allow %v: warnings = %+v, want one wide-open", allow, diag.warnings)
+		}
+		if !allow {
+			if len(policies) != 0 {
+				t.Errorf("wide-open policies were emitted without -allow-wide-open: %+v", policies)
+			}
+			continue
+		}
+		if len(policies) != 1 {
+			t.Fatalf("got %d policies with -allow-wide-open, want 1", len(policies))
+		}
+		want := "WARNING: allows all ingress traffic on any port"
+		if got := policies[0].Metadata.Annotations[wideOpenAnnotation]; got != want {
+			t.Errorf("annotation %s = %q, want %q", wideOpenAnnotation, got, want)
+		}
+	}
+}
+
+func TestWideOpenDirections(t *testing.T) {
+	port := testPorts(t, "TCP", "80")
+	policy := newPolicy("p", "default")
+	policy.Spec.Ingress = []Rule{{Ports: port}}
+	policy.Spec.Egress = []Rule{{To: []NetworkPolicyPeer{{IPBlock: &IPBlock{CIDR: "10.0.0.0/8"}}}}}
+	if directions := wideOpenDirections(policy); len(directions) != 0 {
+		t.Errorf("rules with ports or peers are wide open in %v", directions)
+	}
+	policy.Spec.Egress = append(policy.Spec.Egress, Rule{})
+	if directions := wideOpenDirections(policy); len(directions) != 1 || directions[0] != "egress" {
+		t.Errorf("wideOpenDirections = %v, want [egress]", directions)
+	}
+}
@@ -0,0 +1,160 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// YAML styles selectable with -yaml-style
+const (
+	yamlBlock = "block"
+	yamlFlow  = "flow"
+)
+
+// YAMLOptions controls how policies are written as YAML
+type YAMLOptions struct {
+	Indent int
+	Style  string
+}
+
+// defaultYAML is the historical output format
+var defaultYAML = YAMLOptions{Indent: 2, Style: yamlBlock}
+
+// validate checks the indent and style
+func (o YAMLOptions) validate() error {
+	if o.Indent < 2 || o.Indent > 8 {
+		return fmt.Errorf("indent %d must be between 2 and 8", o.Indent)
+	}
+	if o.Style != yamlBlock && o.Style != yamlFlow {
+		return fmt.Errorf("style %q must be %s or %s", o.Style, yamlBlock, yamlFlow)
+	}
+	return nil
+}
+
+// Output formats selectable with -format
+const (
+	formatYAML  = "yaml"
+	formatJSONL = "jsonl"
+)
+
+// marshalJSONLine encodes v as a single line of JSON. The value goes through
+// its YAML encoding, so the JSON has exactly the fields of the YAML output,
+// in the same order.
+func marshalJSONLine(v interface{}) ([]byte, error) {
+	var node yaml3.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeNodeJSON(&buf, &node); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeNodeJSON writes a YAML node as JSON, keeping the order of the keys
+// of every mapping instead of sorting them
+func writeNodeJSON(buf *bytes.Buffer, node *yaml3.Node) error {
+	switch node.Kind {
+	case yaml3.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeNodeJSON(buf, node.Content[0])
+	case yaml3.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeNodeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml3.SequenceNode:
+		buf.WriteByte('[')
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeNodeJSON(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml3.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int", "!!float", "!!bool":
+			buf.WriteString(node.Value)
+		case "!!null":
+			buf.WriteString("null")
+		default:
+			value, err := json.Marshal(node.Value)
+			if err != nil {
+				return err
+			}
+			buf.Write(value)
+		}
+	default:
+		return fmt.Errorf("cannot write YAML node kind %d as JSON", node.Kind)
+	}
+	return nil
+}
+
+// marshalYAML encodes v with the given options. The default options use
+// yaml.v2 so the output stays byte-identical to earlier releases. Other
+// options need the yaml.v3 encoder, which also indents lists under their key.
+func marshalYAML(v interface{}, opts YAMLOptions) ([]byte, error) {
+	if opts == defaultYAML {
+		return yaml.Marshal(v)
+	}
+	var node yaml3.Node
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+	if opts.Style == yamlFlow {
+		flowLeaves(&node)
+	}
+	var buf bytes.Buffer
+	enc := yaml3.NewEncoder(&buf)
+	enc.SetIndent(opts.Indent)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flowLeaves switches every list and map that holds only scalars to flow
+// style, e.g. policyTypes: [Ingress] and matchLabels: {app: web}
+func flowLeaves(node *yaml3.Node) {
+	if node.Kind == yaml3.SequenceNode || node.Kind == yaml3.MappingNode {
+		leaf := len(node.Content) > 0
+		for _, child := range node.Content {
+			if child.Kind != yaml3.ScalarNode {
+				leaf = false
+			}
+		}
+		if leaf {
+			node.Style = yaml3.FlowStyle
+			return
+		}
+	}
+	for _, child := range node.Content {
+		flowLeaves(child)
+	}
+}
@@ -0,0 +1,43 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSONLine(t *testing.T) {
+	policies, _ := convertExport(t, directionsExport, Pipeline{Options: testOptions()})
+	var out bytes.Buffer
+	for _, policy := range policies {
+		line, err := marshalJSONLine(renderPolicy(policy, kindNetworkPolicy, defaultRender))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(line)
+		out.WriteByte('\n')
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(policies) {
+		t.Fatalf("%d lines for %d policies:\n%s", len(lines), len(policies), out.String())
+	}
+	for i, line := range lines {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Errorf("line %d is not a JSON object: %v\n%s", i, err, line)
+			continue
+		}
+		if fields["kind"] != "NetworkPolicy" || fields["metadata"].(map[string]interface{})["name"] != policies[i].Metadata.Name {
+			t.Errorf("line %d is not the NetworkPolicy %s:\n%s", i, policies[i].Metadata.Name, line)
+		}
+	}
+	prefix := `{"apiVersion":"networking.k8s.io/v1","kind":"NetworkPolicy","metadata":{"name":"web","namespace":"default"},"spec":{"podSelector":`
+	if !strings.HasPrefix(lines[0], prefix) {
+		t.Errorf("line 0 does not keep the YAML field order:\n%s\nwant prefix\n%s", lines[0], prefix)
+	}
+	if !strings.Contains(lines[0], `"ports":[{"port":80,"protocol":"TCP"}]`) {
+		t.Errorf("line 0 does not encode port 80 as a number:\n%s", lines[0])
+	}
+}